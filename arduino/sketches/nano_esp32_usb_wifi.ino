@@ -8,12 +8,15 @@ const uint8_t SHT31_I2C_ADDRESS = 0x44;
 const int BLINK_DURATION = 500;
 const int NUM_READINGS = 48;
 const long INTERVAL = 10000;
+const char* FIRMWARE_VERSION = "dewdrop-framed/2";
+const char* SENSOR_MODEL = "SHT31";
 
 String deviceId = "";
 bool ledState = LOW;
 float temperatureReadings[NUM_READINGS];
 float humidityReadings[NUM_READINGS];
 int readingIndex = 0;
+unsigned long sampleCount = 0;
 unsigned long sensorPreviousMillis = 0;
 unsigned long lightPreviousMillis = 0;
 float humidityOffset = 0;
@@ -24,6 +27,11 @@ WebServer server(80);
 
 void initWiFi();
 void handleSerialInput();
+void handleFrame(String line);
+void sendFrame(uint8_t id, char command, const String& payload);
+uint16_t crc16(const char* data, size_t length);
+String sensorDataMessage();
+String sensorDataJSON();
 void handleDataRequest();
 void handleLedRequest();
 float averageTemperature();
@@ -85,7 +93,13 @@ void handleSerialInput() {
   if (Serial.available() > 0) {
     char incomingByte = Serial.read();
 
-    if (incomingByte == 'd') {
+    if (incomingByte == '@') {
+      String line = Serial.readStringUntil('\n');
+      line.trim();
+      handleFrame(line);
+    } else if (incomingByte == 'v') {
+      sendFrame(0, 'V', FIRMWARE_VERSION);
+    } else if (incomingByte == 'd') {
       float avgTemp = averageTemperature();
       float avgHumid = averageHumidity();
 
@@ -111,9 +125,97 @@ void handleSerialInput() {
   }
 }
 
+// frames look like @IICLLLL<payload>*CCCC where II is the command ID, C the command,
+// LLLL the payload length and CCCC the CRC-16/CCITT-FALSE of IICLLLL<payload>
+void handleFrame(String line) {
+  if (line.length() < 12 || line.charAt(line.length() - 5) != '*') {
+    return;
+  }
+
+  String body = line.substring(0, line.length() - 5);
+  uint16_t crc = strtol(line.substring(line.length() - 4).c_str(), NULL, 16);
+  if (crc != crc16(body.c_str(), body.length())) {
+    return;
+  }
+
+  uint8_t id = strtol(body.substring(0, 2).c_str(), NULL, 16);
+  char command = body.charAt(2);
+  long length = strtol(body.substring(3, 7).c_str(), NULL, 16);
+  String payload = body.substring(7);
+  if (payload.length() != length) {
+    sendFrame(id, 'E', "length mismatch");
+    return;
+  }
+
+  if (command == 'V') {
+    sendFrame(id, 'V', FIRMWARE_VERSION);
+  } else if (command == 'D') {
+    float avgTemp = averageTemperature();
+    float avgHumid = averageHumidity();
+    if (isnan(avgTemp) || isnan(avgHumid)) {
+      sendFrame(id, 'E', "sensor read failed");
+      return;
+    }
+    sendFrame(id, 'D', payload == "json" ? sensorDataJSON() : sensorDataMessage());
+  } else if (command == 'L' && (payload == "1" || payload == "0")) {
+    ledState = (payload == "1");
+    digitalWrite(LED_BUILTIN, ledState ? HIGH : LOW);
+    sendFrame(id, 'L', "a");
+  } else {
+    sendFrame(id, 'E', "unknown command");
+  }
+}
+
+void sendFrame(uint8_t id, char command, const String& payload) {
+  char header[8];
+  snprintf(header, sizeof(header), "%02X%c%04X", id, command, payload.length());
+  String body = String(header) + payload;
+
+  char trailer[6];
+  snprintf(trailer, sizeof(trailer), "*%04X", crc16(body.c_str(), body.length()));
+
+  Serial.print('@');
+  Serial.print(body);
+  Serial.println(trailer);
+}
+
+uint16_t crc16(const char* data, size_t length) {
+  uint16_t crc = 0xFFFF;
+
+  for (size_t i = 0; i < length; i++) {
+    crc ^= (uint16_t)data[i] << 8;
+    for (int bit = 0; bit < 8; bit++) {
+      crc = (crc & 0x8000) ? (crc << 1) ^ 0x1021 : crc << 1;
+    }
+  }
+
+  return crc;
+}
+
+String sensorDataMessage() {
+  return deviceId + "," + String(averageTemperature()) + "," + String(averageHumidity()) + "," + String(ledState);
+}
+
+// device_id is a string because JSON numbers lose precision past 2^53
+String sensorDataJSON() {
+  return "{\"device_id\":\"" + deviceId + "\"" +
+         ",\"temperature\":" + String(averageTemperature()) +
+         ",\"humidity\":" + String(averageHumidity()) +
+         ",\"led_state\":" + (ledState ? "true" : "false") +
+         ",\"firmware_version\":\"" + FIRMWARE_VERSION + "\"" +
+         ",\"sensor_model\":\"" + SENSOR_MODEL + "\"" +
+         ",\"uptime_seconds\":" + String(millis() / 1000) +
+         ",\"sample_count\":" + String(sampleCount) +
+         ",\"capabilities\":[\"csv\",\"json\",\"framed\"]" +
+         ",\"metrics\":{\"rssi\":" + String(WiFi.RSSI()) + "}}";
+}
+
 void handleDataRequest() {
-  String message = deviceId + "," + String(averageTemperature()) + "," + String(averageHumidity()) + "," + String(ledState) + "\n";
-  server.send(200, "text/plain", message);
+  if (server.arg("format") == "json") {
+    server.send(200, "application/json", sensorDataJSON() + "\n");
+    return;
+  }
+  server.send(200, "text/plain", sensorDataMessage() + "\n");
 }
 
 void handleLedRequest() {
@@ -160,6 +262,7 @@ void readSensorData() {
     humidityReadings[readingIndex] = currentHumidity;
 
     readingIndex = (readingIndex + 1) % NUM_READINGS;
+    sampleCount++;
   }
 }
 
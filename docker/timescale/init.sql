@@ -1,7 +1,10 @@
 CREATE TABLE IF NOT EXISTS data (
+    tenant_id TEXT NOT NULL DEFAULT 'default',
     device_id BIGINT,
     indoor_temperature REAL,
     indoor_humidity REAL,
+    raw_indoor_temperature REAL,
+    raw_indoor_humidity REAL,
     indoor_dewpoint REAL,
     outdoor_dewpoint REAL,
     dewpoint_delta REAL,
@@ -10,4 +13,16 @@ CREATE TABLE IF NOT EXISTS data (
     time TIMESTAMPTZ NOT NULL DEFAULT now()
 );
 
-CREATE INDEX IF NOT EXISTS data_time_idx ON data (time DESC);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS data_time_idx ON data (time DESC);
+
+CREATE TABLE IF NOT EXISTS mold_state (
+    device_id BIGINT PRIMARY KEY,
+    tenant_id TEXT NOT NULL DEFAULT 'default',
+    mold_index REAL NOT NULL DEFAULT 0,
+    dry_hours REAL NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS data_device_time_idx ON data (device_id, time DESC);
+
+CREATE INDEX IF NOT EXISTS data_tenant_device_time_idx ON data (tenant_id, device_id, time DESC);
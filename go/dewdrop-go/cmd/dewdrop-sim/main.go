@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/mugglemath/dewdrop-go/internal/sim"
+)
+
+func main() {
+	config := sim.DefaultConfig()
+
+	httpAddr := flag.String("http", ":8080", "address for the /data and /led endpoints, empty to disable")
+	serialEnabled := flag.Bool("serial", true, "emulate the serial protocol over a pseudo-terminal")
+	link := flag.String("link", "", "symlink to create for the pseudo-terminal, e.g. /tmp/ttyDEWDROP")
+	flag.Uint64Var(&config.DeviceID, "device-id", config.DeviceID, "simulated device ID")
+	flag.Float64Var(&config.BaseTemperature, "temperature", config.BaseTemperature, "mean temperature in C")
+	flag.Float64Var(&config.TemperatureAmplitude, "temperature-amplitude", config.TemperatureAmplitude, "temperature swing in C")
+	flag.Float64Var(&config.BaseHumidity, "humidity", config.BaseHumidity, "mean relative humidity in %")
+	flag.Float64Var(&config.HumidityAmplitude, "humidity-amplitude", config.HumidityAmplitude, "relative humidity swing in %")
+	flag.DurationVar(&config.Period, "period", config.Period, "period of the temperature/humidity curves")
+	flag.Float64Var(&config.Noise, "noise", config.Noise, "standard deviation of sensor noise")
+	flag.Float64Var(&config.Speed, "speed", config.Speed, "simulation speed multiplier")
+	flag.DurationVar(&config.Latency, "latency", config.Latency, "delay before each response")
+	flag.Float64Var(&config.DropRate, "drop-rate", config.DropRate, "probability of not answering")
+	flag.Float64Var(&config.GarbageRate, "garbage-rate", config.GarbageRate, "probability of a corrupted response")
+	flag.Float64Var(&config.FailureRate, "failure-rate", config.FailureRate, "probability of a sensor read failure")
+	flag.Float64Var(&config.SpikeRate, "spike-rate", config.SpikeRate, "probability of a reading spike")
+	flag.BoolVar(&config.Legacy, "legacy", config.Legacy, "emulate old sketches without JSON or framing")
+	flag.Int64Var(&config.Seed, "seed", config.Seed, "random seed")
+	flag.Parse()
+
+	device := sim.NewDevice(config)
+
+	if *serialEnabled {
+		master, slave, name, err := sim.OpenPTY()
+		if err != nil {
+			log.Fatalf("failed to open pseudo-terminal: %s", err)
+		}
+		defer master.Close()
+		defer slave.Close()
+
+		if *link != "" {
+			_ = os.Remove(*link)
+			if err := os.Symlink(name, *link); err != nil {
+				log.Fatalf("failed to link pseudo-terminal: %s", err)
+			}
+			defer os.Remove(*link)
+			name = *link
+		}
+		fmt.Printf("Serial device: ARDUINO_PORT=%s\n", name)
+
+		go func() {
+			if err := device.ServeSerial(master); err != nil {
+				log.Printf("serial simulation stopped: %s", err)
+			}
+		}()
+	}
+
+	if *httpAddr != "" {
+		fmt.Printf("WiFi device: ARDUINO_IP=http://localhost%s\n", *httpAddr)
+		go func() {
+			if err := http.ListenAndServe(*httpAddr, device.Handler()); err != nil {
+				log.Fatalf("failed to run server: %v", err)
+			}
+		}()
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT)
+	<-sigs
+}
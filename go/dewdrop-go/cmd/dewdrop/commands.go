@@ -0,0 +1,361 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mugglemath/dewdrop-go/internal/usb"
+	"github.com/mugglemath/dewdrop-go/pkg/calculations"
+	"github.com/mugglemath/dewdrop-go/pkg/calibration"
+	"github.com/mugglemath/dewdrop-go/pkg/models"
+	"github.com/mugglemath/dewdrop-go/pkg/units"
+	"github.com/mugglemath/dewdrop-go/pkg/utils"
+)
+
+var serialPortPatterns = []string{
+	"/dev/ttyUSB*",
+	"/dev/ttyACM*",
+	"/dev/cu.usbmodem*",
+	"/dev/cu.usbserial*",
+}
+
+const (
+	probeHTTPTimeout = 2 * time.Second
+	maxScanHosts     = 1024
+	scanWorkers      = 64
+)
+
+// readCommand prints a single reading without posting it
+func readCommand(config *Config, args []string) error {
+	fs := flag.NewFlagSet("read", flag.ExitOnError)
+	config.RegisterDeviceFlags(fs)
+	config.RegisterFormulaFlags(fs)
+	config.RegisterUnitFlags(fs)
+	asJSON := fs.Bool("json", false, "print the reading as JSON")
+	compare := fs.Bool("compare", false, "print the dew point from every formula")
+	_ = fs.Parse(args)
+
+	formula, err := calculations.ParseDewPointFormula(config.DewPointFormula)
+	if err != nil {
+		return err
+	}
+	u, err := units.Parse(config.Units)
+	if err != nil {
+		return err
+	}
+
+	arduino, err := openDevice(config)
+	if err != nil {
+		return err
+	}
+	defer arduino.Close()
+
+	indoorData, err := arduino.GetIndoorSensorData()
+	if err != nil {
+		return err
+	}
+
+	calibrations, err := calibration.Load(config.CalibrationFile)
+	if err != nil {
+		return err
+	}
+	calibrations.Apply(&indoorData)
+
+	if *asJSON {
+		output, err := json.MarshalIndent(indoorData, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(output))
+		return nil
+	}
+
+	printIndoorData(indoorData, u)
+	fmt.Printf("LED State: %v\n", indoorData.LedState)
+	indoorDewpoint, err := formula.DewPoint(float64(indoorData.Temperature), float64(indoorData.Humidity))
+	if err != nil {
+		return fmt.Errorf("dew point calculation error: %w", err)
+	}
+	fmt.Printf("Indoor Dewpoint: %s (%s)\n", u.FormatTemperature(indoorDewpoint), formula.Name())
+	printIndoorMetrics(indoorData, u)
+	if *compare {
+		return printFormulaComparison(indoorData, u)
+	}
+	return nil
+}
+
+// printFormulaComparison shows how much the formulas disagree for a reading
+func printFormulaComparison(indoorData models.IndoorSensorData, u units.System) error {
+	comparison, err := calculations.CompareDewPointFormulas(float64(indoorData.Temperature),
+		float64(indoorData.Humidity))
+	if err != nil {
+		return fmt.Errorf("dew point calculation error: %w", err)
+	}
+	for _, f := range calculations.DewPointFormulas() {
+		min, max := f.ValidRange()
+		note := ""
+		if slices.Contains(comparison.OutOfRange, f.Name()) {
+			note = " out of range"
+		}
+		fmt.Printf("  %-15s %7.2f  ±%.2f %s for %.0f..%.0f %s%s\n", f.Name(), u.Temperature(comparison.DewPoints[f.Name()]),
+			u.TemperatureDelta(f.MaxError()), u.TemperatureUnit(), u.Temperature(min), u.Temperature(max),
+			u.TemperatureUnit(), note)
+	}
+	fmt.Printf("  %-15s %7.2f\n", "spread", u.TemperatureDelta(comparison.Spread))
+	return nil
+}
+
+// ledCommand switches the warning light on or off
+func ledCommand(config *Config, args []string) error {
+	fs := flag.NewFlagSet("led", flag.ExitOnError)
+	config.RegisterDeviceFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: dewdrop led on|off [flags]")
+		fs.PrintDefaults()
+	}
+	if len(args) == 0 {
+		fs.Usage()
+		return errors.New("missing LED state")
+	}
+	state := args[0]
+	_ = fs.Parse(args[1:])
+
+	// the warning light is on when windows should be kept closed
+	var openWindows bool
+	switch state {
+	case "on":
+		openWindows = false
+	case "off":
+		openWindows = true
+	default:
+		return fmt.Errorf("invalid LED state %q, use on or off", state)
+	}
+
+	arduino, err := openDevice(config)
+	if err != nil {
+		return err
+	}
+	defer arduino.Close()
+
+	return arduino.ToggleWarningLight(openWindows)
+}
+
+// calibrateCommand compares averaged raw readings to a reference instrument and
+// stores the resulting coefficients for the device
+func calibrateCommand(config *Config, args []string) error {
+	fs := flag.NewFlagSet("calibrate", flag.ExitOnError)
+	config.RegisterDeviceFlags(fs)
+	config.RegisterUnitFlags(fs)
+	referenceTemperature := fs.Float64("ref-temperature", 0, "temperature shown by the reference instrument, in C or F with -units imperial")
+	referenceHumidity := fs.Float64("ref-humidity", 0, "relative humidity shown by the reference instrument in %")
+	samples := fs.Int("samples", 5, "number of readings to average")
+	reset := fs.Bool("reset", false, "discard the existing calibration for the device first")
+	_ = fs.Parse(args)
+
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if !set["ref-temperature"] && !set["ref-humidity"] {
+		return errors.New("provide -ref-temperature and/or -ref-humidity")
+	}
+	u, err := units.Parse(config.Units)
+	if err != nil {
+		return err
+	}
+	if config.CalibrationFile == "" {
+		config.CalibrationFile = "calibration.json"
+	}
+	if *samples < 1 {
+		*samples = 1
+	}
+
+	arduino, err := openDevice(config)
+	if err != nil {
+		return err
+	}
+	defer arduino.Close()
+
+	var deviceID uint64
+	var temperatureSum, humiditySum float64
+	for i := 0; i < *samples; i++ {
+		if i > 0 {
+			time.Sleep(time.Second)
+		}
+		indoorData, err := arduino.GetIndoorSensorData()
+		if err != nil {
+			return err
+		}
+		deviceID = indoorData.DeviceID
+		temperatureSum += float64(indoorData.Temperature)
+		humiditySum += float64(indoorData.Humidity)
+	}
+	rawTemperature := temperatureSum / float64(*samples)
+	rawHumidity := humiditySum / float64(*samples)
+
+	calibrations, err := calibration.Load(config.CalibrationFile)
+	if err != nil {
+		return err
+	}
+	c, _ := calibrations.Get(deviceID)
+	if *reset {
+		c = calibration.Calibration{}
+	}
+	if set["ref-temperature"] {
+		c.AddTemperatureReference(rawTemperature, u.FromTemperature(*referenceTemperature))
+	}
+	if set["ref-humidity"] {
+		c.AddHumidityReference(rawHumidity, *referenceHumidity)
+	}
+	calibrations.Set(deviceID, c)
+	if err := calibrations.Save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Device %d raw average: %s %.2f %%\n", deviceID, u.FormatTemperature(rawTemperature), rawHumidity)
+	fmt.Printf("Temperature: %s\n", c.Temperature)
+	fmt.Printf("Humidity: %s (%d reference point(s))\n", c.Humidity, len(c.HumidityPoints))
+	fmt.Printf("Saved to %s\n", config.CalibrationFile)
+	return nil
+}
+
+// probeCommand looks for Arduinos on local serial ports and over HTTP
+func probeCommand(config *Config, args []string) error {
+	fs := flag.NewFlagSet("probe", flag.ExitOnError)
+	config.RegisterDeviceFlags(fs)
+	config.RegisterUnitFlags(fs)
+	scan := fs.String("scan", "", "CIDR range to scan for WiFi devices, e.g. 10.0.0.0/24")
+	_ = fs.Parse(args)
+
+	u, err := units.Parse(config.Units)
+	if err != nil {
+		return err
+	}
+
+	found := 0
+
+	ports := map[string]bool{}
+	if config.ArduinoPort != "" {
+		ports[config.ArduinoPort] = true
+	}
+	for _, pattern := range serialPortPatterns {
+		matches, _ := filepath.Glob(pattern)
+		for _, match := range matches {
+			ports[match] = true
+		}
+	}
+	for port := range ports {
+		fmt.Printf("\nProbing serial port %s\n", port)
+		protocol, err := usb.ParseProtocol(config.USBProtocol)
+		if err != nil {
+			fmt.Printf("%s, using auto-detection\n", err)
+		}
+		usbComm, err := usb.NewUsbCommunication(port, protocol)
+		if err != nil {
+			fmt.Printf("  failed to open: %s\n", err)
+			continue
+		}
+		indoorData, err := usbComm.GetIndoorSensorData()
+		usbComm.Close()
+		if err != nil {
+			fmt.Printf("  no valid response: %s\n", err)
+			continue
+		}
+		found++
+		printIndoorData(indoorData, u)
+	}
+
+	var hosts []string
+	if config.ArduinoIP != "" {
+		hosts = append(hosts, config.ArduinoIP)
+	}
+	if *scan != "" {
+		scanned, err := scanHosts(*scan)
+		if err != nil {
+			return err
+		}
+		hosts = append(hosts, scanned...)
+	}
+	for _, response := range probeHosts(hosts) {
+		found++
+		fmt.Printf("\nWiFi device at %s\n", response.host)
+		fmt.Printf("Response body: %s\n", response.body)
+	}
+
+	fmt.Printf("\nFound %d device(s)\n", found)
+	return nil
+}
+
+type probeResponse struct {
+	host string
+	body string
+}
+
+// probeHosts queries /data on every host concurrently and keeps valid sensor responses
+func probeHosts(hosts []string) []probeResponse {
+	client := &http.Client{Timeout: probeHTTPTimeout}
+	jobs := make(chan string)
+	results := make(chan probeResponse)
+
+	var wg sync.WaitGroup
+	for i := 0; i < scanWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for host := range jobs {
+				resp, err := client.Get(host + "/data?format=json")
+				if err != nil {
+					continue
+				}
+				body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+				resp.Body.Close()
+				if err != nil || resp.StatusCode != http.StatusOK {
+					continue
+				}
+				if _, err := utils.ParseIndoorSensorData(string(body)); err == nil {
+					results <- probeResponse{host: host, body: strings.TrimSpace(string(body))}
+				}
+			}
+		}()
+	}
+
+	go func() {
+		for _, host := range hosts {
+			jobs <- host
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	var responses []probeResponse
+	for response := range results {
+		responses = append(responses, response)
+	}
+	return responses
+}
+
+// scanHosts lists the host URLs of an IPv4 CIDR range
+func scanHosts(cidr string) ([]string, error) {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scan range: %w", err)
+	}
+	if !prefix.Addr().Is4() || prefix.Bits() < 32-10 {
+		return nil, fmt.Errorf("scan range must be IPv4 with at most %d hosts", maxScanHosts)
+	}
+
+	var hosts []string
+	for addr := prefix.Masked().Addr(); prefix.Contains(addr); addr = addr.Next() {
+		hosts = append(hosts, "http://"+addr.String())
+	}
+	return hosts, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"strconv"
+)
+
+type Config struct {
+	Mode        string
+	Interval    int
+	ArduinoPort string
+	ArduinoIP   string
+	USBProtocol string
+
+	CalibrationFile    string
+	TemperatureFilters string
+	HumidityFilters    string
+	FilterStateFile    string
+
+	DewPointFormula string
+	Units           string
+
+	GetURL            string
+	SensorFeedPostURL string
+	// APIKey is only read from the environment, to keep it out of the process list
+	APIKey string
+
+	MetricsAddr string
+
+	LogLevel  string
+	LogFormat string
+
+	TraceExporter string
+}
+
+const defaultInterval = 60
+
+// NewConfig reads the environment, flags registered with RegisterFlags override it
+func NewConfig() *Config {
+	config := &Config{
+		Mode:               os.Getenv("MODE"),
+		ArduinoPort:        os.Getenv("ARDUINO_PORT"),
+		ArduinoIP:          os.Getenv("ARDUINO_IP"),
+		USBProtocol:        os.Getenv("USB_PROTOCOL"),
+		CalibrationFile:    os.Getenv("CALIBRATION_FILE"),
+		TemperatureFilters: os.Getenv("FILTER_TEMPERATURE"),
+		HumidityFilters:    os.Getenv("FILTER_HUMIDITY"),
+		FilterStateFile:    os.Getenv("FILTER_STATE_FILE"),
+		DewPointFormula:    os.Getenv("DEWPOINT_FORMULA"),
+		Units:              os.Getenv("UNITS"),
+		GetURL:             os.Getenv("GET_URL"),
+		SensorFeedPostURL:  os.Getenv("POST_URL_SENSOR_FEED"),
+		APIKey:             os.Getenv("API_KEY"),
+		MetricsAddr:        os.Getenv("METRICS_ADDR"),
+		LogLevel:           os.Getenv("LOG_LEVEL"),
+		LogFormat:          os.Getenv("LOG_FORMAT"),
+		TraceExporter:      os.Getenv("OTEL_TRACES_EXPORTER"),
+	}
+
+	// an unset or invalid INTERVAL is reported by the run command
+	if interval, err := strconv.Atoi(os.Getenv("INTERVAL")); err == nil {
+		config.Interval = interval
+	}
+
+	return config
+}
+
+// RegisterDeviceFlags adds the flags needed to talk to an Arduino
+func (c *Config) RegisterDeviceFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Mode, "mode", c.Mode, "device connection: wifi or usb (env MODE)")
+	fs.StringVar(&c.ArduinoPort, "port", c.ArduinoPort, "serial port in usb mode (env ARDUINO_PORT)")
+	fs.StringVar(&c.ArduinoIP, "ip", c.ArduinoIP, "device URL in wifi mode, e.g. http://10.0.0.123 (env ARDUINO_IP)")
+	fs.StringVar(&c.USBProtocol, "protocol", c.USBProtocol, "serial protocol: auto, framed or legacy (env USB_PROTOCOL)")
+	fs.StringVar(&c.CalibrationFile, "calibration", c.CalibrationFile, "per-device calibration file (env CALIBRATION_FILE)")
+}
+
+// RegisterFilterFlags adds the flags for the smoothing pipeline
+func (c *Config) RegisterFilterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.TemperatureFilters, "filter-temperature", c.TemperatureFilters,
+		"temperature filter pipeline, e.g. hampel:7:3,median:5,ema:0.3,rate:0.5 in C/min (env FILTER_TEMPERATURE)")
+	fs.StringVar(&c.HumidityFilters, "filter-humidity", c.HumidityFilters,
+		"humidity filter pipeline, e.g. hampel:7:3,median:5,ema:0.3,rate:2 in %RH/min (env FILTER_HUMIDITY)")
+	fs.StringVar(&c.FilterStateFile, "filter-state", c.FilterStateFile, "file to keep filter state in across restarts (env FILTER_STATE_FILE)")
+}
+
+// RegisterFormulaFlags adds the flag selecting the dew point formula
+func (c *Config) RegisterFormulaFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.DewPointFormula, "dewpoint-formula", c.DewPointFormula,
+		"magnus, magnus-ice, magnus-sonntag, buck, buck-ice, sonntag, sonntag-ice or auto (env DEWPOINT_FORMULA)")
+}
+
+// RegisterUnitFlags adds the flag selecting the units used for output and input values
+func (c *Config) RegisterUnitFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Units, "units", c.Units, "metric or imperial (env UNITS)")
+}
+
+// RegisterServerFlags adds the flags needed to talk to go-dew
+func (c *Config) RegisterServerFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.GetURL, "get-url", c.GetURL, "outdoor dewpoint URL (env GET_URL)")
+	fs.StringVar(&c.SensorFeedPostURL, "post-url", c.SensorFeedPostURL, "sensor feed URL (env POST_URL_SENSOR_FEED)")
+}
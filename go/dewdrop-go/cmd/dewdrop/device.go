@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/mugglemath/dewdrop-go/internal/usb"
+	"github.com/mugglemath/dewdrop-go/internal/wifi"
+	"github.com/mugglemath/dewdrop-go/pkg/models"
+)
+
+// device hides whether the Arduino is reached over WiFi or USB
+type device interface {
+	GetIndoorSensorData() (models.IndoorSensorData, error)
+	ToggleWarningLight(openWindows bool) error
+	Close() error
+}
+
+type wifiDevice struct {
+	client    wifi.Client
+	arduinoIP string
+}
+
+func (w *wifiDevice) GetIndoorSensorData() (models.IndoorSensorData, error) {
+	return w.client.GetIndoorSensorData(fmt.Sprintf("%s/data", w.arduinoIP))
+}
+
+func (w *wifiDevice) ToggleWarningLight(openWindows bool) error {
+	return w.client.ToggleWarningLight(openWindows)
+}
+
+func (w *wifiDevice) Close() error {
+	return nil
+}
+
+func openDevice(config *Config) (device, error) {
+	switch config.Mode {
+	case "wifi":
+		return &wifiDevice{client: wifi.NewWifiClient(config.ArduinoIP), arduinoIP: config.ArduinoIP}, nil
+	case "usb":
+		protocol, err := usb.ParseProtocol(config.USBProtocol)
+		if err != nil {
+			slog.Warn("using protocol auto-detection", "error", err)
+		}
+		return usb.NewUsbCommunication(config.ArduinoPort, protocol)
+	default:
+		return nil, fmt.Errorf("invalid mode: %s", config.Mode)
+	}
+}
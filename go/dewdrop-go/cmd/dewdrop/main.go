@@ -1,38 +1,141 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
 	"os"
-	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/mugglemath/dewdrop-go/internal/metrics"
 	"github.com/mugglemath/dewdrop-go/internal/requests"
-	"github.com/mugglemath/dewdrop-go/internal/usb"
-	"github.com/mugglemath/dewdrop-go/internal/wifi"
 	"github.com/mugglemath/dewdrop-go/pkg/calculations"
+	"github.com/mugglemath/dewdrop-go/pkg/calibration"
+	"github.com/mugglemath/dewdrop-go/pkg/filter"
+	"github.com/mugglemath/dewdrop-go/pkg/logging"
 	"github.com/mugglemath/dewdrop-go/pkg/models"
+	"github.com/mugglemath/dewdrop-go/pkg/tracing"
+	"github.com/mugglemath/dewdrop-go/pkg/units"
 )
 
+const usage = `Usage: dewdrop <command> [flags]
+
+Commands:
+  run          poll the Arduino and post to go-dew every interval (default)
+  read         print a single reading
+  led on|off   switch the warning light
+  probe        look for Arduinos on serial ports and the network
+  calibrate    derive calibration coefficients from a reference reading
+
+Run 'dewdrop <command> -h' for the flags of a command. Flags override
+the environment variables used by the Docker setup.
+`
+
 func main() {
-	intervalStr := os.Getenv("INTERVAL")
-	const defaultInterval = 60
-	var interval int
+	command, args := "run", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
 
-	mode := os.Getenv("MODE")
-	fmt.Printf("Running in %s mode\n", mode)
+	config := NewConfig()
+	if err := logging.Setup(config.LogLevel, config.LogFormat); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 
-	if intervalStr != "" {
-		var err error
-		interval, err = strconv.Atoi(intervalStr)
-		if err != nil {
-			fmt.Printf("Invalid INTERVAL value, using default: %d seconds\n", defaultInterval)
-			interval = defaultInterval
-		}
-	} else {
-		fmt.Printf("INTERVAL not set, using default: %d seconds\n", defaultInterval)
+	var err error
+	switch command {
+	case "run":
+		err = runCommand(config, args)
+	case "read":
+		err = readCommand(config, args)
+	case "led":
+		err = ledCommand(config, args)
+	case "probe":
+		err = probeCommand(config, args)
+	case "calibrate":
+		err = calibrateCommand(config, args)
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+const tracerName = "github.com/mugglemath/dewdrop-go/cmd/dewdrop"
+
+func runCommand(config *Config, args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	config.RegisterDeviceFlags(fs)
+	config.RegisterServerFlags(fs)
+	config.RegisterFilterFlags(fs)
+	config.RegisterFormulaFlags(fs)
+	config.RegisterUnitFlags(fs)
+	fs.IntVar(&config.Interval, "interval", config.Interval, "seconds between readings (env INTERVAL)")
+	fs.StringVar(&config.MetricsAddr, "metrics-addr", config.MetricsAddr, "serve Prometheus metrics on this address, e.g. :9100 (env METRICS_ADDR)")
+	_ = fs.Parse(args)
+
+	slog.Info("starting", "mode", config.Mode)
+	interval := config.Interval
+	if interval <= 0 {
+		slog.Warn("INTERVAL not set or invalid, using default", "seconds", defaultInterval)
 		interval = defaultInterval
 	}
+	if config.GetURL == "" {
+		slog.Info("GET_URL not set, only reading the sensor without go-dew")
+	}
+
+	formula, err := calculations.ParseDewPointFormula(config.DewPointFormula)
+	if err != nil {
+		return err
+	}
+
+	unitSystem, err := units.Parse(config.Units)
+	if err != nil {
+		return err
+	}
+
+	calibrations, err := calibration.Load(config.CalibrationFile)
+	if err != nil {
+		return err
+	}
+
+	if os.Getenv("FILTERS") != "" {
+		return errors.New("FILTERS was split into FILTER_TEMPERATURE and FILTER_HUMIDITY, a rate limit can't suit both")
+	}
+	filters, err := filter.NewBank(config.TemperatureFilters, config.HumidityFilters)
+	if err != nil {
+		return err
+	}
+	if config.FilterStateFile != "" {
+		if err := filters.Load(config.FilterStateFile); err != nil {
+			slog.Warn("failed to load filter state", "error", err)
+		}
+	}
+
+	if config.MetricsAddr != "" {
+		if err := serveMetrics(config.MetricsAddr); err != nil {
+			return err
+		}
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), "dewdrop", config.TraceExporter)
+	if err != nil {
+		return err
+	}
+	defer shutdownTracing(context.Background())
 
 	ticker := time.NewTicker(time.Duration(interval) * time.Second)
 	defer ticker.Stop()
@@ -43,9 +146,26 @@ func main() {
 	for {
 		<-ticker.C
 		start := time.Now()
-		fmt.Println()
-		execute(mode)
+		ctx := logging.WithRequestID(context.Background(), logging.NewRequestID())
+		ctx, span := tracing.Start(ctx, tracerName, "poll")
+		err := execute(ctx, config, formula, unitSystem, calibrations, filters)
+		tracing.End(span, err)
 		elapsed := time.Since(start)
+		var failed *pollError
+		stage := ""
+		if errors.As(err, &failed) {
+			stage = failed.stage
+		}
+		metrics.Poll(elapsed, stage, err)
+		logger := logging.FromContext(ctx)
+		if err != nil {
+			logger.Error("poll failed", "stage", stage, "error", err)
+			// when exporting, keep polling so failures show up in the error counters
+			if config.MetricsAddr == "" {
+				_ = shutdownTracing(context.Background())
+				os.Exit(1)
+			}
+		}
 		sum += elapsed
 		n++
 
@@ -56,116 +176,231 @@ func main() {
 			max = elapsed
 		}
 
-		fmt.Printf("%d Execution time: %s Average: %s Min: %s Max: %s\n",
-			n, elapsed, time.Duration(int64(sum)/n), min, max)
+		logger.Info("poll finished", "count", n, "duration_ms", milliseconds(elapsed),
+			"average_ms", milliseconds(time.Duration(int64(sum)/n)), "min_ms", milliseconds(min),
+			"max_ms", milliseconds(max))
 	}
 }
 
-func execute(mode string) {
+// serveMetrics listens on addr before returning, so a busy port is reported at startup
+func serveMetrics(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to serve metrics: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	slog.Info("serving metrics", "address", listener.Addr().String(), "path", "/metrics")
+	go func() {
+		if err := http.Serve(listener, mux); err != nil {
+			slog.Error("metrics server stopped", "error", err)
+		}
+	}()
+	return nil
+}
+
+// pollError records the stage a poll failed in for the error counters
+type pollError struct {
+	stage string
+	err   error
+}
+
+func (e *pollError) Error() string {
+	return e.err.Error()
+}
+
+func (e *pollError) Unwrap() error {
+	return e.err
+}
+
+// execute runs one poll. Without a GET_URL it only reads the device, exports and logs
+// the reading, leaving the warning light alone. Without an outdoor dew point, e.g. during
+// an NWS outage, the reading is still posted and the warning light left as it is. The
+// request ID in ctx is sent to go-dew.
+func execute(ctx context.Context, config *Config, formula calculations.DewPointFormula, u units.System, calibrations *calibration.Store, filters *filter.Bank) error {
 	var indoorData models.IndoorSensorData
-	var outdoorDewpoint float32
+	var deviceErr error
+	var outdoorDewpoint *float32
 	var wg sync.WaitGroup
+	standalone := config.GetURL == ""
+	logger := logging.FromContext(ctx)
+
+	arduino, err := openDevice(config)
+	if err != nil {
+		return &pollError{stage: "device", err: err}
+	}
+	defer arduino.Close()
 
 	wg.Add(1)
 
 	// fetch indoor data asynchronously
 	go func() {
 		defer wg.Done()
-		if mode == "wifi" {
-			arduinoIP := os.Getenv("ARDUINO_IP")
-			endpoint := fmt.Sprintf("%s/data", arduinoIP)
-			wifiComm := wifi.NewWifiClient()
-			data, err := wifiComm.GetIndoorSensorData(endpoint)
-			if err != nil {
-				fmt.Println(err)
-				os.Exit(1)
-			}
-			indoorData = data
-		} else if mode == "usb" {
-			portName := os.Getenv("ARDUINO_PORT")
-			usbComm, err := usb.NewUsbCommunication(portName)
-			if err != nil {
-				fmt.Println(err)
-				os.Exit(1)
-			}
-			data, err := usbComm.GetIndoorSensorData()
-			if err != nil {
-				fmt.Println(err)
-				os.Exit(1)
-			}
-			indoorData = data
-		} else {
-			fmt.Printf("Invalid mode: %s\n", mode)
-			os.Exit(1)
-		}
+		_, span := tracing.Start(ctx, tracerName, "device.read")
+		indoorData, deviceErr = arduino.GetIndoorSensorData()
+		tracing.End(span, deviceErr)
 	}()
 
 	// fetch outdoor dewpoint asynchronously
-	httpRequests := requests.New()
-	dewpoint, err := httpRequests.GetOutdoorDewpoint()
-	if err != nil {
-		fmt.Println("Error fetching outdoor dewpoint:", err)
-		os.Exit(1)
+	httpRequests := requests.NewWithAPIKey(config.GetURL, config.SensorFeedPostURL, config.APIKey)
+	if !standalone {
+		dewpoint, err := httpRequests.GetOutdoorDewpoint(ctx)
+		if err != nil {
+			metrics.Error("outdoor")
+			logger.Warn("failed to fetch outdoor dewpoint, posting the indoor reading only", "error", err)
+		} else {
+			outdoorDewpoint = &dewpoint
+		}
 	}
-	outdoorDewpoint = dewpoint
 
 	wg.Wait()
+	if deviceErr != nil {
+		return &pollError{stage: "device", err: deviceErr}
+	}
+
+	// correct and smooth the reading before any decisions are made
+	calibrations.Apply(&indoorData)
+	filters.Apply(&indoorData, time.Now())
+	if config.FilterStateFile != "" {
+		if err := filters.Save(config.FilterStateFile); err != nil {
+			metrics.Error("filter_state")
+			logger.Warn("failed to save filter state", "error", err)
+		}
+	}
 
 	// prepare sensor feed data
 	ledState := indoorData.LedState
-	indoorDewpoint, err := calculations.DewPointCalculator(float64(indoorData.Temperature),
-		float64(indoorData.Humidity))
+	indoorDewpoint, err := formula.DewPoint(float64(indoorData.Temperature), float64(indoorData.Humidity))
 	if err != nil {
-		fmt.Println("dew point calculation error")
+		logger.Warn("dew point calculation error", "error", err)
+	}
+	metrics.SetReading(indoorData, indoorDewpoint, time.Now())
+
+	logger = logger.With(readingAttrs(indoorData, indoorDewpoint, u)...)
+
+	if standalone {
+		metrics.SetLedState(indoorData.DeviceID, ledState)
+		logger.Info("reading", "led_state", ledState)
+		return nil
+	}
+
+	// the warning light is on when windows should be kept closed, without an outdoor dew
+	// point it keeps its state
+	openWindows := !ledState
+	if outdoorDewpoint != nil {
+		dewpointDelta := indoorDewpoint - float64(*outdoorDewpoint)
+		advice, err := calculations.DefaultVentilationAdvisor.Advise(float64(indoorData.Temperature),
+			float64(indoorData.Humidity), float64(*outdoorDewpoint))
+		if err != nil {
+			logger.Warn("ventilation advice error", "error", err)
+		}
+		openWindows = advice.OpenWindows
+		logger = logger.With(
+			"outdoor_dewpoint", round2(u.Temperature(float64(*outdoorDewpoint))),
+			"dewpoint_delta", round2(u.TemperatureDelta(dewpointDelta)),
+			"reason", advice.Reason,
+			"confidence", round2(advice.Confidence),
+			"predicted_humidity", round2(advice.PredictedHumidity))
 	}
-	dewpointDelta := indoorDewpoint - float64(outdoorDewpoint)
-	openWindows := dewpointDelta > -1.0
 	humidityAlert := indoorData.Humidity > 60.0
 
+	var ledErr error
 	wg.Add(1)
 	// toggle the warning light asynchronously
 	go func() {
 		defer wg.Done()
 		if openWindows == ledState {
-			if mode == "usb" {
-				usbComm, err := usb.NewUsbCommunication(os.Getenv("ARDUINO_PORT"))
-				if err != nil {
-					fmt.Println(err)
-					os.Exit(1)
-				}
-				if err = usbComm.ToggleWarningLight(openWindows); err != nil {
-					fmt.Println(err)
-					os.Exit(1)
-				}
-			} else if mode == "wifi" {
-				wifiComm := wifi.NewWifiClient()
-				if err = wifiComm.ToggleWarningLight(openWindows); err != nil {
-					fmt.Println(err)
-					os.Exit(1)
-				}
-			}
+			_, span := tracing.Start(ctx, tracerName, "device.led")
+			ledErr = arduino.ToggleWarningLight(openWindows)
+			tracing.End(span, ledErr)
 		}
 	}()
 
 	// post sensor feed data asynchronously
 	payload, err := httpRequests.PrepareSensorFeedJSON(&indoorData, float32(indoorDewpoint),
-		outdoorDewpoint, float32(dewpointDelta), openWindows, humidityAlert)
+		outdoorDewpoint, openWindows, humidityAlert)
+	if err == nil {
+		err = httpRequests.PostSensorFeed(ctx, payload)
+	}
+
+	wg.Wait()
+	if ledErr != nil {
+		return &pollError{stage: "led", err: ledErr}
+	}
+	metrics.SetLedState(indoorData.DeviceID, !openWindows)
 	if err != nil {
-		fmt.Println(err)
-		return
+		metrics.Error("post")
+		logger.Error("failed to post sensor feed", "error", err)
 	}
-	if err = httpRequests.PostSensorFeed(payload); err != nil {
-		fmt.Println("Error posting sensor feed:", err)
+
+	logger.Info("reading", "open_windows", openWindows, "humidity_alert", humidityAlert)
+	logger.Debug("sensor feed", "payload", payload)
+	return nil
+}
+
+// readingAttrs describes a reading for the log in the configured units
+func readingAttrs(indoorData models.IndoorSensorData, indoorDewpoint float64, u units.System) []any {
+	attrs := []any{
+		"device_id", indoorData.DeviceID,
+		"units", u.String(),
+		"temperature", round2(u.Temperature(float64(indoorData.Temperature))),
+		"humidity", round2(float64(indoorData.Humidity)),
+		"dewpoint", round2(u.Temperature(indoorDewpoint)),
+	}
+	if indoorData.Raw != nil {
+		attrs = append(attrs,
+			"raw_temperature", round2(u.Temperature(float64(indoorData.Raw.Temperature))),
+			"raw_humidity", round2(float64(indoorData.Raw.Humidity)))
 	}
+	if metrics, err := calculations.ComputeMetrics(float64(indoorData.Temperature),
+		float64(indoorData.Humidity), calculations.StandardPressure); err == nil {
+		attrs = append(attrs, "absolute_humidity", round2(u.AbsoluteHumidity(metrics.AbsoluteHumidity)))
+	}
+	if indoorData.Info != nil {
+		attrs = append(attrs, "firmware", indoorData.Info.FirmwareVersion, "uptime_seconds", indoorData.Info.UptimeSeconds)
+	}
+	return attrs
+}
 
-	wg.Wait()
+func milliseconds(d time.Duration) float64 {
+	return round2(float64(d) / float64(time.Millisecond))
+}
+
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
 
-	fmt.Printf("Indoor Temperature: %.2f\n", indoorData.Temperature)
-	fmt.Printf("Indoor Humidity: %.2f\n", indoorData.Humidity)
-	fmt.Printf("Outdoor Dewpoint: %.2f\n", outdoorDewpoint)
-	fmt.Printf("Indoor Dewpoint: %.2f\n", indoorDewpoint)
-	fmt.Printf("Dewpoint Delta: %.2f\n", dewpointDelta)
-	fmt.Printf("Open Windows: %v\n", openWindows)
-	fmt.Printf("Humidity Alert: %v\n", humidityAlert)
-	fmt.Printf("Sensor Feed JSON Data: %s\n", string(payload))
+func printIndoorData(indoorData models.IndoorSensorData, u units.System) {
+	if indoorData.Info != nil {
+		fmt.Printf("Firmware: %s Sensor: %s Uptime: %ds Samples: %d\n", indoorData.Info.FirmwareVersion,
+			indoorData.Info.SensorModel, indoorData.Info.UptimeSeconds, indoorData.Info.SampleCount)
+		if len(indoorData.Info.Capabilities) > 0 {
+			fmt.Printf("Capabilities: %s\n", strings.Join(indoorData.Info.Capabilities, ", "))
+		}
+	}
+	if indoorData.Raw != nil && (indoorData.Raw.Temperature != indoorData.Temperature ||
+		indoorData.Raw.Humidity != indoorData.Humidity) {
+		fmt.Printf("Raw Temperature: %s\n", u.FormatTemperature(float64(indoorData.Raw.Temperature)))
+		fmt.Printf("Raw Humidity: %.2f %%\n", indoorData.Raw.Humidity)
+	}
+	fmt.Printf("Indoor Temperature: %s\n", u.FormatTemperature(float64(indoorData.Temperature)))
+	fmt.Printf("Indoor Humidity: %.2f %%\n", indoorData.Humidity)
+}
+
+func printIndoorMetrics(indoorData models.IndoorSensorData, u units.System) {
+	metrics, err := calculations.ComputeMetrics(float64(indoorData.Temperature),
+		float64(indoorData.Humidity), calculations.StandardPressure)
+	if err != nil {
+		fmt.Println("psychrometric calculation error:", err)
+		return
+	}
+	fmt.Printf("Absolute Humidity: %s\n", u.FormatAbsoluteHumidity(metrics.AbsoluteHumidity))
+	fmt.Printf("Mixing Ratio: %s\n", u.FormatMixingRatio(metrics.MixingRatio))
+	fmt.Printf("Specific Enthalpy: %.2f kJ/kg\n", metrics.SpecificEnthalpy)
+	fmt.Printf("Wet Bulb: %s\n", u.FormatTemperature(metrics.WetBulbTemperature))
+	if metrics.DewPoint < 0 && metrics.VaporPressure > 0 {
+		fmt.Printf("Frost Point: %s\n", u.FormatTemperature(metrics.FrostPoint))
+	}
+	fmt.Printf("Heat Index: %s\n", u.FormatTemperature(metrics.HeatIndex))
+	fmt.Printf("Humidex: %.2f\n", metrics.Humidex)
 }
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mugglemath/dewdrop-go/internal/sim"
+	"github.com/mugglemath/dewdrop-go/pkg/calculations"
+	"github.com/mugglemath/dewdrop-go/pkg/calibration"
+	"github.com/mugglemath/dewdrop-go/pkg/filter"
+	"github.com/mugglemath/dewdrop-go/pkg/units"
+)
+
+func TestExecute_OutdoorUnavailable(t *testing.T) {
+	device := sim.NewDevice(sim.DefaultConfig())
+	var ledRequests atomic.Int32
+	arduino := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/led") {
+			ledRequests.Add(1)
+		}
+		device.Handler().ServeHTTP(w, r)
+	}))
+	defer arduino.Close()
+
+	var posted []byte
+	goDew := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			http.Error(w, `{"error":"outdoor conditions not available yet"}`, http.StatusServiceUnavailable)
+			return
+		}
+		posted, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer goDew.Close()
+
+	config := &Config{
+		Mode:              "wifi",
+		ArduinoIP:         arduino.URL,
+		GetURL:            goDew.URL + "/weather/outdoor-dewpoint",
+		SensorFeedPostURL: goDew.URL + "/arduino/sensor-feed",
+	}
+	calibrations, err := calibration.Load("")
+	if err != nil {
+		t.Fatalf("failed to load calibration: %v", err)
+	}
+	filters, err := filter.NewBank("", "")
+	if err != nil {
+		t.Fatalf("failed to create filters: %v", err)
+	}
+	ledState := device.LedState()
+
+	err = execute(context.Background(), config, calculations.MagnusWater, units.Metric, calibrations, filters)
+	if err != nil {
+		t.Fatalf("expected the poll to succeed without outdoor data, got %v", err)
+	}
+
+	var feed map[string]any
+	if err := json.Unmarshal(posted, &feed); err != nil {
+		t.Fatalf("expected the indoor reading to be posted, got %q: %v", posted, err)
+	}
+	if feed["device_id"] != float64(424242) || feed["indoor_dewpoint"] == nil {
+		t.Errorf("expected the indoor reading of device 424242, got %v", feed)
+	}
+	if _, ok := feed["outdoor_dewpoint"]; ok {
+		t.Errorf("expected no outdoor dew point, got %v", feed["outdoor_dewpoint"])
+	}
+	if ledRequests.Load() != 0 || device.LedState() != ledState {
+		t.Errorf("expected the warning light to be left alone, got %d LED requests", ledRequests.Load())
+	}
+}
@@ -0,0 +1,126 @@
+// Package metrics exposes dewdrop's Prometheus metrics, so it can run as a sensor
+// exporter without go-dew
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mugglemath/dewdrop-go/pkg/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "dewdrop"
+
+var (
+	Registry = prometheus.NewRegistry()
+
+	temperature = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "temperature_celsius",
+		Help:      "Calibrated and filtered temperature of each device.",
+	}, []string{"device_id"})
+	humidity = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "humidity_percent",
+		Help:      "Calibrated and filtered relative humidity of each device.",
+	}, []string{"device_id"})
+	rawTemperature = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "raw_temperature_celsius",
+		Help:      "Temperature as reported by each device.",
+	}, []string{"device_id"})
+	rawHumidity = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "raw_humidity_percent",
+		Help:      "Relative humidity as reported by each device.",
+	}, []string{"device_id"})
+	dewpoint = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "dewpoint_celsius",
+		Help:      "Indoor dew point of each device.",
+	}, []string{"device_id"})
+	ledState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "led_state",
+		Help:      "1 if the warning light of each device is on.",
+	}, []string{"device_id"})
+	lastReading = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_reading_timestamp_seconds",
+		Help:      "Unix time of the latest reading from each device.",
+	}, []string{"device_id"})
+
+	pollDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "poll_duration_seconds",
+		Help:      "Time taken by each poll, including the go-dew round trip.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	})
+	pollsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "polls_total",
+		Help:      "Polls by result.",
+	}, []string{"result"})
+	errorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "errors_total",
+		Help:      "Errors by the stage of the poll they happened in.",
+	}, []string{"stage"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		temperature, humidity, rawTemperature, rawHumidity, dewpoint, ledState, lastReading,
+		pollDuration, pollsTotal, errorsTotal,
+	)
+}
+
+// Handler serves the registry in the Prometheus text or OpenMetrics format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{EnableOpenMetrics: true})
+}
+
+// SetReading updates the gauges of a device, Raw is used for the raw gauges when set
+func SetReading(data models.IndoorSensorData, indoorDewpoint float64, at time.Time) {
+	id := strconv.FormatUint(data.DeviceID, 10)
+	raw := models.RawReading{Temperature: data.Temperature, Humidity: data.Humidity}
+	if data.Raw != nil {
+		raw = *data.Raw
+	}
+	temperature.WithLabelValues(id).Set(float64(data.Temperature))
+	humidity.WithLabelValues(id).Set(float64(data.Humidity))
+	rawTemperature.WithLabelValues(id).Set(float64(raw.Temperature))
+	rawHumidity.WithLabelValues(id).Set(float64(raw.Humidity))
+	dewpoint.WithLabelValues(id).Set(indoorDewpoint)
+	lastReading.WithLabelValues(id).Set(float64(at.Unix()))
+}
+
+func SetLedState(deviceID uint64, on bool) {
+	value := 0.0
+	if on {
+		value = 1
+	}
+	ledState.WithLabelValues(strconv.FormatUint(deviceID, 10)).Set(value)
+}
+
+// Poll records the duration of a poll and, if it failed, the stage it failed in
+func Poll(elapsed time.Duration, stage string, err error) {
+	pollDuration.Observe(elapsed.Seconds())
+	if err != nil {
+		pollsTotal.WithLabelValues("error").Inc()
+		errorsTotal.WithLabelValues(stage).Inc()
+		return
+	}
+	pollsTotal.WithLabelValues("success").Inc()
+}
+
+// Error counts an error that didn't fail the poll, e.g. saving filter state
+func Error(stage string) {
+	errorsTotal.WithLabelValues(stage).Inc()
+}
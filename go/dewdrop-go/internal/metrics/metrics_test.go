@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mugglemath/dewdrop-go/pkg/models"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestSetReading(t *testing.T) {
+	data := models.IndoorSensorData{
+		DeviceID:    42,
+		Temperature: 21.5,
+		Humidity:    55,
+		Raw:         &models.RawReading{Temperature: 22, Humidity: 53},
+	}
+	SetReading(data, 12.1, time.Unix(1700000000, 0))
+	SetLedState(42, true)
+
+	tests := map[string][2]float64{
+		"temperature":     {testutil.ToFloat64(temperature.WithLabelValues("42")), 21.5},
+		"humidity":        {testutil.ToFloat64(humidity.WithLabelValues("42")), 55},
+		"raw temperature": {testutil.ToFloat64(rawTemperature.WithLabelValues("42")), 22},
+		"raw humidity":    {testutil.ToFloat64(rawHumidity.WithLabelValues("42")), 53},
+		"dewpoint":        {testutil.ToFloat64(dewpoint.WithLabelValues("42")), 12.1},
+		"led state":       {testutil.ToFloat64(ledState.WithLabelValues("42")), 1},
+		"last reading":    {testutil.ToFloat64(lastReading.WithLabelValues("42")), 1700000000},
+	}
+	for name, values := range tests {
+		if values[0] != values[1] {
+			t.Errorf("%s: expected %v, got %v", name, values[1], values[0])
+		}
+	}
+}
+
+func TestPoll(t *testing.T) {
+	Poll(200*time.Millisecond, "", nil)
+	Poll(time.Second, "device", errors.New("no response"))
+	Error("filter_state")
+
+	if actual := testutil.ToFloat64(pollsTotal.WithLabelValues("success")); actual != 1 {
+		t.Errorf("expected 1 successful poll, got %v", actual)
+	}
+	if actual := testutil.ToFloat64(errorsTotal.WithLabelValues("device")); actual != 1 {
+		t.Errorf("expected 1 device error, got %v", actual)
+	}
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	for _, expected := range []string{
+		"dewdrop_poll_duration_seconds_count 2",
+		`dewdrop_errors_total{stage="filter_state"} 1`,
+	} {
+		if !strings.Contains(recorder.Body.String(), expected) {
+			t.Errorf("expected %q in the response", expected)
+		}
+	}
+}
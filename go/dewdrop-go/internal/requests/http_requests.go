@@ -2,6 +2,7 @@ package requests
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,64 +12,108 @@ import (
 	"strconv"
 
 	"github.com/mugglemath/dewdrop-go/pkg/calculations"
+	"github.com/mugglemath/dewdrop-go/pkg/logging"
 	"github.com/mugglemath/dewdrop-go/pkg/models"
+	"github.com/mugglemath/dewdrop-go/pkg/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
+const tracerName = "github.com/mugglemath/dewdrop-go/internal/requests"
+
+// httpClient propagates the trace context to go-dew
+var httpClient = &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}
+
 type Client interface {
-	GetOutdoorDewpoint() (float32, error)
+	GetOutdoorDewpoint(ctx context.Context) (float32, error)
 	PrepareSensorFeedJSON(
 		indoorData *models.IndoorSensorData,
 		indoorDewpoint float32,
-		outdoorDewpoint float32,
-		dewpointDelta float32,
+		outdoorDewpoint *float32,
 		openWindows bool,
 		humidityAlert bool,
 	) (string, error)
-	PostSensorFeed(jsonString string) error
+	PostSensorFeed(ctx context.Context, jsonString string) error
 }
 
-type clientImpl struct{}
+type clientImpl struct {
+	getURL            string
+	sensorFeedPostURL string
+	apiKey            string
+}
 
+// New creates a client configured by GET_URL, POST_URL_SENSOR_FEED and API_KEY
 func New() Client {
-	return &clientImpl{}
+	return NewWithAPIKey(os.Getenv("GET_URL"), os.Getenv("POST_URL_SENSOR_FEED"), os.Getenv("API_KEY"))
+}
+
+func NewWithURLs(getURL, sensorFeedPostURL string) Client {
+	return NewWithAPIKey(getURL, sensorFeedPostURL, "")
+}
+
+// NewWithAPIKey creates a client that authenticates to go-dew with apiKey, if not empty
+func NewWithAPIKey(getURL, sensorFeedPostURL, apiKey string) Client {
+	return &clientImpl{
+		getURL:            getURL,
+		sensorFeedPostURL: sensorFeedPostURL,
+		apiKey:            apiKey,
+	}
 }
 
 // GetOutdoorDewpoint retrieves the outdoor dewpoint from a configured URL asynchronously.
-func (c *clientImpl) GetOutdoorDewpoint() (float32, error) {
-	getURL := os.Getenv("GET_URL")
-	getResponse, err := getRequestAsync(getURL)
+func (c *clientImpl) GetOutdoorDewpoint(ctx context.Context) (dewpoint float32, err error) {
+	ctx, span := tracing.Start(ctx, tracerName, "outdoor_dewpoint.fetch")
+	defer func() { tracing.End(span, err) }()
+
+	getResponse, err := c.getRequestAsync(ctx, c.getURL)
 	if err != nil {
 		return 0, err
 	}
 
-	fmt.Printf("GET outdoor dewpoint response: %s\n", getResponse)
+	logging.FromContext(ctx).Debug("outdoor dewpoint response", "body", getResponse)
 
-	dewpoint, err := strconv.ParseFloat(getResponse, 32)
+	value, err := strconv.ParseFloat(getResponse, 32)
 	if err != nil {
 		return 0, errors.New("invalid float format")
 	}
 
-	return float32(dewpoint), nil
+	return float32(value), nil
 }
 
-// PrepareSensorFeedJSON prepares the JSON payload for the sensor feed.
+// PrepareSensorFeedJSON prepares the JSON payload for the sensor feed. Without an
+// outdoor dew point the outdoor values are left out, go-dew fills them in.
 func (c *clientImpl) PrepareSensorFeedJSON(
 	indoorData *models.IndoorSensorData,
 	indoorDewpoint float32,
-	outdoorDewpoint float32,
-	dewpointDelta float32,
+	outdoorDewpoint *float32,
 	openWindows bool,
 	humidityAlert bool,
 ) (string, error) {
+	raw := models.RawReading{Temperature: indoorData.Temperature, Humidity: indoorData.Humidity}
+	if indoorData.Raw != nil {
+		raw = *indoorData.Raw
+	}
+
 	sensorFeed := map[string]interface{}{
-		"device_id":          indoorData.DeviceID,
-		"indoor_temperature": calculations.RoundTo2DecimalPlaces(indoorData.Temperature),
-		"indoor_humidity":    calculations.RoundTo2DecimalPlaces(indoorData.Humidity),
-		"indoor_dewpoint":    calculations.RoundTo2DecimalPlaces(indoorDewpoint),
-		"outdoor_dewpoint":   calculations.RoundTo2DecimalPlaces(outdoorDewpoint),
-		"dewpoint_delta":     calculations.RoundTo2DecimalPlaces(dewpointDelta),
-		"open_windows":       openWindows,
-		"humidity_alert":     humidityAlert,
+		"device_id":              indoorData.DeviceID,
+		"indoor_temperature":     calculations.RoundTo2DecimalPlaces(indoorData.Temperature),
+		"indoor_humidity":        calculations.RoundTo2DecimalPlaces(indoorData.Humidity),
+		"raw_indoor_temperature": calculations.RoundTo2DecimalPlaces(raw.Temperature),
+		"raw_indoor_humidity":    calculations.RoundTo2DecimalPlaces(raw.Humidity),
+		"indoor_dewpoint":        calculations.RoundTo2DecimalPlaces(indoorDewpoint),
+		"open_windows":           openWindows,
+		"humidity_alert":         humidityAlert,
+	}
+	if outdoorDewpoint != nil {
+		sensorFeed["outdoor_dewpoint"] = calculations.RoundTo2DecimalPlaces(*outdoorDewpoint)
+		sensorFeed["dewpoint_delta"] = calculations.RoundTo2DecimalPlaces(indoorDewpoint - *outdoorDewpoint)
+	}
+	if indoorData.Info != nil {
+		sensorFeed["device_info"] = indoorData.Info
+	}
+	metrics, err := calculations.ComputeMetrics(float64(indoorData.Temperature),
+		float64(indoorData.Humidity), calculations.StandardPressure)
+	if err == nil {
+		sensorFeed["indoor_metrics"] = roundMetrics(metrics)
 	}
 
 	jsonData, err := json.Marshal(sensorFeed)
@@ -79,27 +124,48 @@ func (c *clientImpl) PrepareSensorFeedJSON(
 	return string(jsonData), nil
 }
 
-// PostSensorFeed posts the sensor feed JSON data to a configured URL asynchronously.
-func (c *clientImpl) PostSensorFeed(jsonString string) error {
-	sensorFeedURL := os.Getenv("POST_URL_SENSOR_FEED")
+func roundMetrics(m calculations.Metrics) map[string]float32 {
+	return map[string]float32{
+		"frost_point":          calculations.RoundTo2DecimalPlaces(float32(m.FrostPoint)),
+		"vapor_pressure":       calculations.RoundTo2DecimalPlaces(float32(m.VaporPressure)),
+		"absolute_humidity":    calculations.RoundTo2DecimalPlaces(float32(m.AbsoluteHumidity)),
+		"mixing_ratio":         calculations.RoundTo2DecimalPlaces(float32(m.MixingRatio)),
+		"specific_enthalpy":    calculations.RoundTo2DecimalPlaces(float32(m.SpecificEnthalpy)),
+		"wet_bulb_temperature": calculations.RoundTo2DecimalPlaces(float32(m.WetBulbTemperature)),
+		"heat_index":           calculations.RoundTo2DecimalPlaces(float32(m.HeatIndex)),
+		"humidex":              calculations.RoundTo2DecimalPlaces(float32(m.Humidex)),
+	}
+}
+
+// PostSensorFeed posts the sensor feed JSON data to a configured URL asynchronously,
+// along with the request ID in ctx so go-dew's logs can be matched to the poll.
+func (c *clientImpl) PostSensorFeed(ctx context.Context, jsonString string) (err error) {
+	ctx, span := tracing.Start(ctx, tracerName, "sensor_feed.post")
+	defer func() { tracing.End(span, err) }()
+
 	data := make(map[string]interface{})
 
-	err := json.Unmarshal([]byte(jsonString), &data)
+	err = json.Unmarshal([]byte(jsonString), &data)
 	if err != nil {
 		return err
 	}
 
-	_, err = postRequestAsync(sensorFeedURL, data)
+	_, err = c.postRequestAsync(ctx, c.sensorFeedPostURL, data)
 	return err
 }
 
 // getRequestAsync performs an asynchronous GET request.
-func getRequestAsync(url string) (string, error) {
+func (c *clientImpl) getRequestAsync(ctx context.Context, url string) (string, error) {
 	resultChan := make(chan string)
 	errChan := make(chan error)
 
 	go func() {
-		resp, err := http.Get(url)
+		req, err := c.newRequest(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			errChan <- err
+			return
+		}
+		resp, err := httpClient.Do(req)
 		if err != nil {
 			errChan <- err
 			return
@@ -129,7 +195,7 @@ func getRequestAsync(url string) (string, error) {
 }
 
 // postRequestAsync performs an asynchronous POST request.
-func postRequestAsync(url string, data interface{}) (string, error) {
+func (c *clientImpl) postRequestAsync(ctx context.Context, url string, data interface{}) (string, error) {
 	resultChan := make(chan string)
 	errChan := make(chan error)
 
@@ -140,7 +206,13 @@ func postRequestAsync(url string, data interface{}) (string, error) {
 			return
 		}
 
-		resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
+		req, err := c.newRequest(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
+		if err != nil {
+			errChan <- err
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := httpClient.Do(req)
 		if err != nil {
 			errChan <- err
 			return
@@ -168,3 +240,18 @@ func postRequestAsync(url string, data interface{}) (string, error) {
 		return "", err
 	}
 }
+
+// newRequest creates a request carrying the API key and the request ID from ctx, if any
+func (c *clientImpl) newRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	if id := logging.RequestID(ctx); id != "" {
+		req.Header.Set(logging.RequestIDHeader, id)
+	}
+	return req, nil
+}
@@ -1,15 +1,21 @@
 package requests
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/mugglemath/dewdrop-go/pkg/calculations"
+	"github.com/mugglemath/dewdrop-go/pkg/logging"
 	"github.com/mugglemath/dewdrop-go/pkg/models"
+	"github.com/mugglemath/dewdrop-go/pkg/tracing"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func TestGetOutdoorDewpoint_Success(t *testing.T) {
@@ -25,7 +31,7 @@ func TestGetOutdoorDewpoint_Success(t *testing.T) {
 	defer os.Unsetenv("GET_URL")
 
 	client := New()
-	dewpoint, err := client.GetOutdoorDewpoint()
+	dewpoint, err := client.GetOutdoorDewpoint(context.Background())
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -44,7 +50,7 @@ func TestGetOutdoorDewpoint_Failure(t *testing.T) {
 	defer os.Unsetenv("GET_URL")
 
 	client := New()
-	_, err := client.GetOutdoorDewpoint()
+	_, err := client.GetOutdoorDewpoint(context.Background())
 	if err == nil {
 		t.Fatal("expected an error, got none")
 	}
@@ -58,8 +64,9 @@ func TestPrepareSensorFeedJSON(t *testing.T) {
 		Humidity:    60.0,
 		LedState:    true,
 	}
+	outdoorDewpoint := float32(10.0)
 
-	jsonString, err := client.PrepareSensorFeedJSON(indoorData, 15.5, 10.0, 5.5, true, false)
+	jsonString, err := client.PrepareSensorFeedJSON(indoorData, 15.5, &outdoorDewpoint, true, false)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -77,6 +84,33 @@ func TestPrepareSensorFeedJSON(t *testing.T) {
 	if indoorTemperature, ok := result["indoor_temperature"].(float64); !ok || indoorTemperature != float64(expectedTemperature) {
 		t.Errorf("expected indoor_temperature to be %.2f, got %.2f", expectedTemperature, indoorTemperature)
 	}
+	if result["outdoor_dewpoint"] != 10.0 || result["dewpoint_delta"] != 5.5 {
+		t.Errorf("expected outdoor dewpoint 10 and delta 5.5, got %v and %v", result["outdoor_dewpoint"], result["dewpoint_delta"])
+	}
+}
+
+func TestPrepareSensorFeedJSON_WithoutOutdoor(t *testing.T) {
+	client := New()
+	indoorData := &models.IndoorSensorData{DeviceID: 12345, Temperature: 22.5, Humidity: 60.0}
+
+	jsonString, err := client.PrepareSensorFeedJSON(indoorData, 15.5, nil, false, false)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal([]byte(jsonString), &result); err != nil {
+		t.Fatalf("failed to unmarshal JSON: %v", err)
+	}
+	if _, ok := result["outdoor_dewpoint"]; ok {
+		t.Errorf("expected no outdoor_dewpoint, got %v", result["outdoor_dewpoint"])
+	}
+	if _, ok := result["dewpoint_delta"]; ok {
+		t.Errorf("expected no dewpoint_delta, got %v", result["dewpoint_delta"])
+	}
+	if result["indoor_dewpoint"] != 15.5 {
+		t.Errorf("expected indoor_dewpoint 15.5, got %v", result["indoor_dewpoint"])
+	}
 }
 
 func TestPostSensorFeed_Success(t *testing.T) {
@@ -104,12 +138,88 @@ func TestPostSensorFeed_Success(t *testing.T) {
 	"open_windows":true,
 	"humidity_alert":false}`
 
-	err := client.PostSensorFeed(sensorFeedJSON)
+	err := client.PostSensorFeed(context.Background(), sensorFeedJSON)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
 }
 
+func TestPostSensorFeed_RequestID(t *testing.T) {
+	var requestID string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID = r.Header.Get(logging.RequestIDHeader)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer mockServer.Close()
+
+	client := NewWithURLs("", mockServer.URL)
+	ctx := logging.WithRequestID(context.Background(), "poll-1")
+	if err := client.PostSensorFeed(ctx, `{"device_id":12345}`); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if requestID != "poll-1" {
+		t.Errorf("expected request ID poll-1, got %q", requestID)
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+	var authorization []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = append(authorization, r.Header.Get("Authorization"))
+		w.Write([]byte("12.5"))
+	}))
+	defer mockServer.Close()
+
+	client := NewWithAPIKey(mockServer.URL, mockServer.URL, "secret-key")
+	if _, err := client.GetOutdoorDewpoint(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := client.PostSensorFeed(context.Background(), `{"device_id":12345}`); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(authorization) != 2 || authorization[0] != "Bearer secret-key" || authorization[1] != "Bearer secret-key" {
+		t.Errorf("expected both requests to carry the API key, got %q", authorization)
+	}
+}
+
+func TestPostSensorFeed_TraceContext(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	shutdown, err := tracing.Install(context.Background(), "dewdrop", sdktrace.WithSyncer(exporter))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer shutdown(context.Background())
+
+	var traceparent string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer mockServer.Close()
+
+	client := NewWithURLs("", mockServer.URL)
+	ctx, poll := tracing.Start(context.Background(), "test", "poll")
+	if err := client.PostSensorFeed(ctx, `{"device_id":12345}`); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	poll.End()
+
+	traceID := poll.SpanContext().TraceID().String()
+	if !strings.Contains(traceparent, traceID) {
+		t.Errorf("expected traceparent with trace %s, got %q", traceID, traceparent)
+	}
+	names := map[string]bool{}
+	for _, span := range exporter.GetSpans() {
+		names[span.Name] = true
+		if span.SpanContext.TraceID().String() != traceID {
+			t.Errorf("expected span %s in trace %s", span.Name, traceID)
+		}
+	}
+	if !names["sensor_feed.post"] || !names["HTTP POST"] {
+		t.Errorf("expected sensor_feed.post and HTTP POST spans, got %v", names)
+	}
+}
+
 func TestPostSensorFeed_Failure(t *testing.T) {
 	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusBadRequest)
@@ -129,8 +239,36 @@ func TestPostSensorFeed_Failure(t *testing.T) {
 	"open_windows":true,
 	"humidity_alert":false}`
 
-	err := client.PostSensorFeed(sensorFeedJSON)
+	err := client.PostSensorFeed(context.Background(), sensorFeedJSON)
 	if err == nil {
 		t.Fatal("expected an error, got none")
 	}
 }
+
+func TestPrepareSensorFeedJSON_RawValues(t *testing.T) {
+	client := New()
+	indoorData := &models.IndoorSensorData{
+		DeviceID:    12345,
+		Temperature: 22.0,
+		Humidity:    58.0,
+		Raw:         &models.RawReading{Temperature: 22.8, Humidity: 62.0},
+	}
+	outdoorDewpoint := float32(10.0)
+
+	jsonString, err := client.PrepareSensorFeedJSON(indoorData, 15.5, &outdoorDewpoint, true, false)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal([]byte(jsonString), &result); err != nil {
+		t.Fatalf("failed to unmarshal JSON: %v", err)
+	}
+
+	if result["indoor_temperature"] != 22.0 || result["raw_indoor_temperature"] != 22.8 {
+		t.Errorf("expected corrected and raw temperature, got %v and %v", result["indoor_temperature"], result["raw_indoor_temperature"])
+	}
+	if result["indoor_humidity"] != 58.0 || result["raw_indoor_humidity"] != 62.0 {
+		t.Errorf("expected corrected and raw humidity, got %v and %v", result["indoor_humidity"], result["raw_indoor_humidity"])
+	}
+}
@@ -0,0 +1,55 @@
+package sim
+
+import (
+	"net/http"
+)
+
+// Handler serves the /data and /led endpoints of the ESP32 sketch
+func (d *Device) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/data", d.handleData)
+	mux.HandleFunc("/led", d.handleLed)
+	return mux
+}
+
+func (d *Device) handleData(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	d.delay()
+
+	message, contentType := d.csv(), "text/plain"
+	if r.URL.Query().Get("format") == "json" && !d.config.Legacy {
+		message, contentType = d.json(), "application/json"
+	}
+
+	switch d.nextFault() {
+	case faultDrop:
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+		return
+	case faultGarbage:
+		message = string(d.garble([]byte(message)))
+	case faultFailure:
+		message = "-1"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write([]byte(message + "\n"))
+}
+
+func (d *Device) handleLed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	d.delay()
+
+	if !r.URL.Query().Has("state") {
+		http.Error(w, "Bad Request: Missing 'state' parameter", http.StatusBadRequest)
+		return
+	}
+	state := r.URL.Query().Get("state")
+	d.setLedState(state == "1")
+	_, _ = w.Write([]byte("LED state changed to " + state))
+}
@@ -0,0 +1,55 @@
+//go:build linux
+
+package sim
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// OpenPTY opens a raw pseudo-terminal pair. Serial clients open the returned
+// slave name, the simulator serves the master. The slave is kept open so the
+// master doesn't see EIO between client connections.
+func OpenPTY() (*os.File, *os.File, string, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	fd := int(master.Fd())
+	if err := unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+		master.Close()
+		return nil, nil, "", fmt.Errorf("failed to unlock pty: %w", err)
+	}
+	n, err := unix.IoctlGetInt(fd, unix.TIOCGPTN)
+	if err != nil {
+		master.Close()
+		return nil, nil, "", fmt.Errorf("failed to get pty number: %w", err)
+	}
+	name := fmt.Sprintf("/dev/pts/%d", n)
+
+	slave, err := os.OpenFile(name, os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, "", err
+	}
+
+	termios, err := unix.IoctlGetTermios(int(slave.Fd()), unix.TCGETS)
+	if err != nil {
+		master.Close()
+		slave.Close()
+		return nil, nil, "", fmt.Errorf("failed to get pty attributes: %w", err)
+	}
+	termios.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	termios.Oflag &^= unix.OPOST
+	termios.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	if err := unix.IoctlSetTermios(int(slave.Fd()), unix.TCSETS, termios); err != nil {
+		master.Close()
+		slave.Close()
+		return nil, nil, "", fmt.Errorf("failed to set pty attributes: %w", err)
+	}
+
+	return master, slave, name, nil
+}
@@ -0,0 +1,41 @@
+//go:build linux
+
+package sim
+
+import (
+	"testing"
+
+	"github.com/mugglemath/dewdrop-go/internal/usb"
+)
+
+func TestOpenPTY_UsbClient(t *testing.T) {
+	master, slave, name, err := OpenPTY()
+	if err != nil {
+		t.Skipf("pseudo-terminals unavailable: %v", err)
+	}
+	defer master.Close()
+	defer slave.Close()
+
+	device := newTestDevice(Config{})
+	go func() { _ = device.ServeSerial(master) }()
+
+	usbComm, err := usb.NewUsbCommunication(name, usb.ProtocolAuto)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	data, err := usbComm.GetIndoorSensorData()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if data.DeviceID != 123 || data.Info == nil {
+		t.Errorf("unexpected data %+v", data)
+	}
+
+	if err := usbComm.ToggleWarningLight(false); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !device.LedState() {
+		t.Error("expected LED to be on")
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package sim
+
+import (
+	"errors"
+	"os"
+)
+
+// OpenPTY is only implemented on Linux
+func OpenPTY() (*os.File, *os.File, string, error) {
+	return nil, nil, "", errors.New("serial simulation requires Linux pseudo-terminals")
+}
@@ -0,0 +1,87 @@
+package sim
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/mugglemath/dewdrop-go/internal/usb"
+)
+
+// ServeSerial answers legacy single character commands and framed requests
+// on rw until it returns an error
+func (d *Device) ServeSerial(rw io.ReadWriter) error {
+	reader := bufio.NewReader(rw)
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return err
+		}
+
+		var response []byte
+		switch {
+		case b == '@' && !d.config.Legacy:
+			line, err := reader.ReadBytes('\n')
+			if err != nil {
+				return err
+			}
+			response = d.handleFrame(append([]byte{'@'}, line...))
+		case b == 'v' && !d.config.Legacy:
+			response = usb.Frame{Command: usb.CommandVersion, Payload: []byte(FirmwareVersion)}.Encode()
+		case b == 'd':
+			response = []byte(d.csv() + "\r\n")
+		case b == '0' || b == '1':
+			d.setLedState(b == '1')
+			response = []byte("a")
+		}
+		if response == nil {
+			continue
+		}
+
+		switch d.nextFault() {
+		case faultDrop:
+			continue
+		case faultGarbage:
+			response = d.garble(response)
+		case faultFailure:
+			response = []byte("-1\r\n")
+		}
+
+		d.delay()
+		if _, err := rw.Write(response); err != nil {
+			return err
+		}
+	}
+}
+
+// handleFrame answers a framed request, ignoring frames that fail to decode
+// just like the sketch does
+func (d *Device) handleFrame(line []byte) []byte {
+	request, err := usb.DecodeFrame(line)
+	if err != nil {
+		return nil
+	}
+
+	response := usb.Frame{ID: request.ID, Command: request.Command}
+	switch request.Command {
+	case usb.CommandVersion:
+		response.Payload = []byte(FirmwareVersion)
+	case usb.CommandData:
+		if string(request.Payload) == "json" {
+			response.Payload = []byte(d.json())
+		} else {
+			response.Payload = []byte(d.csv())
+		}
+	case usb.CommandLED:
+		if state := string(request.Payload); state == "0" || state == "1" {
+			d.setLedState(state == "1")
+			response.Payload = []byte("a")
+		} else {
+			response.Command = usb.CommandError
+			response.Payload = []byte("invalid LED state")
+		}
+	default:
+		response.Command = usb.CommandError
+		response.Payload = []byte("unknown command")
+	}
+	return response.Encode()
+}
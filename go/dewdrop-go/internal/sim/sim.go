@@ -0,0 +1,181 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	FirmwareVersion = "dewdrop-sim/1"
+	SensorModel     = "SIM31"
+)
+
+// Config describes the simulated climate and the faults to inject
+type Config struct {
+	DeviceID uint64
+
+	BaseTemperature      float64
+	TemperatureAmplitude float64
+	BaseHumidity         float64
+	HumidityAmplitude    float64
+	Period               time.Duration
+	Noise                float64
+	// Speed runs the curves faster than real time for demos
+	Speed float64
+
+	Latency     time.Duration
+	DropRate    float64
+	GarbageRate float64
+	FailureRate float64
+	SpikeRate   float64
+
+	// Legacy emulates the old sketches: CSV only, no framed protocol
+	Legacy bool
+	Seed   int64
+}
+
+func DefaultConfig() Config {
+	return Config{
+		DeviceID:             424242,
+		BaseTemperature:      21.0,
+		TemperatureAmplitude: 3.0,
+		BaseHumidity:         50.0,
+		HumidityAmplitude:    10.0,
+		Period:               24 * time.Hour,
+		Noise:                0.05,
+		Speed:                1,
+		Seed:                 time.Now().UnixNano(),
+	}
+}
+
+type fault int
+
+const (
+	faultNone fault = iota
+	faultDrop
+	faultGarbage
+	faultFailure
+)
+
+// Device emulates the Nano ESP32 sketch
+type Device struct {
+	config      Config
+	start       time.Time
+	mu          sync.Mutex
+	rng         *rand.Rand
+	ledState    bool
+	sampleCount uint64
+}
+
+func NewDevice(config Config) *Device {
+	if config.Period <= 0 {
+		config.Period = 24 * time.Hour
+	}
+	if config.Speed <= 0 {
+		config.Speed = 1
+	}
+	return &Device{
+		config: config,
+		start:  time.Now(),
+		rng:    rand.New(rand.NewSource(config.Seed)),
+	}
+}
+
+// Reading returns the current temperature and humidity on the configured curves
+func (d *Device) Reading() (float64, float64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	elapsed := time.Since(d.start).Seconds() * d.config.Speed
+	phase := math.Sin(2 * math.Pi * elapsed / d.config.Period.Seconds())
+
+	temperature := d.config.BaseTemperature + d.config.TemperatureAmplitude*phase + d.rng.NormFloat64()*d.config.Noise
+	humidity := d.config.BaseHumidity - d.config.HumidityAmplitude*phase + d.rng.NormFloat64()*d.config.Noise
+	if d.rng.Float64() < d.config.SpikeRate {
+		temperature += (d.rng.Float64()*2 - 1) * 10
+		humidity += (d.rng.Float64()*2 - 1) * 30
+	}
+	d.sampleCount++
+
+	return temperature, math.Max(0, math.Min(100, humidity))
+}
+
+func (d *Device) LedState() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.ledState
+}
+
+func (d *Device) setLedState(state bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.ledState = state
+}
+
+// csv formats a reading the way the sketch prints floats
+func (d *Device) csv() string {
+	temperature, humidity := d.Reading()
+	return fmt.Sprintf("%d,%.2f,%.2f,%s", d.config.DeviceID, temperature, humidity, boolDigit(d.LedState()))
+}
+
+func (d *Device) json() string {
+	temperature, humidity := d.Reading()
+	d.mu.Lock()
+	uptime := uint64(time.Since(d.start).Seconds())
+	samples := d.sampleCount
+	d.mu.Unlock()
+	return fmt.Sprintf(`{"device_id":"%d","temperature":%.2f,"humidity":%.2f,"led_state":%t,`+
+		`"firmware_version":"%s","sensor_model":"%s","uptime_seconds":%d,"sample_count":%d,`+
+		`"capabilities":["csv","json","framed"],"metrics":{"rssi":-55}}`,
+		d.config.DeviceID, temperature, humidity, d.LedState(),
+		FirmwareVersion, SensorModel, uptime, samples)
+}
+
+// nextFault picks the fault to inject into the next response, if any
+func (d *Device) nextFault() fault {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	r := d.rng.Float64()
+	switch {
+	case r < d.config.DropRate:
+		return faultDrop
+	case r < d.config.DropRate+d.config.GarbageRate:
+		return faultGarbage
+	case r < d.config.DropRate+d.config.GarbageRate+d.config.FailureRate:
+		return faultFailure
+	default:
+		return faultNone
+	}
+}
+
+// garble corrupts a response the way a noisy cable would
+func (d *Device) garble(response []byte) []byte {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	garbled := []byte(strings.Repeat("~", d.rng.Intn(4)))
+	garbled = append(garbled, response...)
+	if len(garbled) > 2 {
+		i := d.rng.Intn(len(garbled) - 1)
+		garbled[i] ^= byte(1 << d.rng.Intn(7))
+	}
+	return garbled
+}
+
+func (d *Device) delay() {
+	if d.config.Latency > 0 {
+		time.Sleep(d.config.Latency)
+	}
+}
+
+func boolDigit(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
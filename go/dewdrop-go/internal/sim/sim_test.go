@@ -0,0 +1,163 @@
+package sim
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mugglemath/dewdrop-go/internal/usb"
+	"github.com/mugglemath/dewdrop-go/internal/wifi"
+	"github.com/mugglemath/dewdrop-go/pkg/utils"
+)
+
+func newTestDevice(config Config) *Device {
+	config.DeviceID = 123
+	config.BaseTemperature = -5
+	config.BaseHumidity = 80
+	config.Period = time.Hour
+	config.Seed = 1
+	return NewDevice(config)
+}
+
+func TestReading_FollowsCurve(t *testing.T) {
+	device := newTestDevice(Config{TemperatureAmplitude: 2, HumidityAmplitude: 5})
+
+	temperature, humidity := device.Reading()
+	if temperature < -7 || temperature > -3 {
+		t.Errorf("expected temperature within amplitude of -5, got %f", temperature)
+	}
+	if humidity < 75 || humidity > 85 {
+		t.Errorf("expected humidity within amplitude of 80, got %f", humidity)
+	}
+}
+
+func TestReading_ClampsHumidity(t *testing.T) {
+	device := newTestDevice(Config{BaseHumidity: 80, HumidityAmplitude: 0})
+	device.config.BaseHumidity = 140
+
+	if _, humidity := device.Reading(); humidity != 100 {
+		t.Errorf("expected humidity to be clamped to 100, got %f", humidity)
+	}
+}
+
+func TestHandler_WifiClient(t *testing.T) {
+	device := newTestDevice(Config{})
+	server := httptest.NewServer(device.Handler())
+	defer server.Close()
+	wifiComm := wifi.NewWifiClient(server.URL)
+
+	data, err := wifiComm.GetIndoorSensorData(server.URL + "/data")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if data.DeviceID != 123 || data.Temperature > -4 || data.Info == nil || data.Info.FirmwareVersion != FirmwareVersion {
+		t.Errorf("unexpected data %+v", data)
+	}
+
+	if err := wifiComm.ToggleWarningLight(false); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !device.LedState() {
+		t.Error("expected LED to be on")
+	}
+}
+
+func TestHandler_LegacyCSV(t *testing.T) {
+	device := newTestDevice(Config{Legacy: true})
+	server := httptest.NewServer(device.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/data?format=json")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer resp.Body.Close()
+
+	line, _ := bufio.NewReader(resp.Body).ReadString('\n')
+	if !utils.IsValidDataFormat(line[:len(line)-1]) {
+		t.Errorf("expected legacy CSV, got %q", line)
+	}
+}
+
+func TestHandler_Faults(t *testing.T) {
+	device := newTestDevice(Config{DropRate: 1})
+	server := httptest.NewServer(device.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/data")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", resp.StatusCode)
+	}
+}
+
+func TestHandler_MissingLedState(t *testing.T) {
+	device := newTestDevice(Config{})
+	server := httptest.NewServer(device.Handler())
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/led", "application/json", nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestServeSerial_Framed(t *testing.T) {
+	device := newTestDevice(Config{})
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() { _ = device.ServeSerial(server) }()
+
+	request := usb.Frame{ID: 7, Command: usb.CommandLED, Payload: []byte("1")}
+	if _, err := client.Write(request.Encode()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	line, err := bufio.NewReader(client).ReadBytes('\n')
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	response, err := usb.DecodeFrame(line)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if response.ID != 7 || response.Command != usb.CommandLED || string(response.Payload) != "a" {
+		t.Errorf("unexpected response %+v", response)
+	}
+	if !device.LedState() {
+		t.Error("expected LED to be on")
+	}
+}
+
+func TestServeSerial_Legacy(t *testing.T) {
+	device := newTestDevice(Config{Legacy: true})
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() { _ = device.ServeSerial(server) }()
+
+	reader := bufio.NewReader(client)
+	if _, err := client.Write([]byte("v")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := client.Write([]byte("d")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := utils.ParseIndoorSensorData(line); err != nil {
+		t.Errorf("expected a CSV reading, got %q (%v)", line, err)
+	}
+}
@@ -0,0 +1,101 @@
+package usb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// A frame is a single line on the serial port:
+//
+//	@IICLLLL<payload>*CCCC\n
+//
+// II is the command ID in hex, C is the command character, LLLL is the payload
+// length in hex and CCCC is the CRC-16/CCITT-FALSE of everything between '@'
+// and '*'. Responses echo the ID and command of the request they answer.
+const (
+	CommandVersion byte = 'V'
+	CommandData    byte = 'D'
+	CommandLED     byte = 'L'
+	CommandError   byte = 'E'
+
+	frameStart      = '@'
+	frameCRCMarker  = '*'
+	frameHeaderLen  = 7
+	frameTrailerLen = 5
+	maxPayloadLen   = 1024
+)
+
+var (
+	ErrFrameMalformed = errors.New("malformed frame")
+	ErrFrameChecksum  = errors.New("frame checksum mismatch")
+)
+
+type Frame struct {
+	ID      uint8
+	Command byte
+	Payload []byte
+}
+
+// Encode serializes the frame including the trailing newline
+func (f Frame) Encode() []byte {
+	body := fmt.Sprintf("%02X%c%04X%s", f.ID, f.Command, len(f.Payload), f.Payload)
+	return []byte(fmt.Sprintf("%c%s%c%04X\n", frameStart, body, frameCRCMarker, crc16([]byte(body))))
+}
+
+// DecodeFrame parses a single line received from the Arduino
+func DecodeFrame(line []byte) (Frame, error) {
+	line = bytes.TrimSpace(line)
+	if len(line) < 1+frameHeaderLen+frameTrailerLen || line[0] != frameStart {
+		return Frame{}, ErrFrameMalformed
+	}
+	body := line[1 : len(line)-frameTrailerLen]
+	trailer := line[len(line)-frameTrailerLen:]
+	if trailer[0] != frameCRCMarker {
+		return Frame{}, ErrFrameMalformed
+	}
+
+	id, err := strconv.ParseUint(string(body[0:2]), 16, 8)
+	if err != nil {
+		return Frame{}, ErrFrameMalformed
+	}
+	length, err := strconv.ParseUint(string(body[3:7]), 16, 16)
+	if err != nil || length > maxPayloadLen {
+		return Frame{}, ErrFrameMalformed
+	}
+	crc, err := strconv.ParseUint(string(trailer[1:]), 16, 16)
+	if err != nil {
+		return Frame{}, ErrFrameMalformed
+	}
+
+	payload := body[frameHeaderLen:]
+	if uint64(len(payload)) != length {
+		return Frame{}, fmt.Errorf("%w: payload length %d, expected %d", ErrFrameMalformed, len(payload), length)
+	}
+	if uint16(crc) != crc16(body) {
+		return Frame{}, ErrFrameChecksum
+	}
+
+	return Frame{
+		ID:      uint8(id),
+		Command: body[2],
+		Payload: append([]byte(nil), payload...),
+	}, nil
+}
+
+// crc16 computes CRC-16/CCITT-FALSE (poly 0x1021, init 0xFFFF)
+func crc16(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,63 @@
+package usb
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFrameEncodeDecode(t *testing.T) {
+	frame := Frame{ID: 0x2A, Command: CommandData, Payload: []byte("123,25.55,60.01,1")}
+
+	decoded, err := DecodeFrame(frame.Encode())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if decoded.ID != frame.ID || decoded.Command != frame.Command || string(decoded.Payload) != string(frame.Payload) {
+		t.Errorf("expected frame %+v, got %+v", frame, decoded)
+	}
+}
+
+func TestFrameEncode_Format(t *testing.T) {
+	frame := Frame{ID: 1, Command: CommandLED, Payload: []byte("1")}
+	expected := "@01L00011*"
+
+	encoded := string(frame.Encode())
+	if encoded[:len(expected)] != expected {
+		t.Errorf("expected frame to start with %s, got %s", expected, encoded)
+	}
+	if encoded[len(encoded)-1] != '\n' {
+		t.Errorf("expected frame to end with a newline, got %q", encoded)
+	}
+}
+
+func TestDecodeFrame_ChecksumMismatch(t *testing.T) {
+	encoded := Frame{ID: 1, Command: CommandData, Payload: []byte("123,25.55,60.01,1")}.Encode()
+	encoded[10] = '9'
+
+	_, err := DecodeFrame(encoded)
+	if !errors.Is(err, ErrFrameChecksum) {
+		t.Errorf("expected error %v, got %v", ErrFrameChecksum, err)
+	}
+}
+
+func TestDecodeFrame_Malformed(t *testing.T) {
+	tests := []string{
+		"",
+		"123,25.55,60.01,1",
+		"@01D0005abc*0000",
+		"@ZZD0000*0000",
+		"@01D0000#0000",
+	}
+	for _, test := range tests {
+		if _, err := DecodeFrame([]byte(test)); !errors.Is(err, ErrFrameMalformed) {
+			t.Errorf("expected error %v for %q, got %v", ErrFrameMalformed, test, err)
+		}
+	}
+}
+
+func TestCRC16(t *testing.T) {
+	// CRC-16/CCITT-FALSE check value
+	if crc := crc16([]byte("123456789")); crc != 0x29B1 {
+		t.Errorf("expected checksum 0x29B1, got 0x%04X", crc)
+	}
+}
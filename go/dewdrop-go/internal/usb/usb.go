@@ -4,7 +4,10 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"strconv"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/mugglemath/dewdrop-go/pkg/models"
@@ -15,6 +18,7 @@ import (
 type Client interface {
 	GetIndoorSensorData() (models.IndoorSensorData, error)
 	ToggleWarningLight(openWindows bool) error
+	Close() error
 }
 
 type SerialPort interface {
@@ -22,53 +26,98 @@ type SerialPort interface {
 	Read(buffer []byte) (int, error)
 }
 
+// Protocol selects how commands are exchanged with the Arduino
+type Protocol int
+
+const (
+	// ProtocolLegacy sends single character commands and matches responses by regex
+	ProtocolLegacy Protocol = iota
+	// ProtocolFramed sends checksummed frames with command IDs
+	ProtocolFramed
+	// ProtocolAuto probes the firmware and falls back to ProtocolLegacy
+	ProtocolAuto
+)
+
+const (
+	frameTimeout     = 500 * time.Millisecond
+	maxFrameAttempts = 3
+	probeTimeout     = 300 * time.Millisecond
+	probeCommand     = "v"
+)
+
+var errReadTimeout = errors.New("timed out waiting for response")
+
 type usbClientImpl struct {
-	port SerialPort
+	portName string
+	port     SerialPort
+	protocol Protocol
+	nextID   uint8
+	pending  []byte
+}
+
+// session is what a client leaves behind for the next one on its port
+type session struct {
+	protocol Protocol
+	nextID   uint8
 }
 
-func NewUsbCommunication(portName string) (*usbClientImpl, error) {
+// sessions keeps the negotiated protocol and the last command ID of each port across
+// polls, so reopening the port doesn't probe again or reuse IDs. A sketch flashed with
+// the other protocol is picked up on the next start of dewdrop.
+var (
+	sessionsMu sync.Mutex
+	sessions   = map[string]session{}
+)
+
+// ParseProtocol converts the USB_PROTOCOL setting into a Protocol
+func ParseProtocol(value string) (Protocol, error) {
+	switch strings.ToLower(value) {
+	case "", "auto":
+		return ProtocolAuto, nil
+	case "framed":
+		return ProtocolFramed, nil
+	case "legacy":
+		return ProtocolLegacy, nil
+	default:
+		return ProtocolAuto, fmt.Errorf("invalid USB protocol: %s", value)
+	}
+}
+
+func NewUsbCommunication(portName string, protocol Protocol) (*usbClientImpl, error) {
 	c := &serial.Config{
-		Name: portName,
-		Baud: 115200,
+		Name:        portName,
+		Baud:        115200,
+		ReadTimeout: 100 * time.Millisecond,
 	}
 	port, err := serial.OpenPort(c)
 	if err != nil {
 		return nil, err
 	}
-	return &usbClientImpl{port: port}, nil
+	return newClient(portName, port, protocol), nil
+}
+
+// newClient resumes the session of the port, if there is one
+func newClient(portName string, port SerialPort, protocol Protocol) *usbClientImpl {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	usb := &usbClientImpl{portName: portName, port: port, protocol: protocol}
+	if s, ok := sessions[portName]; ok {
+		usb.nextID = s.nextID
+		if protocol == ProtocolAuto {
+			usb.protocol = s.protocol
+		}
+	}
+	return usb
 }
 
 // GetIndoorSensorData retrieves the sensor data from the Arduino
 func (usb *usbClientImpl) GetIndoorSensorData() (models.IndoorSensorData, error) {
-	command := "d"
-	response, err := usb.getArduinoResponse(command, 50*time.Millisecond)
+	response, err := usb.sendCommand("d", CommandData, "json")
 	if err != nil {
 		return models.IndoorSensorData{}, err
 	}
 
-	parts := utils.SplitAndTrim(response, ',')
-	if len(parts) < 4 {
-		return models.IndoorSensorData{}, errors.New("invalid data format")
-	}
-
-	deviceID, _ := strconv.ParseUint(parts[0], 10, 64)
-	temperature, _ := strconv.ParseFloat(parts[1], 32)
-	humidity, _ := strconv.ParseFloat(parts[2], 32)
-	var ledState bool
-	if parts[3] == "1" {
-		ledState = true
-	} else if parts[3] == "0" {
-		ledState = false
-	} else {
-		return models.IndoorSensorData{}, errors.New("invalid LED state value")
-	}
-
-	return models.IndoorSensorData{
-		DeviceID:    deviceID,
-		Temperature: float32(temperature),
-		Humidity:    float32(humidity),
-		LedState:    ledState,
-	}, nil
+	return utils.ParseIndoorSensorData(response)
 }
 
 // ToggleWarningLight toggles the blinking yellow light on the Arduino
@@ -78,26 +127,150 @@ func (usb *usbClientImpl) ToggleWarningLight(openWindows bool) error {
 		command = "0"
 	}
 
-	_, err := usb.getArduinoResponse(command, 50*time.Millisecond)
+	_, err := usb.sendCommand(command, CommandLED, command)
 	if err != nil {
 		return err
 	}
 
-	if openWindows {
-		fmt.Println("Warning light OFF")
-	} else {
-		fmt.Println("Warning light ON")
-	}
+	slog.Info("warning light switched", "on", !openWindows)
+
+	return nil
+}
 
+// Close releases the serial port and keeps the session for the next client
+func (usb *usbClientImpl) Close() error {
+	if usb.protocol != ProtocolAuto {
+		sessionsMu.Lock()
+		sessions[usb.portName] = session{protocol: usb.protocol, nextID: usb.nextID}
+		sessionsMu.Unlock()
+	}
+	if closer, ok := usb.port.(io.Closer); ok {
+		return closer.Close()
+	}
 	return nil
 }
 
+// sendCommand sends a command using the negotiated protocol
+func (usb *usbClientImpl) sendCommand(legacyCommand string, command byte, payload string) (string, error) {
+	if usb.protocol == ProtocolAuto {
+		if err := usb.negotiateProtocol(); err != nil {
+			return "", err
+		}
+	}
+	if usb.protocol == ProtocolFramed {
+		return usb.request(command, payload)
+	}
+	return usb.getArduinoResponse(legacyCommand, 50*time.Millisecond)
+}
+
+// negotiateProtocol asks the firmware for its version, which only framed sketches answer
+func (usb *usbClientImpl) negotiateProtocol() error {
+	if err := usb.writeData(probeCommand); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(probeTimeout)
+	for {
+		line, err := usb.readLine(deadline)
+		if errors.Is(err, errReadTimeout) {
+			slog.Info("framed protocol not supported, using legacy protocol")
+			usb.protocol = ProtocolLegacy
+			usb.pending = nil
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		frame, err := DecodeFrame(line)
+		if err == nil && frame.Command == CommandVersion {
+			slog.Info("arduino firmware", "version", string(frame.Payload))
+			usb.protocol = ProtocolFramed
+			return nil
+		}
+	}
+}
+
+// request sends a framed command and waits for the response with the same ID,
+// retransmitting on timeouts and corrupted frames
+func (usb *usbClientImpl) request(command byte, payload string) (string, error) {
+	var lastErr error
+	for attempt := 1; attempt <= maxFrameAttempts; attempt++ {
+		usb.nextID++
+		request := Frame{ID: usb.nextID, Command: command, Payload: []byte(payload)}
+		if _, err := usb.port.Write(request.Encode()); err != nil {
+			return "", err
+		}
+
+		response, err := usb.awaitFrame(request.ID, time.Now().Add(frameTimeout))
+		if err == nil {
+			if response.Command == CommandError {
+				return "", fmt.Errorf("arduino error: %s", response.Payload)
+			}
+			slog.Debug("arduino response", "payload", string(response.Payload))
+			return string(response.Payload), nil
+		}
+		if !errors.Is(err, errReadTimeout) && !errors.Is(err, ErrFrameChecksum) &&
+			!errors.Is(err, ErrFrameMalformed) {
+			return "", err
+		}
+		lastErr = err
+	}
+	return "", fmt.Errorf("no valid response to command '%c' after %d attempts: %w",
+		command, maxFrameAttempts, lastErr)
+}
+
+// awaitFrame reads lines until the response to id arrives, skipping line noise and stale responses
+func (usb *usbClientImpl) awaitFrame(id uint8, deadline time.Time) (Frame, error) {
+	for {
+		line, err := usb.readLine(deadline)
+		if err != nil {
+			return Frame{}, err
+		}
+		if len(line) == 0 || line[0] != frameStart {
+			continue
+		}
+
+		frame, err := DecodeFrame(line)
+		if err != nil {
+			return Frame{}, err
+		}
+		if frame.ID == id {
+			return frame, nil
+		}
+	}
+}
+
+// readLine returns the next newline-terminated line from the port
+func (usb *usbClientImpl) readLine(deadline time.Time) ([]byte, error) {
+	buffer := make([]byte, 64)
+	for {
+		if i := bytes.IndexByte(usb.pending, '\n'); i >= 0 {
+			line := bytes.TrimSpace(usb.pending[:i])
+			usb.pending = usb.pending[i+1:]
+			return line, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, errReadTimeout
+		}
+
+		n, err := usb.port.Read(buffer)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		usb.pending = append(usb.pending, buffer[:n]...)
+		if len(usb.pending) > 2*maxPayloadLen {
+			usb.pending = nil
+		}
+	}
+}
+
 // getArduinoResponse sends the Arduino a command and retrieves the response
 func (usb *usbClientImpl) getArduinoResponse(command string, sleepDuration time.Duration) (string, error) {
 	startTime := time.Now()
 	maxDuration := time.Millisecond * 500
 
-	fmt.Printf("Waiting for command '%s' ack...\n", command)
+	slog.Debug("waiting for ack", "command", command)
 
 	for {
 		if err := usb.writeData(command); err != nil {
@@ -110,7 +283,7 @@ func (usb *usbClientImpl) getArduinoResponse(command string, sleepDuration time.
 		}
 
 		if utils.IsValidResponse(response) {
-			fmt.Printf("Arduino says: %s\n", response)
+			slog.Debug("arduino response", "payload", response)
 			return response, nil
 		}
 
@@ -123,9 +296,9 @@ func (usb *usbClientImpl) getArduinoResponse(command string, sleepDuration time.
 }
 
 func (usb *usbClientImpl) readData() (string, error) {
-	buffer := make([]byte, 32)
+	buffer := make([]byte, 64)
 	n, err := usb.port.Read(buffer)
-	if err != nil {
+	if err != nil && err != io.EOF {
 		return "", err
 	}
 	if n > 0 {
@@ -2,6 +2,7 @@ package usb
 
 import (
 	"errors"
+	"io"
 	"testing"
 
 	"github.com/mugglemath/dewdrop-go/pkg/models"
@@ -105,3 +106,188 @@ func TestToggleWarningLight_HTTPError(t *testing.T) {
 		t.Errorf("expected error %v, got %v", expectedError, err)
 	}
 }
+
+// FramedSerialPort answers framed requests like the framed Arduino sketch
+type FramedSerialPort struct {
+	version  string
+	respond  func(request Frame) []byte
+	requests []Frame
+	out      []byte
+}
+
+func (f *FramedSerialPort) Write(data []byte) (int, error) {
+	if string(data) == probeCommand && f.version != "" {
+		f.out = append(f.out, Frame{Command: CommandVersion, Payload: []byte(f.version)}.Encode()...)
+	}
+	if request, err := DecodeFrame(data); err == nil {
+		f.requests = append(f.requests, request)
+		f.out = append(f.out, f.respond(request)...)
+	}
+	return len(data), nil
+}
+
+func (f *FramedSerialPort) Read(buffer []byte) (int, error) {
+	if len(f.out) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(buffer, f.out)
+	f.out = f.out[n:]
+	return n, nil
+}
+
+func TestGetIndoorSensorData_Framed(t *testing.T) {
+	mockPort := &FramedSerialPort{
+		respond: func(request Frame) []byte {
+			stale := Frame{ID: request.ID - 1, Command: CommandData, Payload: []byte("123,10.00,10.00,0")}
+			current := Frame{ID: request.ID, Command: CommandData, Payload: []byte("123,25.55,60.01,1")}
+			response := append([]byte("noise\r\n"), stale.Encode()...)
+			return append(response, current.Encode()...)
+		},
+	}
+	usbComm := &usbClientImpl{port: mockPort, protocol: ProtocolFramed}
+
+	data, err := usbComm.GetIndoorSensorData()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expectedData := models.IndoorSensorData{
+		DeviceID:    123,
+		Temperature: 25.55,
+		Humidity:    60.01,
+		LedState:    true,
+	}
+	if data != expectedData {
+		t.Errorf("expected data %v, got %v", expectedData, data)
+	}
+}
+
+func TestGetIndoorSensorData_FramedRetransmitsCorruptedResponse(t *testing.T) {
+	mockPort := &FramedSerialPort{}
+	mockPort.respond = func(request Frame) []byte {
+		response := Frame{ID: request.ID, Command: CommandData, Payload: []byte("123,25.55,60.01,1")}.Encode()
+		if len(mockPort.requests) == 1 {
+			response[9] = '9'
+		}
+		return response
+	}
+	usbComm := &usbClientImpl{port: mockPort, protocol: ProtocolFramed}
+
+	if _, err := usbComm.GetIndoorSensorData(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(mockPort.requests) != 2 {
+		t.Errorf("expected 2 requests, got %d", len(mockPort.requests))
+	}
+}
+
+func TestToggleWarningLight_FramedError(t *testing.T) {
+	mockPort := &FramedSerialPort{
+		respond: func(request Frame) []byte {
+			return Frame{ID: request.ID, Command: CommandError, Payload: []byte("bad state")}.Encode()
+		},
+	}
+	usbComm := &usbClientImpl{port: mockPort, protocol: ProtocolFramed}
+
+	expectedError := "arduino error: bad state"
+	err := usbComm.ToggleWarningLight(false)
+	if err == nil || err.Error() != expectedError {
+		t.Errorf("expected error %v, got %v", expectedError, err)
+	}
+	if string(mockPort.requests[0].Payload) != "1" {
+		t.Errorf("expected LED payload 1, got %s", mockPort.requests[0].Payload)
+	}
+}
+
+func TestNegotiateProtocol_Framed(t *testing.T) {
+	mockPort := &FramedSerialPort{
+		version: "dewdrop-framed/1",
+		respond: func(request Frame) []byte {
+			return Frame{ID: request.ID, Command: CommandLED, Payload: []byte("a")}.Encode()
+		},
+	}
+	usbComm := &usbClientImpl{port: mockPort, protocol: ProtocolAuto}
+
+	if err := usbComm.ToggleWarningLight(true); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if usbComm.protocol != ProtocolFramed {
+		t.Errorf("expected framed protocol, got %v", usbComm.protocol)
+	}
+}
+
+func TestNegotiateProtocol_LegacyFallback(t *testing.T) {
+	mockPort := &MockSerialPort{
+		readData: []byte("123,25.55,60.01,1"),
+	}
+	usbComm := &usbClientImpl{port: mockPort, protocol: ProtocolAuto}
+
+	if _, err := usbComm.GetIndoorSensorData(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if usbComm.protocol != ProtocolLegacy {
+		t.Errorf("expected legacy protocol, got %v", usbComm.protocol)
+	}
+}
+
+func TestNegotiateProtocol_OncePerPort(t *testing.T) {
+	respond := func(request Frame) []byte {
+		return Frame{ID: request.ID, Command: CommandLED, Payload: []byte("a")}.Encode()
+	}
+	first := newClient("/dev/ttyTEST0", &FramedSerialPort{version: "dewdrop-framed/1", respond: respond}, ProtocolAuto)
+	if err := first.ToggleWarningLight(true); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	first.Close()
+
+	// the next poll reopens the port, the sketch would not answer a second probe
+	mockPort := &FramedSerialPort{respond: respond}
+	second := newClient("/dev/ttyTEST0", mockPort, ProtocolAuto)
+	if err := second.ToggleWarningLight(false); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if second.protocol != ProtocolFramed {
+		t.Errorf("expected framed protocol without probing, got %v", second.protocol)
+	}
+	if len(mockPort.requests) != 1 || mockPort.requests[0].ID != 2 {
+		t.Errorf("expected the command IDs to continue at 2, got %v", mockPort.requests)
+	}
+}
+
+func TestParseProtocol(t *testing.T) {
+	tests := map[string]Protocol{
+		"":       ProtocolAuto,
+		"auto":   ProtocolAuto,
+		"FRAMED": ProtocolFramed,
+		"legacy": ProtocolLegacy,
+	}
+	for value, expected := range tests {
+		protocol, err := ParseProtocol(value)
+		if err != nil || protocol != expected {
+			t.Errorf("expected %v for %q, got %v (%v)", expected, value, protocol, err)
+		}
+	}
+	if _, err := ParseProtocol("morse"); err == nil {
+		t.Error("expected an error for an invalid protocol")
+	}
+}
+
+func TestGetIndoorSensorData_FramedJSON(t *testing.T) {
+	mockPort := &FramedSerialPort{
+		respond: func(request Frame) []byte {
+			payload := `{"device_id":123,"temperature":-4.5,"humidity":100.0,"led_state":false,"sensor_model":"SHT31"}`
+			return Frame{ID: request.ID, Command: CommandData, Payload: []byte(payload)}.Encode()
+		},
+	}
+	usbComm := &usbClientImpl{port: mockPort, protocol: ProtocolFramed}
+
+	data, err := usbComm.GetIndoorSensorData()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if string(mockPort.requests[0].Payload) != "json" {
+		t.Errorf("expected data request payload json, got %s", mockPort.requests[0].Payload)
+	}
+	if data.Temperature != -4.5 || data.Humidity != 100.0 || data.Info == nil || data.Info.SensorModel != "SHT31" {
+		t.Errorf("unexpected data %+v", data)
+	}
+}
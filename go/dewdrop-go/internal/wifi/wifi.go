@@ -6,9 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
-	"strconv"
 	"strings"
 
 	"github.com/mugglemath/dewdrop-go/pkg/models"
@@ -20,15 +21,27 @@ type Client interface {
 	ToggleWarningLight(openWindows bool) error
 }
 
-type wifiClientImpl struct{}
+type wifiClientImpl struct {
+	arduinoIP string
+}
 
-func NewWifiClient() Client {
-	return &wifiClientImpl{}
+// NewWifiClient creates a client for the Arduino at arduinoIP, falling back to ARDUINO_IP if empty
+func NewWifiClient(arduinoIP string) Client {
+	return &wifiClientImpl{arduinoIP: arduinoIP}
 }
 
 // GetIndoorSensorData retrieves the sensor data from the Arduino.
 func (w *wifiClientImpl) GetIndoorSensorData(endpoint string) (models.IndoorSensorData, error) {
-	resp, err := http.Get(endpoint)
+	// newer sketches answer with JSON when asked, older ones ignore the parameter and send CSV
+	requestURL, err := url.Parse(endpoint)
+	if err != nil {
+		return models.IndoorSensorData{}, err
+	}
+	query := requestURL.Query()
+	query.Set("format", "json")
+	requestURL.RawQuery = query.Encode()
+
+	resp, err := http.Get(requestURL.String())
 	if err != nil {
 		return models.IndoorSensorData{}, err
 	}
@@ -42,45 +55,13 @@ func (w *wifiClientImpl) GetIndoorSensorData(endpoint string) (models.IndoorSens
 		}
 
 		responseString := string(body)
-		fmt.Println("Response body:", strings.TrimSpace(responseString))
-
-		parts := utils.SplitAndTrim(responseString, ',')
-		if len(parts) < 4 {
-			return models.IndoorSensorData{}, errors.New("invalid data format")
-		}
-
-		deviceID, err := strconv.ParseUint(parts[0], 10, 64)
-		if err != nil {
-			return models.IndoorSensorData{}, errors.New("invalid device ID format")
-		}
+		slog.Debug("arduino response", "body", strings.TrimSpace(responseString))
 
-		temperature, err := strconv.ParseFloat(parts[1], 32)
+		indoorData, err = utils.ParseIndoorSensorData(responseString)
 		if err != nil {
-			return models.IndoorSensorData{}, errors.New("invalid temperature format")
-		}
-
-		humidity, err := strconv.ParseFloat(parts[2], 32)
-		if err != nil {
-			return models.IndoorSensorData{}, errors.New("invalid humidity format")
-		}
-
-		var ledState bool
-		if parts[3] == "1" {
-			ledState = true
-		} else if parts[3] == "0" {
-			ledState = false
-		} else {
-			return models.IndoorSensorData{}, errors.New("invalid LED state value")
-		}
-
-		indoorData = models.IndoorSensorData{
-			DeviceID:    deviceID,
-			Temperature: float32(temperature),
-			Humidity:    float32(humidity),
-			LedState:    ledState,
+			return models.IndoorSensorData{}, err
 		}
 	} else {
-		fmt.Println("HTTP request failed with status:", resp.Status)
 		return indoorData, fmt.Errorf("failed to fetch data: %s", resp.Status)
 	}
 
@@ -89,7 +70,10 @@ func (w *wifiClientImpl) GetIndoorSensorData(endpoint string) (models.IndoorSens
 
 // ToggleWarningLight toggles the blinking yellow light on the Arduino.
 func (w *wifiClientImpl) ToggleWarningLight(openWindows bool) error {
-	arduinoIP := os.Getenv("ARDUINO_IP")
+	arduinoIP := w.arduinoIP
+	if arduinoIP == "" {
+		arduinoIP = os.Getenv("ARDUINO_IP")
+	}
 	if arduinoIP == "" {
 		return errors.New("ARDUINO_IP environment variable is not set")
 	}
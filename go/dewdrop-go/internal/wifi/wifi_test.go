@@ -130,3 +130,28 @@ func TestToggleWarningLight_HTTPError(t *testing.T) {
 
 	os.Setenv("ARDUINO_IP", originalArduinoIP)
 }
+
+func TestGetIndoorSensorData_JSON(t *testing.T) {
+	var format string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		format = r.URL.Query().Get("format")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write([]byte(`{"device_id":123,"temperature":-4.5,"humidity":88.25,"led_state":false,"firmware_version":"2.0.0"}`)); err != nil {
+			log.Printf("Error writing response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	wifiComm := &wifiClientImpl{}
+
+	data, err := wifiComm.GetIndoorSensorData(server.URL + "/data")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if format != "json" {
+		t.Errorf("expected format=json query parameter, got %q", format)
+	}
+	if data.Temperature != -4.5 || data.Humidity != 88.25 || data.Info == nil || data.Info.FirmwareVersion != "2.0.0" {
+		t.Errorf("unexpected data %+v", data)
+	}
+}
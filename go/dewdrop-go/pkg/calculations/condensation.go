@@ -0,0 +1,50 @@
+package calculations
+
+import (
+	"errors"
+	"math"
+)
+
+// WindowSurfaceResistance is the interior surface resistance of glazing in m²K/W (ISO 6946)
+const WindowSurfaceResistance = 0.13
+
+// CondensationRisk compares the inner glass surface with the indoor dew point
+type CondensationRisk struct {
+	SurfaceTemperature float64 `json:"surface_temperature"`
+	// DewPoint is the frost point when below 0 C, since that is what forms on the glass
+	DewPoint float64 `json:"dewpoint"`
+	// Margin is how far the surface is above the dew point in C, condensation forms below 0
+	Margin     float64 `json:"margin"`
+	Condensing bool    `json:"condensing"`
+}
+
+// InnerSurfaceTemperature estimates the steady state temperature of the inner glass
+// surface in C from the window U-value in W/(m²K)
+func InnerSurfaceTemperature(indoorTemperature, outdoorTemperature, uValue float64) (float64, error) {
+	if math.IsNaN(indoorTemperature) || math.IsNaN(outdoorTemperature) || math.IsNaN(uValue) {
+		return 0, errors.New("input values must not be NaN")
+	}
+	// a surface can't lose more heat than its own surface resistance allows
+	if uValue <= 0 || uValue >= 1/WindowSurfaceResistance {
+		return 0, errors.New("U-value must be between 0 and 7.7 W/(m²K)")
+	}
+	return indoorTemperature - uValue*WindowSurfaceResistance*(indoorTemperature-outdoorTemperature), nil
+}
+
+// WindowCondensationRisk estimates condensation on a window with the given U-value
+func WindowCondensationRisk(indoorTemperature, indoorHumidity, outdoorTemperature, uValue float64) (CondensationRisk, error) {
+	surface, err := InnerSurfaceTemperature(indoorTemperature, outdoorTemperature, uValue)
+	if err != nil {
+		return CondensationRisk{}, err
+	}
+	dewPoint, err := Auto.DewPoint(indoorTemperature, indoorHumidity)
+	if err != nil {
+		return CondensationRisk{}, err
+	}
+	return CondensationRisk{
+		SurfaceTemperature: surface,
+		DewPoint:           dewPoint,
+		Margin:             surface - dewPoint,
+		Condensing:         surface <= dewPoint,
+	}, nil
+}
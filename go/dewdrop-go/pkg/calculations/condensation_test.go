@@ -0,0 +1,60 @@
+package calculations
+
+import (
+	"math"
+	"testing"
+)
+
+func TestInnerSurfaceTemperature(t *testing.T) {
+	tests := []struct {
+		name     string
+		uValue   float64
+		expected float64
+	}{
+		{"single glazing", 5.8, 1.15},
+		{"double glazing", 2.8, 10.90},
+		{"triple glazing", 0.7, 17.73},
+	}
+	for _, test := range tests {
+		actual, err := InnerSurfaceTemperature(20, -5, test.uValue)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		}
+		if math.Abs(actual-test.expected) > 0.01 {
+			t.Errorf("%s: expected %.2f, got %.2f", test.name, test.expected, actual)
+		}
+	}
+
+	for _, uValue := range []float64{0, -1, 8, math.NaN()} {
+		if _, err := InnerSurfaceTemperature(20, -5, uValue); err == nil {
+			t.Errorf("expected an error for U-value %v", uValue)
+		}
+	}
+}
+
+func TestWindowCondensationRisk(t *testing.T) {
+	// 20 C and 50% has a dew point of 9.3 C
+	risk, err := WindowCondensationRisk(20, 50, -5, 2.8)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if risk.Condensing || math.Abs(risk.Margin-(10.90-9.27)) > 0.05 {
+		t.Errorf("expected double glazing to stay clear, got %+v", risk)
+	}
+
+	risk, _ = WindowCondensationRisk(20, 50, -5, 5.8)
+	if !risk.Condensing || risk.Margin >= 0 {
+		t.Errorf("expected single glazing to condense, got %+v", risk)
+	}
+
+	// below freezing the frost point applies
+	risk, _ = WindowCondensationRisk(18, 25, -20, 5.8)
+	frostPoint, _ := SonntagIce.DewPoint(18, 25)
+	if risk.DewPoint != frostPoint {
+		t.Errorf("expected the frost point %.2f, got %+v", frostPoint, risk)
+	}
+
+	if _, err := WindowCondensationRisk(20, 120, -5, 2.8); err == nil {
+		t.Error("expected an error for humidity over 100%")
+	}
+}
@@ -0,0 +1,221 @@
+package calculations
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// DewPointFormula converts temperature in C and relative humidity (over water) in %
+// to the temperature at which the air saturates
+type DewPointFormula interface {
+	Name() string
+	DewPoint(temperature, relativeHumidity float64) (float64, error)
+	// ValidRange is the temperature range in C where MaxError holds
+	ValidRange() (min, max float64)
+	// MaxError is the approximate worst case error in C within ValidRange
+	MaxError() float64
+}
+
+// vaporPressureFormula inverts a saturation vapor pressure curve. Humidity is always
+// relative to water, the ice variants solve for saturation over ice (the frost point).
+type vaporPressureFormula struct {
+	name       string
+	water      func(temperature float64) float64
+	saturation func(temperature float64) float64
+	min, max   float64
+	maxError   float64
+}
+
+var (
+	// MagnusWater uses the Alduchov & Eskridge (1996) coefficients, same as DewPointCalculator
+	MagnusWater DewPointFormula = &vaporPressureFormula{
+		name: "magnus", water: magnusAE, saturation: magnusAE,
+		min: -40, max: 50, maxError: 0.1,
+	}
+	// MagnusIce uses the Alduchov & Eskridge (1996) ice coefficients and returns the frost point
+	MagnusIce DewPointFormula = &vaporPressureFormula{
+		name: "magnus-ice", water: magnusAE, saturation: magnusAEIce,
+		min: -80, max: 0, maxError: 0.1,
+	}
+	// MagnusSonntag uses the Magnus coefficients recommended by Sonntag (1990) and the WMO
+	MagnusSonntag DewPointFormula = &vaporPressureFormula{
+		name: "magnus-sonntag", water: magnusSonntag, saturation: magnusSonntag,
+		min: -45, max: 60, maxError: 0.1,
+	}
+	// ArdenBuck uses Buck's (1996) enhanced equation over water
+	ArdenBuck DewPointFormula = &vaporPressureFormula{
+		name: "buck", water: buckWater, saturation: buckWater,
+		min: -80, max: 50, maxError: 0.05,
+	}
+	// ArdenBuckIce uses Buck's (1996) equation over ice and returns the frost point
+	ArdenBuckIce DewPointFormula = &vaporPressureFormula{
+		name: "buck-ice", water: buckWater, saturation: buckIce,
+		min: -80, max: 0, maxError: 0.05,
+	}
+	// Sonntag uses Sonntag's (1990) fit to the ITS-90 saturation curve over water
+	Sonntag DewPointFormula = &vaporPressureFormula{
+		name: "sonntag", water: sonntagWater, saturation: sonntagWater,
+		min: -100, max: 100, maxError: 0.01,
+	}
+	// SonntagIce uses Sonntag's (1990) fit over ice and returns the frost point
+	SonntagIce DewPointFormula = &vaporPressureFormula{
+		name: "sonntag-ice", water: sonntagWater, saturation: sonntagIce,
+		min: -100, max: 0.01, maxError: 0.01,
+	}
+)
+
+// Auto returns the Sonntag dew point, or the frost point once that falls below freezing
+var Auto DewPointFormula = &waterIceFormula{name: "auto", water: Sonntag, ice: SonntagIce}
+
+// waterIceFormula picks saturation over ice whenever the dew point is below 0 C
+type waterIceFormula struct {
+	name       string
+	water, ice DewPointFormula
+}
+
+// DewPointFormulas lists every available formula
+func DewPointFormulas() []DewPointFormula {
+	return []DewPointFormula{MagnusWater, MagnusIce, MagnusSonntag, ArdenBuck, ArdenBuckIce, Sonntag, SonntagIce, Auto}
+}
+
+// ParseDewPointFormula looks up a formula by name, an empty name selects MagnusWater
+func ParseDewPointFormula(name string) (DewPointFormula, error) {
+	if name == "" {
+		return MagnusWater, nil
+	}
+	var names []string
+	for _, f := range DewPointFormulas() {
+		if f.Name() == strings.ToLower(name) {
+			return f, nil
+		}
+		names = append(names, f.Name())
+	}
+	return nil, fmt.Errorf("unknown dew point formula %q, use one of %s", name, strings.Join(names, ", "))
+}
+
+func (f *vaporPressureFormula) Name() string {
+	return f.name
+}
+
+func (f *vaporPressureFormula) ValidRange() (float64, float64) {
+	return f.min, f.max
+}
+
+func (f *vaporPressureFormula) MaxError() float64 {
+	return f.maxError
+}
+
+func (f *vaporPressureFormula) DewPoint(temperature, relativeHumidity float64) (float64, error) {
+	if err := validateInputs(temperature, relativeHumidity); err != nil {
+		return 0, err
+	}
+	if relativeHumidity == 0 {
+		return -kelvinOffset, errors.New("dew point is undefined at 0% relative humidity")
+	}
+
+	// the saturation curves are monotonic, so bisect for the temperature where they reach e
+	e := relativeHumidity / 100 * f.water(temperature)
+	low, high := -kelvinOffset+1, math.Max(temperature, 0)+1
+	for i := 0; i < 100; i++ {
+		mid := (low + high) / 2
+		if f.saturation(mid) < e {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return (low + high) / 2, nil
+}
+
+func (f *waterIceFormula) Name() string {
+	return f.name
+}
+
+func (f *waterIceFormula) ValidRange() (float64, float64) {
+	min, _ := f.ice.ValidRange()
+	_, max := f.water.ValidRange()
+	return min, max
+}
+
+func (f *waterIceFormula) MaxError() float64 {
+	return math.Max(f.water.MaxError(), f.ice.MaxError())
+}
+
+func (f *waterIceFormula) DewPoint(temperature, relativeHumidity float64) (float64, error) {
+	dewPoint, err := f.water.DewPoint(temperature, relativeHumidity)
+	if err != nil || dewPoint >= 0 {
+		return dewPoint, err
+	}
+	return f.ice.DewPoint(temperature, relativeHumidity)
+}
+
+// FormulaComparison reports how far the formulas disagree for one reading
+type FormulaComparison struct {
+	DewPoints map[string]float64
+	// OutOfRange lists formulas used outside their ValidRange
+	OutOfRange []string
+	Min        float64
+	Max        float64
+	Spread     float64
+}
+
+// CompareDewPointFormulas evaluates every formula (or all of them if none are given)
+func CompareDewPointFormulas(temperature, relativeHumidity float64, formulas ...DewPointFormula) (FormulaComparison, error) {
+	if len(formulas) == 0 {
+		formulas = DewPointFormulas()
+	}
+
+	comparison := FormulaComparison{
+		DewPoints: map[string]float64{},
+		Min:       math.Inf(1),
+		Max:       math.Inf(-1),
+	}
+	for _, f := range formulas {
+		dewPoint, err := f.DewPoint(temperature, relativeHumidity)
+		if err != nil {
+			return FormulaComparison{}, fmt.Errorf("%s: %w", f.Name(), err)
+		}
+		comparison.DewPoints[f.Name()] = dewPoint
+		comparison.Min = math.Min(comparison.Min, dewPoint)
+		comparison.Max = math.Max(comparison.Max, dewPoint)
+		if min, max := f.ValidRange(); temperature < min || temperature > max {
+			comparison.OutOfRange = append(comparison.OutOfRange, f.Name())
+		}
+	}
+	sort.Strings(comparison.OutOfRange)
+	comparison.Spread = comparison.Max - comparison.Min
+	return comparison, nil
+}
+
+func magnusAE(t float64) float64 {
+	return magnusA * math.Exp(magnusB*t/(magnusC+t))
+}
+
+func magnusAEIce(t float64) float64 {
+	return magnusIceA * math.Exp(magnusIceB*t/(magnusIceC+t))
+}
+
+func magnusSonntag(t float64) float64 {
+	return 6.112 * math.Exp(17.62*t/(243.12+t))
+}
+
+func buckWater(t float64) float64 {
+	return 6.1121 * math.Exp((18.678-t/234.5)*(t/(257.14+t)))
+}
+
+func buckIce(t float64) float64 {
+	return 6.1115 * math.Exp((23.036-t/333.7)*(t/(279.82+t)))
+}
+
+func sonntagWater(t float64) float64 {
+	k := t + kelvinOffset
+	return math.Exp(-6096.9385/k + 16.635794 - 2.711193e-2*k + 1.673952e-5*k*k + 2.433502*math.Log(k))
+}
+
+func sonntagIce(t float64) float64 {
+	k := t + kelvinOffset
+	return math.Exp(-6024.5282/k + 24.7219 + 1.0613868e-2*k - 1.3198825e-5*k*k - 0.49382577*math.Log(k))
+}
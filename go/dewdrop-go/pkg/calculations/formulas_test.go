@@ -0,0 +1,126 @@
+package calculations
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDewPointFormulas_ReferenceValues(t *testing.T) {
+	// reference dew and frost points from the Sonntag (1990) saturation curves
+	tests := []struct {
+		temperature      float64
+		relativeHumidity float64
+		dewPoint         float64
+		frostPoint       float64
+	}{
+		{20, 60, 12.00, 0},
+		{30, 80, 26.17, 0},
+		{0, 80, -3.02, -2.68},
+		{-10, 80, -12.79, -11.42},
+		{-25, 60, -30.53, -27.63},
+	}
+
+	water := []DewPointFormula{MagnusWater, MagnusSonntag, ArdenBuck, Sonntag}
+	ice := []DewPointFormula{MagnusIce, ArdenBuckIce, SonntagIce}
+	for _, test := range tests {
+		for _, f := range water {
+			actual, err := f.DewPoint(test.temperature, test.relativeHumidity)
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", f.Name(), err)
+			}
+			if math.Abs(actual-test.dewPoint) > f.MaxError()+0.05 {
+				t.Errorf("%s(%v, %v) = %.3f, expected %.2f", f.Name(), test.temperature, test.relativeHumidity, actual, test.dewPoint)
+			}
+		}
+		if test.temperature > 0 {
+			continue
+		}
+		for _, f := range ice {
+			actual, err := f.DewPoint(test.temperature, test.relativeHumidity)
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", f.Name(), err)
+			}
+			if math.Abs(actual-test.frostPoint) > f.MaxError()+0.05 {
+				t.Errorf("%s(%v, %v) = %.3f, expected %.2f", f.Name(), test.temperature, test.relativeHumidity, actual, test.frostPoint)
+			}
+		}
+	}
+}
+
+func TestAuto_SwitchesToIceBelowFreezing(t *testing.T) {
+	tests := []struct {
+		temperature      float64
+		relativeHumidity float64
+		expected         DewPointFormula
+	}{
+		{20, 60, Sonntag},
+		{10, 70, Sonntag},
+		{2, 70, SonntagIce},
+		{-10, 80, SonntagIce},
+	}
+	for _, test := range tests {
+		expected, _ := test.expected.DewPoint(test.temperature, test.relativeHumidity)
+		actual, err := Auto.DewPoint(test.temperature, test.relativeHumidity)
+		if err != nil || actual != expected {
+			t.Errorf("Auto(%v, %v) = %f, expected %s result %f", test.temperature, test.relativeHumidity,
+				actual, test.expected.Name(), expected)
+		}
+	}
+}
+
+func TestMagnusWater_MatchesDewPointCalculator(t *testing.T) {
+	for _, input := range [][2]float64{{20, 60}, {-5, 90}, {35, 20}} {
+		expected, _ := DewPointCalculator(input[0], input[1])
+		actual, err := MagnusWater.DewPoint(input[0], input[1])
+		if err != nil || math.Abs(actual-expected) > 1e-6 {
+			t.Errorf("MagnusWater(%v, %v) = %f, DewPointCalculator returned %f", input[0], input[1], actual, expected)
+		}
+	}
+}
+
+func TestDewPointFormula_InvalidInput(t *testing.T) {
+	for _, f := range DewPointFormulas() {
+		for _, input := range [][2]float64{{20, 0}, {20, 120}, {math.NaN(), 50}, {-300, 50}} {
+			if _, err := f.DewPoint(input[0], input[1]); err == nil {
+				t.Errorf("%s(%v, %v) did not return an error", f.Name(), input[0], input[1])
+			}
+		}
+	}
+}
+
+func TestParseDewPointFormula(t *testing.T) {
+	tests := map[string]string{"": "magnus", "buck": "buck", "Sonntag-Ice": "sonntag-ice"}
+	for name, expected := range tests {
+		f, err := ParseDewPointFormula(name)
+		if err != nil || f.Name() != expected {
+			t.Errorf("ParseDewPointFormula(%q) = %v, %v, expected %s", name, f, err, expected)
+		}
+	}
+	if _, err := ParseDewPointFormula("goff-gratch"); err == nil {
+		t.Error("expected an error for an unknown formula")
+	}
+}
+
+func TestCompareDewPointFormulas(t *testing.T) {
+	comparison, err := CompareDewPointFormulas(-10, 80, MagnusWater, ArdenBuck, SonntagIce)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(comparison.DewPoints) != 3 {
+		t.Errorf("expected 3 results, got %d", len(comparison.DewPoints))
+	}
+	if comparison.Spread < 1 || comparison.Spread != comparison.Max-comparison.Min {
+		t.Errorf("expected a spread of over 1 C between water and ice, got %+v", comparison)
+	}
+	if len(comparison.OutOfRange) != 0 {
+		t.Errorf("expected all formulas in range, got %v", comparison.OutOfRange)
+	}
+
+	comparison, err = CompareDewPointFormulas(20, 60)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(comparison.DewPoints) != len(DewPointFormulas()) || len(comparison.OutOfRange) != 3 {
+		t.Errorf("expected the ice formulas out of range at 20 C, got %v", comparison.OutOfRange)
+	}
+}
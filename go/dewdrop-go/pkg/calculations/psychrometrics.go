@@ -0,0 +1,199 @@
+package calculations
+
+import (
+	"errors"
+	"math"
+)
+
+const (
+	// StandardPressure is sea level pressure in hPa
+	StandardPressure = 1013.25
+
+	// waterVaporGasConstant in J/(kg K)
+	waterVaporGasConstant = 461.5
+	kelvinOffset          = 273.15
+
+	// Magnus coefficients over water (Alduchov & Eskridge 1996), matching DewPointCalculator
+	magnusA      = 6.1094
+	magnusB      = 17.625
+	magnusC      = 243.04
+	magnusIceA   = 6.1121
+	magnusIceB   = 22.587
+	magnusIceC   = 273.86
+	epsilonRatio = 0.622
+)
+
+// Metrics bundles the derived psychrometric values for a reading
+type Metrics struct {
+	DewPoint           float64 `json:"dewpoint"`
+	FrostPoint         float64 `json:"frost_point"`
+	VaporPressure      float64 `json:"vapor_pressure"`
+	AbsoluteHumidity   float64 `json:"absolute_humidity"`
+	MixingRatio        float64 `json:"mixing_ratio"`
+	SpecificEnthalpy   float64 `json:"specific_enthalpy"`
+	WetBulbTemperature float64 `json:"wet_bulb_temperature"`
+	HeatIndex          float64 `json:"heat_index"`
+	Humidex            float64 `json:"humidex"`
+}
+
+// ComputeMetrics derives all metrics from temperature in C, relative humidity in % and pressure in hPa.
+// Air without water vapor has no dew or frost point, at 0% relative humidity both are
+// reported as -273.15 C while the vapor pressure, absolute humidity and mixing ratio are 0.
+func ComputeMetrics(temperature, relativeHumidity, pressure float64) (Metrics, error) {
+	if err := validateInputs(temperature, relativeHumidity); err != nil {
+		return Metrics{}, err
+	}
+	if pressure <= 0 || math.IsNaN(pressure) {
+		return Metrics{}, errors.New("pressure must be positive")
+	}
+
+	var m Metrics
+	var err error
+	if relativeHumidity == 0 {
+		m.DewPoint, m.FrostPoint = -kelvinOffset, -kelvinOffset
+	} else {
+		if m.DewPoint, err = DewPointCalculator(temperature, relativeHumidity); err != nil {
+			return Metrics{}, err
+		}
+		if m.FrostPoint, err = FrostPoint(temperature, relativeHumidity); err != nil {
+			return Metrics{}, err
+		}
+	}
+	m.VaporPressure, _ = VaporPressure(temperature, relativeHumidity)
+	m.AbsoluteHumidity, _ = AbsoluteHumidity(temperature, relativeHumidity)
+	m.MixingRatio, _ = MixingRatio(temperature, relativeHumidity, pressure)
+	m.SpecificEnthalpy, _ = SpecificEnthalpy(temperature, relativeHumidity, pressure)
+	m.WetBulbTemperature, _ = WetBulbTemperature(temperature, relativeHumidity)
+	m.HeatIndex, _ = HeatIndex(temperature, relativeHumidity)
+	m.Humidex, _ = Humidex(temperature, relativeHumidity)
+	return m, nil
+}
+
+// SaturationVaporPressure returns the saturation vapor pressure over water in hPa.
+// Within 0.3% of the Wexler/Hyland values between -40 and 50 C.
+func SaturationVaporPressure(temperature float64) float64 {
+	return magnusA * math.Exp(magnusB*temperature/(magnusC+temperature))
+}
+
+// SaturationVaporPressureIce returns the saturation vapor pressure over ice in hPa,
+// valid between -80 and 0 C
+func SaturationVaporPressureIce(temperature float64) float64 {
+	return magnusIceA * math.Exp(magnusIceB*temperature/(magnusIceC+temperature))
+}
+
+// VaporPressure returns the partial pressure of water vapor in hPa
+func VaporPressure(temperature, relativeHumidity float64) (float64, error) {
+	if err := validateInputs(temperature, relativeHumidity); err != nil {
+		return 0, err
+	}
+	return relativeHumidity / 100 * SaturationVaporPressure(temperature), nil
+}
+
+// AbsoluteHumidity returns grams of water vapor per cubic meter of air
+func AbsoluteHumidity(temperature, relativeHumidity float64) (float64, error) {
+	e, err := VaporPressure(temperature, relativeHumidity)
+	if err != nil {
+		return 0, err
+	}
+	return AbsoluteHumidityFromVaporPressure(temperature, e), nil
+}
+
+// AbsoluteHumidityFromVaporPressure returns g/m³ for a vapor pressure in hPa at temperature in C
+func AbsoluteHumidityFromVaporPressure(temperature, vaporPressure float64) float64 {
+	return vaporPressure * 100 / (waterVaporGasConstant * (temperature + kelvinOffset)) * 1000
+}
+
+// MixingRatio returns grams of water vapor per kilogram of dry air at pressure in hPa
+func MixingRatio(temperature, relativeHumidity, pressure float64) (float64, error) {
+	e, err := VaporPressure(temperature, relativeHumidity)
+	if err != nil {
+		return 0, err
+	}
+	if e >= pressure {
+		return 0, errors.New("vapor pressure must be below total pressure")
+	}
+	return epsilonRatio * e / (pressure - e) * 1000, nil
+}
+
+// SpecificEnthalpy returns the enthalpy of moist air in kJ per kilogram of dry air, relative to 0 C
+func SpecificEnthalpy(temperature, relativeHumidity, pressure float64) (float64, error) {
+	w, err := MixingRatio(temperature, relativeHumidity, pressure)
+	if err != nil {
+		return 0, err
+	}
+	return 1.006*temperature + w/1000*(2501+1.86*temperature), nil
+}
+
+// WetBulbTemperature uses Stull's (2011) empirical fit at standard pressure.
+// Valid for 5-99% RH and -20 to 50 C, within about 0.3 C.
+func WetBulbTemperature(temperature, relativeHumidity float64) (float64, error) {
+	if err := validateInputs(temperature, relativeHumidity); err != nil {
+		return 0, err
+	}
+	t, rh := temperature, relativeHumidity
+	return t*math.Atan(0.151977*math.Sqrt(rh+8.313659)) +
+		math.Atan(t+rh) - math.Atan(rh-1.676331) +
+		0.00391838*math.Pow(rh, 1.5)*math.Atan(0.023101*rh) - 4.686035, nil
+}
+
+// FrostPoint returns the temperature at which the air saturates over ice. Only
+// meaningful when the dew point is below 0 C, valid down to -80 C.
+func FrostPoint(temperature, relativeHumidity float64) (float64, error) {
+	e, err := VaporPressure(temperature, relativeHumidity)
+	if err != nil {
+		return 0, err
+	}
+	if e == 0 {
+		return -kelvinOffset, errors.New("frost point is undefined at 0% relative humidity")
+	}
+	ratio := math.Log(e / magnusIceA)
+	return magnusIceC * ratio / (magnusIceB - ratio), nil
+}
+
+// HeatIndex returns the NWS heat index in C using the Rothfusz regression and its
+// adjustments, falling back to Steadman's simple formula below about 26.7 C (80 F)
+func HeatIndex(temperature, relativeHumidity float64) (float64, error) {
+	if err := validateInputs(temperature, relativeHumidity); err != nil {
+		return 0, err
+	}
+	t := temperature*9/5 + 32
+	rh := relativeHumidity
+
+	hi := 0.5 * (t + 61 + (t-68)*1.2 + rh*0.094)
+	if (hi+t)/2 >= 80 {
+		hi = -42.379 + 2.04901523*t + 10.14333127*rh - 0.22475541*t*rh -
+			0.00683783*t*t - 0.05481717*rh*rh + 0.00122874*t*t*rh +
+			0.00085282*t*rh*rh - 0.00000199*t*t*rh*rh
+		if rh < 13 && t >= 80 && t <= 112 {
+			hi -= (13 - rh) / 4 * math.Sqrt((17-math.Abs(t-95))/17)
+		} else if rh > 85 && t >= 80 && t <= 87 {
+			hi += (rh - 85) / 10 * (87 - t) / 5
+		}
+	}
+	return (hi - 32) * 5 / 9, nil
+}
+
+// Humidex returns the Environment Canada humidex, intended for temperatures above 20 C
+func Humidex(temperature, relativeHumidity float64) (float64, error) {
+	e, err := VaporPressure(temperature, relativeHumidity)
+	if err != nil {
+		return 0, err
+	}
+	return temperature + 0.5555*(e-10), nil
+}
+
+func validateInputs(temperature, relativeHumidity float64) error {
+	if math.IsNaN(temperature) || math.IsNaN(relativeHumidity) {
+		return errors.New("input values must not be NaN")
+	}
+	if math.IsInf(temperature, 0) || math.IsInf(relativeHumidity, 0) {
+		return errors.New("input values must not be infinite")
+	}
+	if temperature < -kelvinOffset {
+		return errors.New("temperature must be greater than or equal to -273.15")
+	}
+	if relativeHumidity < 0 || relativeHumidity > 100 {
+		return errors.New("relative humidity must be between 0 and 100")
+	}
+	return nil
+}
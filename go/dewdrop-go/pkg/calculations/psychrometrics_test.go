@@ -0,0 +1,125 @@
+package calculations
+
+import (
+	"math"
+	"testing"
+)
+
+// reference values from the Vaisala humidity calculator, Stull (2011), the NWS
+// heat index table and the Environment Canada humidex table
+func TestPsychrometrics_ReferenceValues(t *testing.T) {
+	tests := []struct {
+		name      string
+		fn        func(temperature, relativeHumidity float64) (float64, error)
+		t, rh     float64
+		expected  float64
+		tolerance float64
+	}{
+		{"vapor pressure", VaporPressure, 20, 50, 11.69, 0.05},
+		{"vapor pressure freezing", VaporPressure, -10, 80, 2.29, 0.02},
+		{"absolute humidity", AbsoluteHumidity, 20, 50, 8.65, 0.05},
+		{"absolute humidity warm", AbsoluteHumidity, 30, 80, 24.27, 0.15},
+		{"absolute humidity freezing", AbsoluteHumidity, -10, 80, 1.89, 0.02},
+		{"mixing ratio", mixingRatioAtSeaLevel, 20, 50, 7.26, 0.05},
+		{"specific enthalpy", enthalpyAtSeaLevel, 20, 50, 38.5, 0.2},
+		{"wet bulb", WetBulbTemperature, 20, 50, 13.7, 0.1},
+		{"wet bulb humid", WetBulbTemperature, 30, 90, 28.6, 0.2},
+		{"frost point", FrostPoint, -10, 80, -11.4, 0.1},
+		{"frost point cold", FrostPoint, -25, 60, -27.6, 0.2},
+		{"heat index 90F 70%", HeatIndex, 32.22, 70, 41.1, 0.5},
+		{"heat index 100F 40%", HeatIndex, 37.78, 40, 43.3, 0.5},
+		{"heat index mild", HeatIndex, 20, 50, 19.4, 0.3},
+		{"humidex 30C dewpoint 15C", Humidex, 30, 42.4, 34, 0.5},
+		{"humidex 35C dewpoint 25C", Humidex, 35, 56.6, 47, 0.7},
+	}
+	for _, test := range tests {
+		actual, err := test.fn(test.t, test.rh)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+		if math.Abs(actual-test.expected) > test.tolerance {
+			t.Errorf("%s: got %.3f, expected %.3f ± %.2f", test.name, actual, test.expected, test.tolerance)
+		}
+	}
+}
+
+func TestSaturationVaporPressure(t *testing.T) {
+	tests := []struct {
+		temperature float64
+		water, ice  float64
+	}{
+		{0, 6.11, 6.11},
+		{-20, 1.26, 1.03},
+		{20, 23.37, 0},
+		{40, 73.75, 0},
+	}
+	for _, test := range tests {
+		if actual := SaturationVaporPressure(test.temperature); math.Abs(actual-test.water)/test.water > 0.005 {
+			t.Errorf("SaturationVaporPressure(%.0f) = %.3f, expected %.3f", test.temperature, actual, test.water)
+		}
+		if test.ice == 0 {
+			continue
+		}
+		if actual := SaturationVaporPressureIce(test.temperature); math.Abs(actual-test.ice)/test.ice > 0.005 {
+			t.Errorf("SaturationVaporPressureIce(%.0f) = %.3f, expected %.3f", test.temperature, actual, test.ice)
+		}
+	}
+}
+
+func TestPsychrometrics_InvalidInput(t *testing.T) {
+	fns := map[string]func(temperature, relativeHumidity float64) (float64, error){
+		"VaporPressure":      VaporPressure,
+		"AbsoluteHumidity":   AbsoluteHumidity,
+		"WetBulbTemperature": WetBulbTemperature,
+		"FrostPoint":         FrostPoint,
+		"HeatIndex":          HeatIndex,
+		"Humidex":            Humidex,
+	}
+	inputs := [][2]float64{{-300, 50}, {20, -1}, {20, 101}, {math.NaN(), 50}, {math.Inf(1), 50}}
+	for name, fn := range fns {
+		for _, input := range inputs {
+			if _, err := fn(input[0], input[1]); err == nil {
+				t.Errorf("%s(%v, %v) did not return an error", name, input[0], input[1])
+			}
+		}
+	}
+}
+
+func TestComputeMetrics(t *testing.T) {
+	m, err := ComputeMetrics(20, 50, StandardPressure)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if math.Abs(m.DewPoint-9.26) > 0.05 || math.Abs(m.AbsoluteHumidity-8.65) > 0.05 || math.Abs(m.WetBulbTemperature-13.7) > 0.1 {
+		t.Errorf("unexpected metrics %+v", m)
+	}
+
+	if _, err := ComputeMetrics(20, 50, 0); err == nil {
+		t.Error("expected an error for zero pressure")
+	}
+}
+
+func TestComputeMetrics_DryAir(t *testing.T) {
+	m, err := ComputeMetrics(20, 0, StandardPressure)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if m.DewPoint != -273.15 || m.FrostPoint != -273.15 {
+		t.Errorf("expected undefined dew and frost points at -273.15, got %+v", m)
+	}
+	if m.VaporPressure != 0 || m.AbsoluteHumidity != 0 || m.MixingRatio != 0 {
+		t.Errorf("expected no water vapor, got %+v", m)
+	}
+	if math.Abs(m.SpecificEnthalpy-20.12) > 0.01 {
+		t.Errorf("expected the enthalpy of dry air, got %+v", m)
+	}
+}
+
+func mixingRatioAtSeaLevel(temperature, relativeHumidity float64) (float64, error) {
+	return MixingRatio(temperature, relativeHumidity, StandardPressure)
+}
+
+func enthalpyAtSeaLevel(temperature, relativeHumidity float64) (float64, error) {
+	return SpecificEnthalpy(temperature, relativeHumidity, StandardPressure)
+}
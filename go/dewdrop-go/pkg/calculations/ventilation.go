@@ -0,0 +1,107 @@
+package calculations
+
+import (
+	"fmt"
+	"math"
+)
+
+// VentilationAdvisor decides whether opening the windows removes moisture. Outdoor air
+// is assumed to warm to indoor temperature, so the comparison is between indoor and
+// outdoor absolute humidity rather than dew points.
+type VentilationAdvisor struct {
+	// MinimumDifference is how many g/m³ drier outdoor air must be before opening is worthwhile
+	MinimumDifference float64
+	// MinimumHumidity keeps the windows closed when a full air exchange would dry the
+	// room below this relative humidity in %
+	MinimumHumidity float64
+	// TemperatureUncertainty and HumidityUncertainty describe the indoor sensor
+	TemperatureUncertainty float64
+	HumidityUncertainty    float64
+	// DewPointUncertainty describes the outdoor dew point forecast
+	DewPointUncertainty float64
+}
+
+// VentilationAdvice is the outcome of VentilationAdvisor.Advise
+type VentilationAdvice struct {
+	OpenWindows bool   `json:"open_windows"`
+	Reason      string `json:"reason"`
+	// IndoorAbsoluteHumidity and OutdoorAbsoluteHumidity are in g/m³ at indoor temperature
+	IndoorAbsoluteHumidity  float64 `json:"indoor_absolute_humidity"`
+	OutdoorAbsoluteHumidity float64 `json:"outdoor_absolute_humidity"`
+	// PredictedHumidity is the indoor relative humidity after a full exchange with outdoor air
+	PredictedHumidity float64 `json:"predicted_humidity"`
+	// Margin is how far in g/m³ the reading is from the MinimumDifference threshold
+	Margin float64 `json:"margin"`
+	// Uncertainty is the combined sensor and forecast uncertainty of Margin in g/m³
+	Uncertainty float64 `json:"uncertainty"`
+	// Confidence is the probability, from 0.5 to 1, that the recommendation holds
+	Confidence float64 `json:"confidence"`
+}
+
+// DefaultVentilationAdvisor uses typical DHT22/SHT3x accuracy and NWS forecast error
+var DefaultVentilationAdvisor = VentilationAdvisor{
+	MinimumDifference:      0.5,
+	MinimumHumidity:        30,
+	TemperatureUncertainty: 0.5,
+	HumidityUncertainty:    3,
+	DewPointUncertainty:    1,
+}
+
+// Advise compares an indoor reading in C and % with the outdoor dew point in C
+func (a VentilationAdvisor) Advise(indoorTemperature, indoorHumidity, outdoorDewpoint float64) (VentilationAdvice, error) {
+	if err := validateInputs(indoorTemperature, indoorHumidity); err != nil {
+		return VentilationAdvice{}, err
+	}
+	if math.IsNaN(outdoorDewpoint) || math.IsInf(outdoorDewpoint, 0) {
+		return VentilationAdvice{}, fmt.Errorf("invalid outdoor dew point %v", outdoorDewpoint)
+	}
+
+	indoor := a.absoluteHumidity(indoorTemperature, indoorHumidity)
+	outdoor := a.outdoorAbsoluteHumidity(indoorTemperature, outdoorDewpoint)
+	predicted := math.Min(100, SaturationVaporPressure(outdoorDewpoint)/SaturationVaporPressure(indoorTemperature)*100)
+
+	// propagate each input uncertainty separately and combine them as root sum of squares
+	dTemperature := math.Abs(a.absoluteHumidity(indoorTemperature+a.TemperatureUncertainty, indoorHumidity)-indoor) +
+		math.Abs(a.outdoorAbsoluteHumidity(indoorTemperature+a.TemperatureUncertainty, outdoorDewpoint)-outdoor)
+	dHumidity := math.Abs(a.absoluteHumidity(indoorTemperature, math.Min(100, indoorHumidity+a.HumidityUncertainty)) - indoor)
+	dDewpoint := math.Abs(a.outdoorAbsoluteHumidity(indoorTemperature, outdoorDewpoint+a.DewPointUncertainty) - outdoor)
+
+	advice := VentilationAdvice{
+		IndoorAbsoluteHumidity:  indoor,
+		OutdoorAbsoluteHumidity: outdoor,
+		PredictedHumidity:       predicted,
+		Margin:                  indoor - outdoor - a.MinimumDifference,
+		Uncertainty:             math.Sqrt(dTemperature*dTemperature + dHumidity*dHumidity + dDewpoint*dDewpoint),
+	}
+	advice.OpenWindows = advice.Margin > 0
+	advice.Confidence = confidence(advice.Margin, advice.Uncertainty)
+
+	switch {
+	case !advice.OpenWindows:
+		advice.Reason = "outdoor air would add moisture"
+	case predicted < a.MinimumHumidity && indoorHumidity < a.MinimumHumidity+10:
+		advice.OpenWindows = false
+		advice.Reason = fmt.Sprintf("outdoor air would dry the room to %.0f%%", predicted)
+		advice.Confidence = confidence(a.MinimumHumidity-predicted, a.HumidityUncertainty)
+	default:
+		advice.Reason = "outdoor air is drier"
+	}
+	return advice, nil
+}
+
+func (a VentilationAdvisor) absoluteHumidity(temperature, relativeHumidity float64) float64 {
+	return AbsoluteHumidityFromVaporPressure(temperature, relativeHumidity/100*SaturationVaporPressure(temperature))
+}
+
+// outdoorAbsoluteHumidity is the outdoor vapor content once warmed to indoor temperature
+func (a VentilationAdvisor) outdoorAbsoluteHumidity(indoorTemperature, outdoorDewpoint float64) float64 {
+	return AbsoluteHumidityFromVaporPressure(indoorTemperature, SaturationVaporPressure(outdoorDewpoint))
+}
+
+// confidence is the normal probability that the true margin has the same sign
+func confidence(margin, uncertainty float64) float64 {
+	if uncertainty <= 0 {
+		return 1
+	}
+	return 0.5 * (1 + math.Erf(math.Abs(margin)/uncertainty/math.Sqrt2))
+}
@@ -0,0 +1,75 @@
+package calculations
+
+import (
+	"math"
+	"testing"
+)
+
+func TestVentilationAdvisor_Advise(t *testing.T) {
+	tests := []struct {
+		name              string
+		indoorTemperature float64
+		indoorHumidity    float64
+		outdoorDewpoint   float64
+		openWindows       bool
+		predictedHumidity float64
+	}{
+		{"humid inside dry outside", 22, 65, 5, true, 33.0},
+		{"muggy outside", 24, 50, 20, false, 78.7},
+		{"same dew point", 20, 60, 12, false, 60.0},
+		{"winter would over-dry", 21, 35, -10, false, 11.3},
+		{"winter with a damp room", 21, 60, -10, true, 11.3},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			advice, err := DefaultVentilationAdvisor.Advise(test.indoorTemperature, test.indoorHumidity, test.outdoorDewpoint)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if advice.OpenWindows != test.openWindows {
+				t.Errorf("expected open windows %t, got %+v", test.openWindows, advice)
+			}
+			if math.Abs(advice.PredictedHumidity-test.predictedHumidity) > 0.5 {
+				t.Errorf("expected predicted humidity %.1f, got %.2f", test.predictedHumidity, advice.PredictedHumidity)
+			}
+			if advice.Confidence < 0.5 || advice.Confidence > 1 {
+				t.Errorf("confidence %f out of range", advice.Confidence)
+			}
+			if advice.Reason == "" {
+				t.Error("expected a reason")
+			}
+		})
+	}
+}
+
+func TestVentilationAdvisor_Confidence(t *testing.T) {
+	clear, _ := DefaultVentilationAdvisor.Advise(25, 70, 0)
+	marginal, _ := DefaultVentilationAdvisor.Advise(20, 62, 12)
+	if clear.Confidence < 0.99 {
+		t.Errorf("expected a clear case to be confident, got %+v", clear)
+	}
+	if marginal.Confidence > 0.8 || marginal.Uncertainty <= 0 {
+		t.Errorf("expected a marginal case to have low confidence, got %+v", marginal)
+	}
+}
+
+func TestVentilationAdvisor_DiffersFromDewpointDelta(t *testing.T) {
+	// the old rule opened windows whenever the indoor dew point was within 1 C of outdoors
+	advice, _ := DefaultVentilationAdvisor.Advise(20, 58, 12)
+	if advice.OpenWindows {
+		t.Errorf("expected windows closed when outdoor air is as humid as indoors, got %+v", advice)
+	}
+	if advice.IndoorAbsoluteHumidity >= advice.OutdoorAbsoluteHumidity {
+		t.Errorf("expected outdoor air to hold more water, got %+v", advice)
+	}
+}
+
+func TestVentilationAdvisor_InvalidInput(t *testing.T) {
+	if _, err := DefaultVentilationAdvisor.Advise(20, 120, 10); err == nil {
+		t.Error("expected an error for humidity over 100%")
+	}
+	if _, err := DefaultVentilationAdvisor.Advise(20, 50, math.NaN()); err == nil {
+		t.Error("expected an error for a NaN dew point")
+	}
+}
@@ -0,0 +1,146 @@
+package calibration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/mugglemath/dewdrop-go/pkg/models"
+)
+
+// minTwoPointSpan is how far apart (in %RH) two humidity references must be
+// before they are used for a two-point calibration
+const minTwoPointSpan = 10.0
+
+// Linear corrects a raw value as raw*Slope + Offset. A zero slope is treated as 1.
+type Linear struct {
+	Offset float64 `json:"offset"`
+	Slope  float64 `json:"slope"`
+}
+
+func (l Linear) Apply(value float64) float64 {
+	return value*l.slope() + l.Offset
+}
+
+func (l Linear) String() string {
+	return fmt.Sprintf("offset %.3f slope %.4f", l.Offset, l.slope())
+}
+
+func (l Linear) slope() float64 {
+	if l.Slope == 0 {
+		return 1
+	}
+	return l.Slope
+}
+
+// Point is a raw sensor value recorded next to a reference instrument
+type Point struct {
+	Raw       float64 `json:"raw"`
+	Reference float64 `json:"reference"`
+}
+
+type Calibration struct {
+	Temperature Linear `json:"temperature"`
+	Humidity    Linear `json:"humidity"`
+	// HumidityPoints are the references Humidity was derived from, e.g. salt tests at 33% and 75%
+	HumidityPoints []Point `json:"humidity_points,omitempty"`
+}
+
+// Apply returns the corrected temperature and humidity
+func (c Calibration) Apply(temperature, humidity float64) (float64, float64) {
+	return c.Temperature.Apply(temperature), math.Max(0, math.Min(100, c.Humidity.Apply(humidity)))
+}
+
+// AddTemperatureReference sets the temperature offset so raw reads as reference, keeping the slope
+func (c *Calibration) AddTemperatureReference(raw, reference float64) {
+	slope := c.Temperature.slope()
+	c.Temperature = Linear{Offset: reference - raw*slope, Slope: slope}
+}
+
+// AddHumidityReference records a humidity reference and derives the correction from the
+// lowest and highest recorded references, or a plain offset if there is only one
+func (c *Calibration) AddHumidityReference(raw, reference float64) {
+	points := []Point{{Raw: raw, Reference: reference}}
+	for _, p := range c.HumidityPoints {
+		if math.Abs(p.Reference-reference) >= minTwoPointSpan {
+			points = append(points, p)
+		}
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Reference < points[j].Reference })
+	c.HumidityPoints = points
+
+	low, high := points[0], points[len(points)-1]
+	if len(points) == 1 || high.Raw == low.Raw {
+		c.Humidity = Linear{Offset: reference - raw, Slope: 1}
+		return
+	}
+	slope := (high.Reference - low.Reference) / (high.Raw - low.Raw)
+	c.Humidity = Linear{Offset: low.Reference - slope*low.Raw, Slope: slope}
+}
+
+// Store holds calibrations keyed by device ID and persists them as JSON
+type Store struct {
+	path    string
+	Devices map[string]Calibration `json:"devices"`
+}
+
+// Load reads the calibration file at path, a missing file yields an empty store
+func Load(path string) (*Store, error) {
+	store := &Store{path: path, Devices: map[string]Calibration{}}
+	if path == "" {
+		return store, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read calibration file: %w", err)
+	}
+	if err := json.Unmarshal(content, store); err != nil {
+		return nil, fmt.Errorf("failed to parse calibration file: %w", err)
+	}
+	if store.Devices == nil {
+		store.Devices = map[string]Calibration{}
+	}
+	return store, nil
+}
+
+func (s *Store) Get(deviceID uint64) (Calibration, bool) {
+	c, ok := s.Devices[strconv.FormatUint(deviceID, 10)]
+	return c, ok
+}
+
+func (s *Store) Set(deviceID uint64, c Calibration) {
+	s.Devices[strconv.FormatUint(deviceID, 10)] = c
+}
+
+// Apply corrects a reading in place, keeping the device values in Raw
+func (s *Store) Apply(data *models.IndoorSensorData) {
+	if data.Raw == nil {
+		data.Raw = &models.RawReading{Temperature: data.Temperature, Humidity: data.Humidity}
+	}
+	c, ok := s.Get(data.DeviceID)
+	if !ok {
+		return
+	}
+	temperature, humidity := c.Apply(float64(data.Temperature), float64(data.Humidity))
+	data.Temperature = float32(temperature)
+	data.Humidity = float32(humidity)
+}
+
+func (s *Store) Save() error {
+	if s.path == "" {
+		return errors.New("no calibration file configured")
+	}
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, append(content, '\n'), 0644)
+}
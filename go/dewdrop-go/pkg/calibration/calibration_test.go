@@ -0,0 +1,102 @@
+package calibration
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/mugglemath/dewdrop-go/pkg/models"
+)
+
+func TestLinearApply(t *testing.T) {
+	tests := []struct {
+		linear   Linear
+		value    float64
+		expected float64
+	}{
+		{Linear{}, 21.5, 21.5},
+		{Linear{Offset: -0.8}, 21.5, 20.7},
+		{Linear{Offset: 2, Slope: 0.9}, 50, 47},
+	}
+	for _, test := range tests {
+		if actual := test.linear.Apply(test.value); math.Abs(actual-test.expected) > 1e-9 {
+			t.Errorf("%+v.Apply(%f) = %f, expected %f", test.linear, test.value, actual, test.expected)
+		}
+	}
+}
+
+func TestApply_ClampsHumidity(t *testing.T) {
+	c := Calibration{Humidity: Linear{Offset: 4}}
+	if _, humidity := c.Apply(20, 98); humidity != 100 {
+		t.Errorf("expected humidity to be clamped to 100, got %f", humidity)
+	}
+}
+
+func TestAddTemperatureReference(t *testing.T) {
+	var c Calibration
+	c.AddTemperatureReference(22.3, 21.5)
+	if math.Abs(c.Temperature.Apply(22.3)-21.5) > 1e-9 {
+		t.Errorf("expected 22.3 to read as 21.5, got %f", c.Temperature.Apply(22.3))
+	}
+}
+
+func TestAddHumidityReference_SinglePoint(t *testing.T) {
+	var c Calibration
+	c.AddHumidityReference(79.0, 75.3)
+	if math.Abs(c.Humidity.Offset+3.7) > 1e-9 || c.Humidity.Slope != 1 {
+		t.Errorf("expected offset -3.7 and slope 1, got %+v", c.Humidity)
+	}
+}
+
+func TestAddHumidityReference_TwoPoint(t *testing.T) {
+	var c Calibration
+	c.AddHumidityReference(35.0, 33.0)
+	c.AddHumidityReference(79.0, 75.0)
+
+	if len(c.HumidityPoints) != 2 {
+		t.Fatalf("expected 2 humidity points, got %d", len(c.HumidityPoints))
+	}
+	for _, p := range c.HumidityPoints {
+		if math.Abs(c.Humidity.Apply(p.Raw)-p.Reference) > 1e-9 {
+			t.Errorf("expected %f to read as %f, got %f", p.Raw, p.Reference, c.Humidity.Apply(p.Raw))
+		}
+	}
+
+	// a new reference close to an existing one replaces it
+	c.AddHumidityReference(80.0, 75.5)
+	if len(c.HumidityPoints) != 2 || c.HumidityPoints[1].Raw != 80.0 {
+		t.Errorf("expected the 75%% point to be replaced, got %+v", c.HumidityPoints)
+	}
+}
+
+func TestStore_SaveLoadApply(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calibration.json")
+	store, err := Load(path)
+	if err != nil {
+		t.Fatalf("expected no error for a missing file, got %v", err)
+	}
+	store.Set(123, Calibration{Temperature: Linear{Offset: -0.5}, Humidity: Linear{Offset: 2}})
+	if err := store.Save(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	data := models.IndoorSensorData{DeviceID: 123, Temperature: 20.5, Humidity: 50}
+	loaded.Apply(&data)
+
+	if data.Temperature != 20 || data.Humidity != 52 {
+		t.Errorf("expected corrected values 20 and 52, got %f and %f", data.Temperature, data.Humidity)
+	}
+	if data.Raw == nil || data.Raw.Temperature != 20.5 || data.Raw.Humidity != 50 {
+		t.Errorf("expected raw values to be kept, got %+v", data.Raw)
+	}
+
+	other := models.IndoorSensorData{DeviceID: 456, Temperature: 20.5, Humidity: 50}
+	loaded.Apply(&other)
+	if other.Temperature != 20.5 || other.Humidity != 50 {
+		t.Errorf("expected uncalibrated device to be unchanged, got %+v", other)
+	}
+}
@@ -0,0 +1,132 @@
+package filter
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter smooths a single channel of readings
+type Filter interface {
+	Apply(value float64, at time.Time) float64
+}
+
+// Median outputs the median of the last Window values
+type Median struct {
+	Window int       `json:"window"`
+	Values []float64 `json:"values"`
+}
+
+func (m *Median) Apply(value float64, _ time.Time) float64 {
+	m.Values = appendWindow(m.Values, value, m.Window)
+	return median(m.Values)
+}
+
+// EMA is an exponential moving average, Alpha close to 1 follows the input closely
+type EMA struct {
+	Alpha float64  `json:"alpha"`
+	Value *float64 `json:"value,omitempty"`
+}
+
+func (e *EMA) Apply(value float64, _ time.Time) float64 {
+	if e.Value != nil {
+		value = e.Alpha*value + (1-e.Alpha)*(*e.Value)
+	}
+	e.Value = &value
+	return value
+}
+
+// RateLimiter caps how fast the output may change, in units per minute
+type RateLimiter struct {
+	MaxPerMinute float64   `json:"max_per_minute"`
+	Value        *float64  `json:"value,omitempty"`
+	LastUpdate   time.Time `json:"last_update"`
+}
+
+func (r *RateLimiter) Apply(value float64, at time.Time) float64 {
+	if r.Value != nil {
+		maxDelta := r.MaxPerMinute * at.Sub(r.LastUpdate).Minutes()
+		value = math.Max(*r.Value-maxDelta, math.Min(*r.Value+maxDelta, value))
+	}
+	r.Value = &value
+	r.LastUpdate = at
+	return value
+}
+
+// Hampel replaces values more than Threshold scaled MADs from the window median with the median.
+// While the window is flat the MAD is 0 and any change would be rejected, so values pass.
+type Hampel struct {
+	Window    int       `json:"window"`
+	Threshold float64   `json:"threshold"`
+	Values    []float64 `json:"values"`
+}
+
+// madScale makes the median absolute deviation consistent with the standard deviation
+const madScale = 1.4826
+
+func (h *Hampel) Apply(value float64, _ time.Time) float64 {
+	h.Values = appendWindow(h.Values, value, h.Window)
+	if len(h.Values) < 3 {
+		return value
+	}
+
+	m := median(h.Values)
+	deviations := make([]float64, len(h.Values))
+	for i, v := range h.Values {
+		deviations[i] = math.Abs(v - m)
+	}
+	mad := madScale * median(deviations)
+
+	// the raw value stays in the window so a sustained change takes over the median
+	if mad > 0 && math.Abs(value-m) > h.Threshold*mad {
+		return m
+	}
+	return value
+}
+
+// Parse builds a filter from a spec like "median:5", "ema:0.3", "rate:2" or "hampel:7:3"
+func Parse(spec string) (Filter, error) {
+	parts := strings.Split(strings.TrimSpace(spec), ":")
+	args := make([]float64, len(parts)-1)
+	for i, part := range parts[1:] {
+		value, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter argument %q in %q", part, spec)
+		}
+		args[i] = value
+	}
+
+	switch {
+	case parts[0] == "median" && len(args) == 1 && args[0] >= 1:
+		return &Median{Window: int(args[0])}, nil
+	case parts[0] == "ema" && len(args) == 1 && args[0] > 0 && args[0] <= 1:
+		return &EMA{Alpha: args[0]}, nil
+	case parts[0] == "rate" && len(args) == 1 && args[0] > 0:
+		return &RateLimiter{MaxPerMinute: args[0]}, nil
+	case parts[0] == "hampel" && len(args) == 2 && args[0] >= 3 && args[1] > 0:
+		return &Hampel{Window: int(args[0]), Threshold: args[1]}, nil
+	default:
+		return nil, fmt.Errorf("invalid filter %q, use median:N, ema:ALPHA, rate:PER_MINUTE or hampel:N:K", spec)
+	}
+}
+
+func appendWindow(values []float64, value float64, window int) []float64 {
+	values = append(values, value)
+	if window > 0 && len(values) > window {
+		values = values[len(values)-window:]
+	}
+	return values
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
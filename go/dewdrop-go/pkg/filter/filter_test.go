@@ -0,0 +1,174 @@
+package filter
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mugglemath/dewdrop-go/pkg/models"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func applyAll(f Filter, values []float64) []float64 {
+	var out []float64
+	for i, v := range values {
+		out = append(out, f.Apply(v, start.Add(time.Duration(i)*time.Minute)))
+	}
+	return out
+}
+
+func TestMedian(t *testing.T) {
+	out := applyAll(&Median{Window: 3}, []float64{20, 21, 35, 22, 23})
+	expected := []float64{20, 20.5, 21, 22, 23}
+	for i := range expected {
+		if out[i] != expected[i] {
+			t.Errorf("step %d: expected %f, got %f", i, expected[i], out[i])
+		}
+	}
+}
+
+func TestEMA(t *testing.T) {
+	out := applyAll(&EMA{Alpha: 0.5}, []float64{20, 22, 22})
+	expected := []float64{20, 21, 21.5}
+	for i := range expected {
+		if out[i] != expected[i] {
+			t.Errorf("step %d: expected %f, got %f", i, expected[i], out[i])
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	out := applyAll(&RateLimiter{MaxPerMinute: 1}, []float64{50, 60, 60, 49})
+	expected := []float64{50, 51, 52, 51}
+	for i := range expected {
+		if out[i] != expected[i] {
+			t.Errorf("step %d: expected %f, got %f", i, expected[i], out[i])
+		}
+	}
+}
+
+func TestHampel_RejectsSpike(t *testing.T) {
+	h := &Hampel{Window: 7, Threshold: 3}
+	out := applyAll(h, []float64{55, 55.2, 54.9, 55.1, 85, 55.0})
+
+	if out[4] > 56 {
+		t.Errorf("expected the spike to be replaced by the median, got %f", out[4])
+	}
+	if out[5] != 55.0 {
+		t.Errorf("expected normal values to pass through, got %f", out[5])
+	}
+}
+
+func TestHampel_FollowsStep(t *testing.T) {
+	h := &Hampel{Window: 5, Threshold: 3}
+	out := applyAll(h, []float64{20, 20.1, 19.9, 25, 25.1, 24.9, 25, 25.2})
+
+	if math.Abs(out[len(out)-1]-25.2) > 1e-9 {
+		t.Errorf("expected a sustained change to pass through, got %f", out[len(out)-1])
+	}
+}
+
+func TestHampel_StepAfterFlatReadings(t *testing.T) {
+	h := &Hampel{Window: 7, Threshold: 3}
+	out := applyAll(h, []float64{50, 50, 50, 50, 50, 50, 50, 53})
+
+	if out[len(out)-1] != 53 {
+		t.Errorf("expected the step after flat readings to pass through, got %f", out[len(out)-1])
+	}
+}
+
+func TestParse(t *testing.T) {
+	valid := []string{"median:5", "ema:0.3", "rate:2", "hampel:7:3", " ema:1 "}
+	for _, spec := range valid {
+		if _, err := Parse(spec); err != nil {
+			t.Errorf("expected %q to parse, got %v", spec, err)
+		}
+	}
+
+	invalid := []string{"", "median", "median:0", "ema:0", "ema:1.5", "rate:-1", "hampel:2:3", "hampel:7", "kalman:1", "ema:x"}
+	for _, spec := range invalid {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("expected an error for %q", spec)
+		}
+	}
+}
+
+func TestBank_AppliesPerDeviceAndKeepsRaw(t *testing.T) {
+	bank, err := NewBank("ema:0.5", "ema:0.5")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	first := models.IndoorSensorData{DeviceID: 1, Temperature: 20, Humidity: 50}
+	bank.Apply(&first, start)
+	other := models.IndoorSensorData{DeviceID: 2, Temperature: 30, Humidity: 70}
+	bank.Apply(&other, start)
+	second := models.IndoorSensorData{DeviceID: 1, Temperature: 22, Humidity: 60}
+	bank.Apply(&second, start.Add(time.Minute))
+
+	if second.Temperature != 21 || second.Humidity != 55 {
+		t.Errorf("expected filtered values 21 and 55, got %f and %f", second.Temperature, second.Humidity)
+	}
+	if second.Raw == nil || second.Raw.Temperature != 22 || second.Raw.Humidity != 60 {
+		t.Errorf("expected raw values to be kept, got %+v", second.Raw)
+	}
+	if other.Temperature != 30 {
+		t.Errorf("expected devices to be filtered independently, got %f", other.Temperature)
+	}
+}
+
+func TestBank_SeparateChannels(t *testing.T) {
+	bank, err := NewBank("rate:1", "rate:10")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	first := models.IndoorSensorData{DeviceID: 1, Temperature: 20, Humidity: 50}
+	bank.Apply(&first, start)
+	second := models.IndoorSensorData{DeviceID: 1, Temperature: 25, Humidity: 70}
+	bank.Apply(&second, start.Add(time.Minute))
+
+	if second.Temperature != 21 || second.Humidity != 60 {
+		t.Errorf("expected each channel limited by its own rate, got %f and %f", second.Temperature, second.Humidity)
+	}
+	if _, err := NewBank("", "rate:x"); err == nil || err.Error() != `humidity: invalid filter argument "x" in "rate:x"` {
+		t.Errorf("expected the humidity spec to be rejected, got %v", err)
+	}
+}
+
+func TestBank_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filters.json")
+	bank, _ := NewBank("median:3,ema:0.5", "")
+	for i, v := range []float32{20, 21, 22} {
+		data := models.IndoorSensorData{DeviceID: 1, Temperature: v, Humidity: 50}
+		bank.Apply(&data, start.Add(time.Duration(i)*time.Minute))
+	}
+	if err := bank.Save(path); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	restored, _ := NewBank("median:3,ema:0.5", "")
+	if err := restored.Load(path); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := models.IndoorSensorData{DeviceID: 1, Temperature: 23, Humidity: 50}
+	actual := expected
+	bank.Apply(&expected, start.Add(3*time.Minute))
+	restored.Apply(&actual, start.Add(3*time.Minute))
+	if actual.Temperature != expected.Temperature {
+		t.Errorf("expected restored bank to output %f, got %f", expected.Temperature, actual.Temperature)
+	}
+
+	// state saved with a different spec is ignored
+	changed, _ := NewBank("ema:0.5", "")
+	if err := changed.Load(path); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	data := models.IndoorSensorData{DeviceID: 1, Temperature: 30, Humidity: 50}
+	changed.Apply(&data, start)
+	if data.Temperature != 30 {
+		t.Errorf("expected fresh filters for a changed spec, got %f", data.Temperature)
+	}
+}
@@ -0,0 +1,164 @@
+package filter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mugglemath/dewdrop-go/pkg/models"
+)
+
+// maxStateAge is how old saved filter state may be before it is discarded on load
+const maxStateAge = time.Hour
+
+// Pipeline runs filters in order
+type Pipeline []Filter
+
+// NewPipeline parses a comma separated list of filter specs, e.g. "hampel:7:3,median:5,ema:0.3"
+func NewPipeline(spec string) (Pipeline, error) {
+	var pipeline Pipeline
+	if strings.TrimSpace(spec) == "" {
+		return pipeline, nil
+	}
+	for _, part := range strings.Split(spec, ",") {
+		f, err := Parse(part)
+		if err != nil {
+			return nil, err
+		}
+		pipeline = append(pipeline, f)
+	}
+	return pipeline, nil
+}
+
+func (p Pipeline) Apply(value float64, at time.Time) float64 {
+	for _, f := range p {
+		value = f.Apply(value, at)
+	}
+	return value
+}
+
+type deviceFilters struct {
+	Temperature Pipeline `json:"temperature"`
+	Humidity    Pipeline `json:"humidity"`
+}
+
+// Bank keeps a temperature and a humidity pipeline per device. Each channel has its own
+// spec, a rate limit in C per minute means something else in %RH per minute.
+type Bank struct {
+	temperatureSpec string
+	humiditySpec    string
+	devices         map[string]*deviceFilters
+}
+
+func NewBank(temperatureSpec, humiditySpec string) (*Bank, error) {
+	if _, err := NewPipeline(temperatureSpec); err != nil {
+		return nil, fmt.Errorf("temperature: %w", err)
+	}
+	if _, err := NewPipeline(humiditySpec); err != nil {
+		return nil, fmt.Errorf("humidity: %w", err)
+	}
+	return &Bank{temperatureSpec: temperatureSpec, humiditySpec: humiditySpec, devices: map[string]*deviceFilters{}}, nil
+}
+
+// Apply filters a reading in place, keeping the unfiltered values in Raw
+func (b *Bank) Apply(data *models.IndoorSensorData, at time.Time) {
+	if data.Raw == nil {
+		data.Raw = &models.RawReading{Temperature: data.Temperature, Humidity: data.Humidity}
+	}
+	if b.temperatureSpec == "" && b.humiditySpec == "" {
+		return
+	}
+
+	filters := b.device(strconv.FormatUint(data.DeviceID, 10))
+	data.Temperature = float32(filters.Temperature.Apply(float64(data.Temperature), at))
+	data.Humidity = float32(filters.Humidity.Apply(float64(data.Humidity), at))
+}
+
+func (b *Bank) device(id string) *deviceFilters {
+	filters, ok := b.devices[id]
+	if !ok {
+		// the specs were validated by NewBank
+		temperature, _ := NewPipeline(b.temperatureSpec)
+		humidity, _ := NewPipeline(b.humiditySpec)
+		filters = &deviceFilters{Temperature: temperature, Humidity: humidity}
+		b.devices[id] = filters
+	}
+	return filters
+}
+
+type bankState struct {
+	TemperatureSpec string                                  `json:"temperature_spec"`
+	HumiditySpec    string                                  `json:"humidity_spec"`
+	SavedAt         time.Time                               `json:"saved_at"`
+	Devices         map[string]map[string][]json.RawMessage `json:"devices"`
+}
+
+// Save writes the filter state so a restart continues where it left off
+func (b *Bank) Save(path string) error {
+	state := bankState{TemperatureSpec: b.temperatureSpec, HumiditySpec: b.humiditySpec, SavedAt: time.Now(),
+		Devices: map[string]map[string][]json.RawMessage{}}
+	for id, filters := range b.devices {
+		channels := map[string][]json.RawMessage{}
+		for name, pipeline := range map[string]Pipeline{"temperature": filters.Temperature, "humidity": filters.Humidity} {
+			for _, f := range pipeline {
+				raw, err := json.Marshal(f)
+				if err != nil {
+					return err
+				}
+				channels[name] = append(channels[name], raw)
+			}
+		}
+		state.Devices[id] = channels
+	}
+
+	content, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err := os.WriteFile(tmp, content, 0644); err != nil {
+		return fmt.Errorf("failed to write filter state: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// Load restores filter state saved with the same specs, ignoring missing or stale files
+func (b *Bank) Load(path string) error {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read filter state: %w", err)
+	}
+
+	var state bankState
+	if err := json.Unmarshal(content, &state); err != nil {
+		return fmt.Errorf("failed to parse filter state: %w", err)
+	}
+	if state.TemperatureSpec != b.temperatureSpec || state.HumiditySpec != b.humiditySpec ||
+		time.Since(state.SavedAt) > maxStateAge {
+		return nil
+	}
+
+	for id, channels := range state.Devices {
+		filters := b.device(id)
+		for name, pipeline := range map[string]Pipeline{"temperature": filters.Temperature, "humidity": filters.Humidity} {
+			saved := channels[name]
+			if len(saved) != len(pipeline) {
+				continue
+			}
+			for i, f := range pipeline {
+				if err := json.Unmarshal(saved[i], f); err != nil {
+					return fmt.Errorf("failed to restore filter state: %w", err)
+				}
+			}
+		}
+	}
+	return nil
+}
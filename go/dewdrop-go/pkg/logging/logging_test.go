@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]slog.Level{
+		"":      slog.LevelInfo,
+		"debug": slog.LevelDebug,
+		"WARN":  slog.LevelWarn,
+		"error": slog.LevelError,
+	}
+	for input, expected := range tests {
+		level, err := ParseLevel(input)
+		if err != nil || level != expected {
+			t.Errorf("ParseLevel(%q) = %v, %v, expected %v", input, level, err, expected)
+		}
+	}
+
+	_, err := ParseLevel("verbose")
+	expected := `unknown log level "verbose", use debug, info, warn or error`
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+}
+
+func TestNew_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "warn", "json")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	logger.Info("hidden")
+	logger.Warn("shown", "device_id", 7)
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("expected a single JSON record, got %q: %v", buf.String(), err)
+	}
+	if record["msg"] != "shown" || record["device_id"] != 7.0 {
+		t.Errorf("unexpected record %v", record)
+	}
+
+	_, err = New(&buf, "", "xml")
+	expected := `unknown log format "xml", use text or json`
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+}
+
+func TestValidRequestID(t *testing.T) {
+	tests := map[string]bool{
+		NewRequestID():          true,
+		"dewdrop-1.poll_2":      true,
+		"":                      false,
+		"id with spaces":        false,
+		"line\nbreak":           false,
+		strings.Repeat("a", 65): false,
+	}
+	for id, expected := range tests {
+		if actual := ValidRequestID(id); actual != expected {
+			t.Errorf("ValidRequestID(%q) = %t, expected %t", id, actual, expected)
+		}
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _ := New(&buf, "", "text")
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	defer slog.SetDefault(previous)
+
+	ctx := WithRequestID(context.Background(), "abc123")
+	if id := RequestID(ctx); id != "abc123" {
+		t.Errorf("expected request ID abc123, got %q", id)
+	}
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx = trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+	FromContext(ctx).Info("posted")
+	for _, expected := range []string{"request_id=abc123", "trace_id=4bf92f3577b34da6a3ce929d0e0e4736"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("expected %s in %q", expected, buf.String())
+		}
+	}
+}
@@ -2,8 +2,27 @@ package models
 
 // IndoorSensorData is the response from the Arduino
 type IndoorSensorData struct {
-	DeviceID    uint64  `json:"device_id"`
+	DeviceID    uint64      `json:"device_id"`
+	Temperature float32     `json:"temperature"`
+	Humidity    float32     `json:"humidity"`
+	LedState    bool        `json:"led_state"`
+	Info        *DeviceInfo `json:"info,omitempty"`
+	Raw         *RawReading `json:"raw,omitempty"`
+}
+
+// RawReading keeps the values reported by the device before any correction
+type RawReading struct {
 	Temperature float32 `json:"temperature"`
 	Humidity    float32 `json:"humidity"`
-	LedState    bool    `json:"led_state"`
+}
+
+// DeviceInfo is only reported by firmware that sends JSON payloads
+type DeviceInfo struct {
+	FirmwareVersion string `json:"firmware_version,omitempty"`
+	SensorModel     string `json:"sensor_model,omitempty"`
+	UptimeSeconds   uint64 `json:"uptime_seconds"`
+	SampleCount     uint64 `json:"sample_count"`
+	// Capabilities lists the formats and protocols the firmware speaks, e.g. csv, json and framed
+	Capabilities []string           `json:"capabilities,omitempty"`
+	Metrics      map[string]float64 `json:"metrics,omitempty"`
 }
@@ -0,0 +1,78 @@
+// Package tracing sets up OpenTelemetry for dewdrop and go-dew. Spans are propagated
+// between them with the W3C traceparent header.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Shutdown flushes pending spans
+type Shutdown func(context.Context) error
+
+// Setup installs the global tracer provider for the exporter named by OTEL_TRACES_EXPORTER:
+// otlp sends to OTEL_EXPORTER_OTLP_ENDPOINT over HTTP, console writes spans to stdout and
+// none, or an empty value, disables tracing. OTEL_SERVICE_NAME overrides serviceName.
+func Setup(ctx context.Context, serviceName, exporter string) (Shutdown, error) {
+	var spanExporter sdktrace.SpanExporter
+	var err error
+	switch strings.ToLower(exporter) {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		spanExporter, err = otlptracehttp.New(ctx)
+	case "console", "stdout":
+		spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q, use otlp, console or none", exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+	return Install(ctx, serviceName, sdktrace.WithBatcher(spanExporter))
+}
+
+// Install sets the global tracer provider with the given exporter or processor option,
+// which lets tests record spans in memory with tracetest
+func Install(ctx context.Context, serviceName string, spans sdktrace.TracerProviderOption) (Shutdown, error) {
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(spans, sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// Start begins a span with the global tracer provider, which is a no-op until Setup
+func Start(ctx context.Context, tracer, name string, attrs ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(tracer).Start(ctx, name, attrs...)
+}
+
+// End records err on the span, if any, and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,93 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSetup_UnknownExporter(t *testing.T) {
+	_, err := Setup(context.Background(), "test", "zipkin")
+	expected := `unknown trace exporter "zipkin", use otlp, console or none`
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+}
+
+func TestSetup_OTLP(t *testing.T) {
+	// an in-process collector receiving OTLP over HTTP
+	var mu sync.Mutex
+	var paths []string
+	var received int
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		received += len(body)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", collector.URL)
+
+	shutdown, err := Setup(context.Background(), "test", "otlp")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	_, span := Start(context.Background(), "test", "poll")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("expected no error on shutdown, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(paths) != 1 || paths[0] != "/v1/traces" || received == 0 {
+		t.Errorf("expected one export to /v1/traces, got %v with %d bytes", paths, received)
+	}
+}
+
+func TestStartAndEnd(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	shutdown, err := Install(context.Background(), "test", sdktrace.WithSyncer(exporter))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer shutdown(context.Background())
+
+	ctx, parent := Start(context.Background(), "test", "poll")
+	_, child := Start(ctx, "test", "device.read")
+	End(child, errors.New("no response"))
+	End(parent, nil)
+
+	// the propagator carries the trace to the other service
+	header := http.Header{}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+	if header.Get("traceparent") == "" {
+		t.Error("expected a traceparent header")
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if spans[0].Name != "device.read" || spans[0].Parent.SpanID() != spans[1].SpanContext.SpanID() {
+		t.Errorf("expected device.read to be a child of poll, got %v", spans[0])
+	}
+	if spans[0].Status.Code != codes.Error || spans[0].Status.Description != "no response" {
+		t.Errorf("expected an error status, got %v", spans[0].Status)
+	}
+	if spans[1].Status.Code != codes.Unset {
+		t.Errorf("expected no status on poll, got %v", spans[1].Status)
+	}
+}
@@ -0,0 +1,146 @@
+// Package units converts the metric values used internally for display. Values are
+// always stored, transmitted and configured in metric unless a field says otherwise.
+package units
+
+import (
+	"fmt"
+	"strings"
+)
+
+type System int
+
+const (
+	Metric System = iota
+	Imperial
+)
+
+const (
+	gramsPerCubicMeterInGrainsPerCubicFoot = 0.43700
+	hectopascalInInchesOfMercury           = 0.0295300
+	gramsPerKilogramInGrainsPerPound       = 7.0
+)
+
+// Parse accepts metric or imperial, with a few common aliases; empty selects Metric
+func Parse(name string) (System, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "metric", "si", "c", "celsius":
+		return Metric, nil
+	case "imperial", "us", "f", "fahrenheit":
+		return Imperial, nil
+	default:
+		return Metric, fmt.Errorf("unknown unit system %q, use metric or imperial", name)
+	}
+}
+
+func (s System) String() string {
+	if s == Imperial {
+		return "imperial"
+	}
+	return "metric"
+}
+
+// Temperature converts from C
+func (s System) Temperature(celsius float64) float64 {
+	if s == Imperial {
+		return celsius*9/5 + 32
+	}
+	return celsius
+}
+
+// FromTemperature converts a temperature in this system back to C, e.g. for user input
+func (s System) FromTemperature(value float64) float64 {
+	if s == Imperial {
+		return (value - 32) * 5 / 9
+	}
+	return value
+}
+
+// TemperatureDelta converts a difference or margin from C
+func (s System) TemperatureDelta(celsius float64) float64 {
+	if s == Imperial {
+		return celsius * 9 / 5
+	}
+	return celsius
+}
+
+// FromTemperatureDelta converts a difference in this system back to C, e.g. for thresholds
+func (s System) FromTemperatureDelta(delta float64) float64 {
+	if s == Imperial {
+		return delta * 5 / 9
+	}
+	return delta
+}
+
+func (s System) TemperatureUnit() string {
+	if s == Imperial {
+		return "F"
+	}
+	return "C"
+}
+
+// AbsoluteHumidity converts from g/m³
+func (s System) AbsoluteHumidity(gramsPerCubicMeter float64) float64 {
+	if s == Imperial {
+		return gramsPerCubicMeter * gramsPerCubicMeterInGrainsPerCubicFoot
+	}
+	return gramsPerCubicMeter
+}
+
+func (s System) AbsoluteHumidityUnit() string {
+	if s == Imperial {
+		return "gr/ft³"
+	}
+	return "g/m³"
+}
+
+// MixingRatio converts from g/kg
+func (s System) MixingRatio(gramsPerKilogram float64) float64 {
+	if s == Imperial {
+		return gramsPerKilogram * gramsPerKilogramInGrainsPerPound
+	}
+	return gramsPerKilogram
+}
+
+func (s System) MixingRatioUnit() string {
+	if s == Imperial {
+		return "gr/lb"
+	}
+	return "g/kg"
+}
+
+// Pressure converts from hPa
+func (s System) Pressure(hectopascal float64) float64 {
+	if s == Imperial {
+		return hectopascal * hectopascalInInchesOfMercury
+	}
+	return hectopascal
+}
+
+func (s System) PressureUnit() string {
+	if s == Imperial {
+		return "inHg"
+	}
+	return "hPa"
+}
+
+// FormatTemperature formats a value in C with two decimals and its unit
+func (s System) FormatTemperature(celsius float64) string {
+	return fmt.Sprintf("%.2f %s", s.Temperature(celsius), s.TemperatureUnit())
+}
+
+// FormatTemperatureDelta formats a difference in C with two decimals and its unit
+func (s System) FormatTemperatureDelta(celsius float64) string {
+	return fmt.Sprintf("%.2f %s", s.TemperatureDelta(celsius), s.TemperatureUnit())
+}
+
+func (s System) FormatAbsoluteHumidity(gramsPerCubicMeter float64) string {
+	return fmt.Sprintf("%.2f %s", s.AbsoluteHumidity(gramsPerCubicMeter), s.AbsoluteHumidityUnit())
+}
+
+func (s System) FormatMixingRatio(gramsPerKilogram float64) string {
+	return fmt.Sprintf("%.2f %s", s.MixingRatio(gramsPerKilogram), s.MixingRatioUnit())
+}
+
+func (s System) FormatPressure(hectopascal float64) string {
+	return fmt.Sprintf("%.2f %s", s.Pressure(hectopascal), s.PressureUnit())
+}
@@ -0,0 +1,71 @@
+package units
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := map[string]System{
+		"":           Metric,
+		"metric":     Metric,
+		"Celsius":    Metric,
+		"imperial":   Imperial,
+		" US ":       Imperial,
+		"fahrenheit": Imperial,
+	}
+	for name, expected := range tests {
+		actual, err := Parse(name)
+		if err != nil || actual != expected {
+			t.Errorf("Parse(%q) = %v, %v, expected %v", name, actual, err, expected)
+		}
+	}
+	if _, err := Parse("kelvin"); err == nil {
+		t.Error("expected an error for an unknown system")
+	}
+}
+
+func TestConversions(t *testing.T) {
+	tests := []struct {
+		name     string
+		actual   float64
+		expected float64
+	}{
+		{"freezing", Imperial.Temperature(0), 32},
+		{"body temperature", Imperial.Temperature(37), 98.6},
+		{"below zero", Imperial.Temperature(-40), -40},
+		{"from fahrenheit", Imperial.FromTemperature(50), 10},
+		{"delta", Imperial.TemperatureDelta(5), 9},
+		{"delta back", Imperial.FromTemperatureDelta(9), 5},
+		{"absolute humidity", Imperial.AbsoluteHumidity(10), 4.37},
+		{"mixing ratio", Imperial.MixingRatio(10), 70},
+		{"standard pressure", Imperial.Pressure(1013.25), 29.92},
+		{"metric temperature", Metric.Temperature(21.5), 21.5},
+		{"metric delta", Metric.FromTemperatureDelta(2), 2},
+		{"metric pressure", Metric.Pressure(1013.25), 1013.25},
+	}
+	for _, test := range tests {
+		if math.Abs(test.actual-test.expected) > 0.01 {
+			t.Errorf("%s: expected %.2f, got %.4f", test.name, test.expected, test.actual)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := map[string]string{
+		Metric.FormatTemperature(21.456):     "21.46 C",
+		Imperial.FormatTemperature(21.456):   "70.62 F",
+		Imperial.FormatTemperatureDelta(-1):  "-1.80 F",
+		Metric.FormatAbsoluteHumidity(9.5):   "9.50 g/m³",
+		Imperial.FormatAbsoluteHumidity(9.5): "4.15 gr/ft³",
+		Imperial.FormatMixingRatio(8):        "56.00 gr/lb",
+		Imperial.FormatPressure(1000):        "29.53 inHg",
+		Metric.FormatPressure(1000):          "1000.00 hPa",
+		Imperial.String() + Metric.String():  "imperialmetric",
+	}
+	for actual, expected := range tests {
+		if actual != expected {
+			t.Errorf("expected %q, got %q", expected, actual)
+		}
+	}
+}
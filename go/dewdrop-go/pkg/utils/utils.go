@@ -2,7 +2,20 @@ package utils
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
 	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mugglemath/dewdrop-go/pkg/models"
+)
+
+const (
+	minTemperature = -60.0
+	maxTemperature = 125.0
 )
 
 // IsValidResponse checks if Arduino acks with an "a" or gives data in a valid format
@@ -12,7 +25,10 @@ func IsValidResponse(response string) bool {
 
 // IsValidDataFormat checks if Arduino returns data in a valid format
 func IsValidDataFormat(input string) bool {
-	pattern := `^\d{1,20},\d{2}\.\d{2},\d{2}\.\d{2},[01]$`
+	if strings.HasPrefix(input, "{") {
+		return json.Valid([]byte(input))
+	}
+	pattern := `^\d{1,20},-?\d{1,3}\.\d{1,2},\d{1,3}\.\d{1,2},[01]$`
 	matched, _ := regexp.MatchString(pattern, input)
 	return matched
 }
@@ -25,3 +41,139 @@ func SplitAndTrim(s string, sep rune) []string {
 	}
 	return result
 }
+
+// ParseIndoorSensorData parses a JSON payload from newer sketches or the
+// id,temp,hum,led CSV sent by older ones
+func ParseIndoorSensorData(response string) (models.IndoorSensorData, error) {
+	response = strings.TrimSpace(response)
+
+	var data models.IndoorSensorData
+	var err error
+	if strings.HasPrefix(response, "{") {
+		data, err = parseJSONData(response)
+	} else {
+		data, err = parseCSVData(response)
+	}
+	if err != nil {
+		return models.IndoorSensorData{}, err
+	}
+
+	if err := validateReading(data); err != nil {
+		return models.IndoorSensorData{}, err
+	}
+	return data, nil
+}
+
+type jsonPayload struct {
+	DeviceID        json.Number `json:"device_id"`
+	Temperature     *float64    `json:"temperature"`
+	Humidity        *float64    `json:"humidity"`
+	LedState        bool        `json:"led_state"`
+	FirmwareVersion string      `json:"firmware_version"`
+	SensorModel     string      `json:"sensor_model"`
+	UptimeSeconds   uint64      `json:"uptime_seconds"`
+	SampleCount     uint64      `json:"sample_count"`
+	Capabilities    []string    `json:"capabilities"`
+	// Metrics are decoded one by one, so an odd one doesn't lose the reading
+	Metrics map[string]json.RawMessage `json:"metrics"`
+}
+
+func parseJSONData(response string) (models.IndoorSensorData, error) {
+	var payload jsonPayload
+	if err := json.Unmarshal([]byte(response), &payload); err != nil {
+		return models.IndoorSensorData{}, errors.New("invalid data format")
+	}
+
+	deviceID, err := strconv.ParseUint(payload.DeviceID.String(), 10, 64)
+	if err != nil {
+		return models.IndoorSensorData{}, errors.New("invalid device ID format")
+	}
+	if payload.Temperature == nil {
+		return models.IndoorSensorData{}, errors.New("invalid temperature format")
+	}
+	if payload.Humidity == nil {
+		return models.IndoorSensorData{}, errors.New("invalid humidity format")
+	}
+
+	return models.IndoorSensorData{
+		DeviceID:    deviceID,
+		Temperature: float32(*payload.Temperature),
+		Humidity:    float32(*payload.Humidity),
+		LedState:    payload.LedState,
+		Info: &models.DeviceInfo{
+			FirmwareVersion: payload.FirmwareVersion,
+			SensorModel:     payload.SensorModel,
+			UptimeSeconds:   payload.UptimeSeconds,
+			SampleCount:     payload.SampleCount,
+			Capabilities:    payload.Capabilities,
+			Metrics:         numericMetrics(payload.Metrics),
+		},
+	}, nil
+}
+
+// numericMetrics keeps the extra metrics that are numbers and skips the others
+func numericMetrics(raw map[string]json.RawMessage) map[string]float64 {
+	metrics := map[string]float64{}
+	for name, value := range raw {
+		var number *float64
+		if err := json.Unmarshal(value, &number); err != nil || number == nil {
+			slog.Debug("skipping non-numeric device metric", "metric", name, "value", string(value))
+			continue
+		}
+		metrics[name] = *number
+	}
+	if len(metrics) == 0 {
+		return nil
+	}
+	return metrics
+}
+
+func parseCSVData(response string) (models.IndoorSensorData, error) {
+	parts := SplitAndTrim(response, ',')
+	if len(parts) < 4 {
+		return models.IndoorSensorData{}, errors.New("invalid data format")
+	}
+
+	deviceID, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return models.IndoorSensorData{}, errors.New("invalid device ID format")
+	}
+
+	temperature, err := strconv.ParseFloat(parts[1], 32)
+	if err != nil {
+		return models.IndoorSensorData{}, errors.New("invalid temperature format")
+	}
+
+	humidity, err := strconv.ParseFloat(parts[2], 32)
+	if err != nil {
+		return models.IndoorSensorData{}, errors.New("invalid humidity format")
+	}
+
+	var ledState bool
+	if parts[3] == "1" {
+		ledState = true
+	} else if parts[3] == "0" {
+		ledState = false
+	} else {
+		return models.IndoorSensorData{}, errors.New("invalid LED state value")
+	}
+
+	return models.IndoorSensorData{
+		DeviceID:    deviceID,
+		Temperature: float32(temperature),
+		Humidity:    float32(humidity),
+		LedState:    ledState,
+	}, nil
+}
+
+func validateReading(data models.IndoorSensorData) error {
+	temperature := float64(data.Temperature)
+	humidity := float64(data.Humidity)
+	if math.IsNaN(temperature) || temperature < minTemperature || temperature > maxTemperature {
+		return errors.New("temperature out of range")
+	}
+	if math.IsNaN(humidity) || humidity < 0 || humidity > 100 {
+		return errors.New("humidity out of range")
+	}
+	return nil
+}
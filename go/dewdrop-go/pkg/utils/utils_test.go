@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/mugglemath/dewdrop-go/pkg/models"
+)
+
+func TestIsValidDataFormat(t *testing.T) {
+	tests := []struct {
+		input string
+		valid bool
+	}{
+		{"123,25.55,60.01,1", true},
+		{"123,-4.20,85.00,0", true},
+		{"123,5.5,100.00,0", true},
+		{`{"device_id":123,"temperature":-4.2}`, true},
+		{"123,25.55,60.01", false},
+		{"123,25.55,60.01,2", false},
+		{"a", false},
+		{`{"device_id":`, false},
+	}
+	for _, test := range tests {
+		if valid := IsValidDataFormat(test.input); valid != test.valid {
+			t.Errorf("IsValidDataFormat(%q) = %v, expected %v", test.input, valid, test.valid)
+		}
+	}
+}
+
+func TestParseIndoorSensorData_CSV(t *testing.T) {
+	data, err := ParseIndoorSensorData("123,-18.25,100.00,0\r\n")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expectedData := models.IndoorSensorData{
+		DeviceID:    123,
+		Temperature: -18.25,
+		Humidity:    100.0,
+		LedState:    false,
+	}
+	if data != expectedData {
+		t.Errorf("expected data %v, got %v", expectedData, data)
+	}
+}
+
+func TestParseIndoorSensorData_JSON(t *testing.T) {
+	response := `{"device_id":"246813579024680","temperature":-18.25,"humidity":72.5,` +
+		`"led_state":true,"firmware_version":"2.0.0","sensor_model":"SHT31",` +
+		`"uptime_seconds":3600,"sample_count":360,"capabilities":["csv","json"],` +
+		`"metrics":{"rssi":-61,"ssid":"home","battery":null}}`
+
+	data, err := ParseIndoorSensorData(response)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if data.DeviceID != 246813579024680 || data.Temperature != -18.25 || data.Humidity != 72.5 || !data.LedState {
+		t.Errorf("unexpected reading: %+v", data)
+	}
+	if data.Info == nil {
+		t.Fatal("expected device info, got nil")
+	}
+	if data.Info.FirmwareVersion != "2.0.0" || data.Info.SensorModel != "SHT31" ||
+		data.Info.UptimeSeconds != 3600 || data.Info.SampleCount != 360 {
+		t.Errorf("unexpected device info: %+v", data.Info)
+	}
+	if len(data.Info.Capabilities) != 2 || data.Info.Capabilities[1] != "json" {
+		t.Errorf("unexpected capabilities: %+v", data.Info)
+	}
+	// non-numeric metrics are skipped rather than failing the reading
+	if len(data.Info.Metrics) != 1 || data.Info.Metrics["rssi"] != -61 {
+		t.Errorf("unexpected metrics: %+v", data.Info)
+	}
+}
+
+func TestParseIndoorSensorData_Errors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"123,25.5", "invalid data format"},
+		{"abc,25.55,60.01,1", "invalid device ID format"},
+		{"123,warm,60.01,1", "invalid temperature format"},
+		{"123,25.55,damp,1", "invalid humidity format"},
+		{"123,25.55,60.01,X", "invalid LED state value"},
+		{"123,25.55,101.00,1", "humidity out of range"},
+		{"123,-99.00,60.01,1", "temperature out of range"},
+		{`{"device_id":123,"humidity":50}`, "invalid temperature format"},
+		{`{"device_id":123,"temperature":20}`, "invalid humidity format"},
+		{`{"device_id":-1,"temperature":20,"humidity":50}`, "invalid device ID format"},
+		{`{"device_id":123`, "invalid data format"},
+	}
+	for _, test := range tests {
+		_, err := ParseIndoorSensorData(test.input)
+		if err == nil || err.Error() != test.expectedError {
+			t.Errorf("expected error %v for %q, got %v", test.expectedError, test.input, err)
+		}
+	}
+}
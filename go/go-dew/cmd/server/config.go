@@ -2,76 +2,90 @@ package main
 
 import (
 	"fmt"
-	"log"
-	"os"
 
-	"github.com/joho/godotenv"
-	"github.com/mugglemath/go-dew/internal/weather"
+	"github.com/mugglemath/go-dew/internal/config"
+	"github.com/mugglemath/go-dew/internal/discord"
+	"github.com/mugglemath/go-dew/internal/handler"
+	"github.com/mugglemath/go-dew/internal/mold"
 )
 
-type Config struct {
-	Latitude     string
-	Longitude    string
-	Office       string
-	GridX        string
-	GridY        string
-	NWSUserAgent string
+// handlerConfig holds the households' API keys and alert rules and the device
+// locations, the part of the configuration that SIGHUP reloads along with the Discord
+// webhooks
+func handlerConfig(cfg *config.Config) (*handler.Config, error) {
+	defaultTenant, err := tenantConfig(config.DefaultTenant, cfg.Server.APIKeys, nil, nil, &cfg.Alerts, &cfg.Units)
+	if err != nil {
+		return nil, err
+	}
+	tenants := []handler.TenantConfig{*defaultTenant}
+	for _, t := range cfg.Tenants {
+		tenant, err := tenantConfig(t.Name, t.APIKeys, t.Devices, t.Locations, t.Alerts, t.Units)
+		if err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", t.Name, err)
+		}
+		tenants = append(tenants, *tenant)
+	}
 
-	PostgresUser     string
-	PostgresPassword string
-	PostgresDatabase string
+	return &handler.Config{
+		Tenants: tenants,
 
-	DiscordSensorFeedWebhookURL    string
-	DiscordWindowAlertWebhookURL   string
-	DiscordHumidityAlertWebhookURL string
-	DiscordDebugWebhookURL         string
+		OfflineAfter: cfg.Alerts.DeviceOfflineAfter,
 
-	GinMode string
-}
+		DeviceLocations: cfg.Weather.DeviceLocations(),
 
-var dsn string
+		Version: buildVersion(),
+	}, nil
+}
 
-func NewConfig() (*Config, error) {
-	err := godotenv.Load()
+func tenantConfig(name string, apiKeys []config.Secret, devices []uint64, locations []string,
+	alerts *config.Alerts, units *config.Units) (*handler.TenantConfig, error) {
+	moldModel, err := mold.ParseModel(alerts.MoldModel)
 	if err != nil {
-		log.Printf("failed to load .env file: %s", err)
+		return nil, fmt.Errorf("invalid MOLD_MODEL: %w", err)
 	}
-	var config Config
+	defaultUnits, sensorFeedUnits, windowAlertUnits := units.UnitSystems()
 
-	config.Latitude = os.Getenv("LATITUDE")
-	config.Longitude = os.Getenv("LONGITUDE")
-	config.Office = os.Getenv("OFFICE")
-	config.GridX = os.Getenv("GRID_X")
-	config.GridY = os.Getenv("GRID_Y")
-	config.NWSUserAgent = os.Getenv("NWS_USER_AGENT")
-	config.PostgresUser = os.Getenv("POSTGRES_USER")
-	config.PostgresPassword = os.Getenv("POSTGRES_PASSWORD")
-	config.PostgresDatabase = os.Getenv("POSTGRES_DB")
-	config.DiscordSensorFeedWebhookURL = os.Getenv("DISCORD_SENSOR_FEED_WEBHOOK_URL")
-	config.DiscordWindowAlertWebhookURL = os.Getenv("DISCORD_WINDOW_ALERT_WEBHOOK_URL")
-	config.DiscordHumidityAlertWebhookURL = os.Getenv("DISCORD_HUMIDITY_ALERT_WEBHOOK_URL")
-	config.DiscordDebugWebhookURL = os.Getenv("DISCORD_DEBUG_WEBHOOK_URL")
-	config.GinMode = os.Getenv("GIN_MODE")
+	keys := make([]string, len(apiKeys))
+	for i, key := range apiKeys {
+		keys[i] = key.Value
+	}
+	return &handler.TenantConfig{
+		ID:        name,
+		APIKeys:   keys,
+		Devices:   devices,
+		Locations: locations,
 
-	dsn = fmt.Sprintf("host=postgres user=%s password=%s dbname=%s port=5432 sslmode=disable",
-		config.PostgresUser, config.PostgresPassword, config.PostgresDatabase)
+		MoldModel:      moldModel,
+		MoldAlertIndex: alerts.MoldAlertIndex,
 
-	hasLatLong := config.Latitude != "" && config.Longitude != ""
-	hasOfficeGrid := config.Office != "" && config.GridX != "" && config.GridY != ""
+		WindowUValues:       alerts.WindowUValues,
+		DefaultWindowUValue: alerts.WindowUValue,
+		// temperature thresholds are given in the default units
+		CondensationMargin: defaultUnits.FromTemperatureDelta(alerts.CondensationMargin),
 
-	if !hasLatLong && !hasOfficeGrid {
-		return nil, fmt.Errorf("must provide either {LATITUDE, LONGITUDE} or {OFFICE, GRID_X, GRID_Y}")
-	}
+		Units: handler.ChannelUnits{
+			SensorFeed:  sensorFeedUnits,
+			WindowAlert: windowAlertUnits,
+			API:         defaultUnits,
+		},
+	}, nil
+}
 
-	if !hasOfficeGrid {
-		office, gridX, gridY, err := weather.GetGridData(config.Latitude, config.Longitude, config.NWSUserAgent)
-		if err != nil {
-			log.Fatal("Error retrieving grid data:", err)
-		}
-		config.Office = office
-		config.GridX = fmt.Sprintf("%d", gridX)
-		config.GridY = fmt.Sprintf("%d", gridY)
+// discordConfigs maps each household to its webhooks
+func discordConfigs(cfg *config.Config) map[string]*discord.Config {
+	configs := map[string]*discord.Config{config.DefaultTenant: discordConfig(cfg.Discord)}
+	for _, tenant := range cfg.Tenants {
+		configs[tenant.Name] = discordConfig(tenant.Discord)
 	}
+	return configs
+}
 
-	return &config, nil
+func discordConfig(d config.Discord) *discord.Config {
+	return &discord.Config{
+		SensorFeedWebhook:    d.SensorFeedWebhook.Value,
+		WindowAlertWebhook:   d.WindowAlertWebhook.Value,
+		HumidityAlertWebhook: d.HumidityAlertWebhook.Value,
+		DeviceAlertWebhook:   d.DeviceAlertWebhook.Value,
+		DebugWebhook:         d.DebugWebhook.Value,
+	}
 }
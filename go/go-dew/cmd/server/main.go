@@ -2,90 +2,300 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"runtime/debug"
 	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/joho/godotenv"
+	"github.com/mugglemath/dewdrop-go/pkg/logging"
+	"github.com/mugglemath/dewdrop-go/pkg/tracing"
+	"github.com/mugglemath/go-dew/internal/config"
+	"github.com/mugglemath/go-dew/internal/dashboard"
 	"github.com/mugglemath/go-dew/internal/db"
 	"github.com/mugglemath/go-dew/internal/discord"
 	"github.com/mugglemath/go-dew/internal/handler"
+	"github.com/mugglemath/go-dew/internal/metrics"
 	"github.com/mugglemath/go-dew/internal/weather"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
-func init() {
-	log.SetFlags(log.Lshortfile | log.LstdFlags)
-}
-
 func main() {
-	// set env variables
-	config, err := NewConfig()
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "YAML config file, environment variables override it (env CONFIG_FILE)")
+	flag.Parse()
+
+	// a .env file only fills in variables that aren't set
+	if err := godotenv.Load(); err != nil {
+		slog.Warn("failed to load .env file", "error", err)
+	}
+	cfg, err := config.Load(*configFile, os.LookupEnv)
 	if err != nil {
-		log.Fatal(err)
+		logProblems("invalid configuration", err)
+		os.Exit(1)
+	}
+	// exit only once run's deferred shutdowns have flushed the traces
+	if err := run(cfg, *configFile); err != nil {
+		slog.Error("go-dew stopped", "error", err)
+		os.Exit(1)
+	}
+}
+
+// run starts go-dew and serves until a shutdown signal or a server error, the errors
+// that keep it from starting are returned
+func run(cfg *config.Config, configFile string) error {
+	if err := logging.Setup(cfg.Log.Level, cfg.Log.Format); err != nil {
+		return fmt.Errorf("invalid logging configuration: %w", err)
 	}
 
 	// set gin mode
-	setGinMode(config.GinMode)
+	if err := setGinMode(cfg.Server.GinMode); err != nil {
+		return err
+	}
 
 	// listen for SIGTERM (and SIGINT) signals
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(sigs)
+
+	shutdownTracing, err := tracing.Setup(ctx, "go-dew", cfg.Log.TraceExporter)
+	if err != nil {
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Error("failed to flush traces", "error", err)
+		}
+	}()
 
 	// initialize clients
-	_, dbClient, err := db.ConnectToPostgres(dsn, nil)
+	_, dbClient, err := db.ConnectToPostgres(cfg.Postgres.DSN(), nil)
 	if err != nil {
-		log.Fatalf("failed to connect to db: %s", err)
+		return fmt.Errorf("failed to connect to db: %w", err)
+	}
+	if err := dbClient.Migrate(ctx); err != nil {
+		return fmt.Errorf("failed to migrate db: %w", err)
 	}
 
-	weatherClient, err := weather.NewClient(config.Office, config.GridX, config.GridY, config.NWSUserAgent)
+	locations, err := newLocations(cfg.Weather)
 	if err != nil {
-		log.Fatalf("failed to initialize weather client: %s", err)
+		return fmt.Errorf("failed to initialize weather client: %w", err)
 	}
 
-	discordClient := discord.New(&discord.Config{
-		SensorFeedWebhook:    config.DiscordSensorFeedWebhookURL,
-		WindowAlertWebhook:   config.DiscordWindowAlertWebhookURL,
-		HumidityAlertWebhook: config.DiscordHumidityAlertWebhookURL,
-		DebugWebhook:         config.DiscordDebugWebhookURL,
-	})
+	tenants := newTenants(cfg)
 
-	handler := handler.New(dbClient, discordClient, weatherClient)
+	handlerCfg, err := handlerConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	handler := handler.New(dbClient, tenants, locations, handlerCfg)
 	err = handler.Initialize(ctx)
 	if err != nil {
-		log.Fatalf("failed to initialize app: %s", err)
+		return fmt.Errorf("failed to initialize app: %w", err)
 	}
 
+	monitorDone := make(chan struct{})
+	go func() {
+		defer close(monitorDone)
+		handler.MonitorDevices(ctx)
+	}()
+
+	go reloadOnHangup(ctx, configFile, handler, tenants)
+
 	// start server
-	r := gin.Default()
-	setPanicRecoveryMiddleware(r, discordClient.PanicHandler)
-	r.GET("/weather/outdoor-dewpoint", handler.HandleOutdoorDewpoint)
-	r.POST("/arduino/sensor-feed", handler.HandleSensorData)
+	r := gin.New()
+	// handlers pass the gin context on, so it must expose the request's context values
+	r.ContextWithFallback = true
+	r.Use(otelgin.Middleware("go-dew", otelgin.WithFilter(func(req *http.Request) bool {
+		// probes and scrapes would drown out the request traces
+		return req.URL.Path != "/healthz" && req.URL.Path != "/readyz" && req.URL.Path != "/metrics"
+	})))
+	setRequestLogMiddleware(r)
+	// the stack goes to the debug channel of the household that made the request
+	setPanicRecoveryMiddleware(r, func(c *gin.Context, debugStack string) {
+		handler.Notifier(c).PanicHandler(debugStack, c.Request)
+	})
+	// the data of a household needs its API key, the probes and metrics are left to the operator
+	api := r.Group("/", handler.Authenticate)
+	api.GET("/weather/outdoor-dewpoint", handler.HandleOutdoorDewpoint)
+	api.GET("/weather/outdoor", handler.HandleOutdoorConditions)
+	api.POST("/arduino/sensor-feed", handler.HandleSensorData)
+	api.GET("/mold", handler.HandleMoldIndex)
+	api.GET("/mold/:device_id", handler.HandleMoldIndex)
+	api.GET("/devices", handler.HandleDevices)
+	api.GET("/devices/:device_id", handler.HandleDevices)
+	api.GET("/readings", handler.HandleReadings)
+	api.GET("/readings/:device_id", handler.HandleReadings)
+	// the dashboard's files are public, it asks for the API key and sends it with its requests
+	r.GET("/", func(c *gin.Context) { c.Redirect(http.StatusFound, "/dashboard/") })
+	r.GET("/dashboard/*filepath", gin.WrapH(http.StripPrefix("/dashboard", dashboard.Handler())))
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+	r.GET("/healthz", handler.HandleHealthz)
+	r.GET("/readyz", handler.HandleReadyz)
 
+	server := &http.Server{
+		Addr:              cfg.Server.ListenAddr,
+		Handler:           r,
+		ReadHeaderTimeout: cfg.Server.ReadTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       2 * cfg.Server.WriteTimeout,
+	}
+	serverErr := make(chan error, 1)
 	go func() {
-		if err := r.Run(":5000"); err != nil {
-			log.Fatalf("failed to run server: %v", err)
+		slog.Info("listening", "addr", cfg.Server.ListenAddr, "tls", cfg.Server.TLSCertFile != "")
+		if cfg.Server.TLSCertFile != "" {
+			serverErr <- server.ListenAndServeTLS(cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile)
+		} else {
+			serverErr <- server.ListenAndServe()
 		}
 	}()
 
-	<-sigs
-	log.Println("Received shutdown signal. Exiting...")
-	cancel()
+	select {
+	case sig := <-sigs:
+		slog.Info("received shutdown signal, draining", "signal", sig.String(), "timeout", cfg.Server.ShutdownTimeout)
+	case err = <-serverErr:
+		err = fmt.Errorf("failed to run server: %w", err)
+	}
+	shutdown(server, handler, dbClient, cancel, monitorDone, cfg.Server.ShutdownTimeout)
+	return err
+}
+
+// newLocations creates a weather client for every location. The grid of coordinates is
+// looked up when the weather is first fetched, so an NWS outage doesn't keep go-dew
+// from starting.
+func newLocations(w config.Weather) ([]handler.Location, error) {
+	grids := weather.NewGridCache(w.GridCacheFile, w.GridMaxAge, w.UserAgent)
+	var locations []handler.Location
+	for _, l := range w.AllLocations() {
+		client := weather.NewClientForCoordinates(grids, l.Latitude, l.Longitude, w.UserAgent)
+		if l.HasGrid() {
+			var err error
+			if client, err = weather.NewClient(l.Office, l.GridX, l.GridY, w.UserAgent); err != nil {
+				return nil, fmt.Errorf("location %s: %w", l.Name, err)
+			}
+		}
+		locations = append(locations, handler.Location{Name: l.Name, Weather: client})
+	}
+	return locations, nil
+}
+
+// newTenants creates the Discord client of every household, the default one first
+func newTenants(cfg *config.Config) []handler.Tenant {
+	tenants := []handler.Tenant{{ID: config.DefaultTenant, Discord: discord.New(discordConfig(cfg.Discord))}}
+	for _, tenant := range cfg.Tenants {
+		tenants = append(tenants, handler.Tenant{ID: tenant.Name, Discord: discord.New(discordConfig(tenant.Discord))})
+	}
+	return tenants
+}
+
+// reloadOnHangup rereads the configuration on SIGHUP and applies the households' API
+// keys, devices and alert rules, the devices of each location and the Discord webhooks.
+// Other settings, like adding a location or a household, take effect on the next
+// restart, and an invalid configuration is reported and ignored.
+func reloadOnHangup(ctx context.Context, configFile string, h handler.Handler, tenants []handler.Tenant) {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangups:
+		}
+		cfg, err := config.Load(configFile, os.LookupEnv)
+		if err != nil {
+			logProblems("invalid configuration, keeping the current one", err)
+			continue
+		}
+		handlerCfg, err := handlerConfig(cfg)
+		if err != nil {
+			slog.Error("invalid configuration, keeping the current one", "error", err)
+			continue
+		}
+		h.SetConfig(handlerCfg)
+		discordConfigs := discordConfigs(cfg)
+		for _, tenant := range tenants {
+			if webhooks, ok := discordConfigs[tenant.ID]; ok {
+				tenant.Discord.SetConfig(webhooks)
+			}
+		}
+		slog.Info("reloaded households, alert rules, device locations and Discord webhooks", "config_file", configFile)
+	}
+}
+
+// logProblems logs each problem of a configuration error on its own line
+func logProblems(message string, err error) {
+	var joined interface{ Unwrap() []error }
+	if !errors.As(err, &joined) {
+		slog.Error(message, "error", err)
+		return
+	}
+	for _, problem := range joined.Unwrap() {
+		slog.Error(message, "error", problem)
+	}
+}
+
+// shutdown stops accepting requests and waits for those in flight, then for the
+// notifications they queued, before closing the database. Everything shares one timeout.
+func shutdown(server *http.Server, h handler.Handler, dbClient db.Client, stopBackground context.CancelFunc,
+	backgroundDone <-chan struct{}, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Error("failed to drain requests", "error", err)
+	}
+	stopBackground()
+	select {
+	case <-backgroundDone:
+	case <-ctx.Done():
+	}
+	if err := h.Shutdown(ctx); err != nil {
+		slog.Error("failed to flush notifications", "error", err)
+	}
+	if err := dbClient.Close(); err != nil {
+		slog.Error("failed to close db", "error", err)
+	}
+	slog.Info("shutdown complete")
+}
+
+// version is set at build time with -ldflags "-X main.version=..."
+var version string
+
+// buildVersion falls back to the VCS revision embedded by go build
+func buildVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				return setting.Value
+			}
+		}
+	}
+	return "dev"
 }
 
-type RecoveryFn func(debugStack string, req *http.Request)
+type RecoveryFn func(c *gin.Context, debugStack string)
 
 func setPanicRecoveryMiddleware(r *gin.Engine, fn RecoveryFn) {
 	r.Use(func(c *gin.Context) {
 		defer func() {
 			if err := recover(); err != nil {
-				log.Printf("Panic recovered: %v", err)
+				logging.FromContext(c.Request.Context()).Error("panic recovered", "panic", err)
 				stack := debug.Stack()
-				fn(string(stack), c.Request)
+				fn(c, string(stack))
 				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 					"error": "Internal Server Error",
 				})
@@ -95,7 +305,7 @@ func setPanicRecoveryMiddleware(r *gin.Engine, fn RecoveryFn) {
 	})
 }
 
-func setGinMode(ginMode string) {
+func setGinMode(ginMode string) error {
 	switch ginMode {
 	case "", "release":
 		gin.SetMode(gin.ReleaseMode)
@@ -104,6 +314,7 @@ func setGinMode(ginMode string) {
 	case "test":
 		gin.SetMode(gin.TestMode)
 	default:
-		log.Fatalf("Invalid GIN_MODE value: %s. Use 'debug', 'test', or 'release'.", ginMode)
+		return fmt.Errorf("invalid GIN_MODE: unknown mode %q, use debug, test or release", ginMode)
 	}
+	return nil
 }
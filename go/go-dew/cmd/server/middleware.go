@@ -0,0 +1,44 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mugglemath/dewdrop-go/pkg/logging"
+)
+
+// setRequestLogMiddleware tags each request with an ID, taken from the X-Request-ID
+// header when dewdrop sent one, and logs the request once it's done
+func setRequestLogMiddleware(r *gin.Engine) {
+	r.Use(func(c *gin.Context) {
+		start := time.Now()
+		id := c.GetHeader(logging.RequestIDHeader)
+		if !logging.ValidRequestID(id) {
+			id = logging.NewRequestID()
+		}
+		c.Header(logging.RequestIDHeader, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+
+		c.Next()
+
+		path := c.FullPath()
+		if path == "" {
+			path = c.Request.URL.Path
+		}
+		level := slog.LevelInfo
+		switch {
+		case c.Writer.Status() >= 500:
+			level = slog.LevelError
+		case path == "/healthz" || path == "/metrics":
+			// probed every few seconds
+			level = slog.LevelDebug
+		}
+		logging.FromContext(c.Request.Context()).Log(c.Request.Context(), level, "request",
+			"method", c.Request.Method,
+			"path", path,
+			"status", c.Writer.Status(),
+			"duration_ms", float64(time.Since(start).Microseconds())/1000,
+			"client_ip", c.ClientIP())
+	})
+}
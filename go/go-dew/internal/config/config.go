@@ -0,0 +1,593 @@
+// Package config loads go-dew's settings from an optional YAML file, with
+// environment variables taking precedence over the file
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mugglemath/dewdrop-go/pkg/logging"
+	"github.com/mugglemath/dewdrop-go/pkg/units"
+	"github.com/mugglemath/go-dew/internal/mold"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Weather  Weather  `yaml:"weather"`
+	Postgres Postgres `yaml:"postgres"`
+	Discord  Discord  `yaml:"discord"`
+	Alerts   Alerts   `yaml:"alerts"`
+	Units    Units    `yaml:"units"`
+	Server   Server   `yaml:"server"`
+	Log      Log      `yaml:"log"`
+	// Tenants are the households sharing the deployment besides the default one,
+	// which the settings above belong to
+	Tenants []Tenant `yaml:"tenants"`
+}
+
+// DefaultTenant is the household of the top-level settings, rows stored before
+// households existed belong to it
+const DefaultTenant = "default"
+
+// Tenant is a household with its own API keys, devices, locations, alert rules and
+// Discord channels
+type Tenant struct {
+	Name    string   `yaml:"name"`
+	APIKeys []Secret `yaml:"api_keys"`
+	Devices []uint64 `yaml:"devices"`
+	// Locations names the household's locations from weather, the first is the
+	// default for its devices. Without any it uses the default location.
+	Locations []string `yaml:"locations"`
+	Discord   Discord  `yaml:"discord"`
+	// Alerts and Units default to the top-level sections
+	Alerts *Alerts `yaml:"alerts"`
+	Units  *Units  `yaml:"units"`
+}
+
+// Weather holds the locations whose NWS forecast is fetched. The location given at the
+// top level is the default one, for devices not assigned to any of Locations.
+type Weather struct {
+	Location  `yaml:",inline"`
+	Locations []Location `yaml:"locations"`
+	UserAgent string     `yaml:"user_agent"`
+	// the grid looked up for the coordinates is kept in GridCacheFile and looked up
+	// again after GridMaxAge
+	GridCacheFile string        `yaml:"grid_cache_file"`
+	GridMaxAge    time.Duration `yaml:"grid_max_age"`
+}
+
+// Location is a named place with the devices in it, its forecast is located either by
+// coordinates or by office and grid
+type Location struct {
+	Name      string   `yaml:"name"`
+	Latitude  string   `yaml:"latitude"`
+	Longitude string   `yaml:"longitude"`
+	Office    string   `yaml:"office"`
+	GridX     string   `yaml:"grid_x"`
+	GridY     string   `yaml:"grid_y"`
+	Devices   []uint64 `yaml:"devices"`
+}
+
+// HasGrid is true when the office and grid are configured, instead of looked up
+func (l Location) HasGrid() bool {
+	return l.Office != "" && l.GridX != "" && l.GridY != ""
+}
+
+// located is true when the forecast can be found by coordinates or grid
+func (l Location) located() bool {
+	return (l.Latitude != "" && l.Longitude != "") || l.HasGrid()
+}
+
+// isSet is true when any part of the location's forecast is configured
+func (l Location) isSet() bool {
+	return l.Latitude != "" || l.Longitude != "" || l.Office != "" || l.GridX != "" || l.GridY != ""
+}
+
+// AllLocations returns the configured locations, the first is the default
+func (w Weather) AllLocations() []Location {
+	var locations []Location
+	if w.Location.isSet() {
+		locations = append(locations, w.Location)
+	}
+	return append(locations, w.Locations...)
+}
+
+// DeviceLocations maps the device IDs to the name of the location they were assigned to
+func (w Weather) DeviceLocations() map[uint64]string {
+	devices := map[uint64]string{}
+	for _, location := range w.AllLocations() {
+		for _, deviceID := range location.Devices {
+			devices[deviceID] = location.Name
+		}
+	}
+	return devices
+}
+
+type Postgres struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	User     string `yaml:"user"`
+	Password Secret `yaml:"password"`
+	Database string `yaml:"database"`
+	SSLMode  string `yaml:"sslmode"`
+}
+
+type Discord struct {
+	SensorFeedWebhook    Secret `yaml:"sensor_feed_webhook_url"`
+	WindowAlertWebhook   Secret `yaml:"window_alert_webhook_url"`
+	HumidityAlertWebhook Secret `yaml:"humidity_alert_webhook_url"`
+	// DeviceAlertWebhook defaults to DebugWebhook
+	DeviceAlertWebhook Secret `yaml:"device_alert_webhook_url"`
+	DebugWebhook       Secret `yaml:"debug_webhook_url"`
+}
+
+type Alerts struct {
+	MoldModel      string  `yaml:"mold_model"`
+	MoldAlertIndex float64 `yaml:"mold_alert_index"`
+	// WindowUValues maps device IDs to the U-value of the windows in their room,
+	// WindowUValue applies to the other devices
+	WindowUValues map[uint64]float64 `yaml:"window_u_values"`
+	WindowUValue  float64            `yaml:"window_u_value"`
+	// CondensationMargin is given in the default units
+	CondensationMargin float64       `yaml:"condensation_margin"`
+	DeviceOfflineAfter time.Duration `yaml:"device_offline_after"`
+}
+
+// Units is the default unit system and the per channel overrides
+type Units struct {
+	Default     string `yaml:"default"`
+	SensorFeed  string `yaml:"sensor_feed"`
+	WindowAlert string `yaml:"window_alert"`
+}
+
+type Server struct {
+	// APIKeys authenticate the requests of the default household, without any
+	// it is served without authentication
+	APIKeys         []Secret      `yaml:"api_keys"`
+	ListenAddr      string        `yaml:"listen_addr"`
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// TLS is served when both files are set
+	TLSCertFile string `yaml:"tls_cert_file"`
+	TLSKeyFile  string `yaml:"tls_key_file"`
+	GinMode     string `yaml:"gin_mode"`
+}
+
+type Log struct {
+	Level         string `yaml:"level"`
+	Format        string `yaml:"format"`
+	TraceExporter string `yaml:"trace_exporter"`
+}
+
+// Secret is a value that can be kept in a file instead, like a Docker secret.
+// In YAML it is either a plain string or {file: /run/secrets/...}.
+type Secret struct {
+	Value string
+	File  string
+}
+
+func (s *Secret) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*s = Secret{Value: node.Value}
+		return nil
+	}
+	var file struct {
+		File string `yaml:"file"`
+	}
+	if err := node.Decode(&file); err != nil {
+		return err
+	}
+	*s = Secret{File: file.File}
+	return nil
+}
+
+// resolve reads the secret from its file, if it has one
+func (s *Secret) resolve() error {
+	if s.File == "" {
+		return nil
+	}
+	content, err := os.ReadFile(s.File)
+	if err != nil {
+		return err
+	}
+	s.Value = strings.TrimSpace(string(content))
+	return nil
+}
+
+const (
+	defaultLocationName    = "default"
+	defaultGridCacheFile   = "nws-grid.json"
+	defaultGridMaxAge      = 30 * 24 * time.Hour
+	defaultPostgresHost    = "postgres"
+	defaultPostgresPort    = 5432
+	defaultListenAddr      = ":5000"
+	defaultReadTimeout     = 10 * time.Second
+	defaultWriteTimeout    = 30 * time.Second
+	defaultShutdownTimeout = 30 * time.Second
+)
+
+// Load reads the file at path, if not empty, applies the environment from lookupEnv
+// and validates the result. The error lists every problem found.
+func Load(path string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	config := &Config{
+		Weather: Weather{
+			Location:      Location{Name: defaultLocationName},
+			GridCacheFile: defaultGridCacheFile,
+			GridMaxAge:    defaultGridMaxAge,
+		},
+		Postgres: Postgres{Host: defaultPostgresHost, Port: defaultPostgresPort, SSLMode: "disable"},
+		Server: Server{
+			ListenAddr:      defaultListenAddr,
+			ReadTimeout:     defaultReadTimeout,
+			WriteTimeout:    defaultWriteTimeout,
+			ShutdownTimeout: defaultShutdownTimeout,
+		},
+	}
+	if path != "" {
+		if err := config.readFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	env := environment{lookup: lookupEnv}
+	env.apply(config)
+	errs := env.errs
+
+	for _, secret := range config.secrets() {
+		if err := secret.value.resolve(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: failed to read secret: %w", secret.field, err))
+		}
+	}
+	config.Discord.defaultDeviceAlerts()
+	for i := range config.Tenants {
+		tenant := &config.Tenants[i]
+		tenant.Discord.defaultDeviceAlerts()
+		if tenant.Alerts == nil {
+			alerts := config.Alerts
+			tenant.Alerts = &alerts
+		}
+		if tenant.Units == nil {
+			units := config.Units
+			tenant.Units = &units
+		}
+	}
+
+	errs = append(errs, config.validate()...)
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// readFile decodes the YAML file strictly, so a misspelled key is an error
+func (c *Config) readFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return nil
+}
+
+type namedSecret struct {
+	field string
+	value *Secret
+}
+
+func (c *Config) secrets() []namedSecret {
+	secrets := []namedSecret{{"postgres.password (POSTGRES_PASSWORD)", &c.Postgres.Password}}
+	secrets = append(secrets, c.Discord.secrets(envField("discord"))...)
+	for i := range c.Server.APIKeys {
+		secrets = append(secrets, namedSecret{fmt.Sprintf("server.api_keys[%d] (API_KEY)", i), &c.Server.APIKeys[i]})
+	}
+	for i := range c.Tenants {
+		tenant := &c.Tenants[i]
+		prefix := fmt.Sprintf("tenants[%d]", i)
+		secrets = append(secrets, tenant.Discord.secrets(fileField(prefix+".discord"))...)
+		for j := range tenant.APIKeys {
+			secrets = append(secrets, namedSecret{fmt.Sprintf("%s.api_keys[%d]", prefix, j), &tenant.APIKeys[j]})
+		}
+	}
+	return secrets
+}
+
+// envField names a key of section along with its environment variable
+func envField(section string) func(key, env string) string {
+	return func(key, env string) string {
+		return fmt.Sprintf("%s.%s (%s)", section, key, env)
+	}
+}
+
+// fileField names a key of section that can only be set in the file
+func fileField(section string) func(key, env string) string {
+	return func(key, _ string) string {
+		return section + "." + key
+	}
+}
+
+// defaultDeviceAlerts sends device alerts to the debug channel unless they have their own
+func (d *Discord) defaultDeviceAlerts() {
+	if d.DeviceAlertWebhook.Value == "" && d.DeviceAlertWebhook.File == "" {
+		d.DeviceAlertWebhook = d.DebugWebhook
+	}
+}
+
+func (d *Discord) secrets(field func(key, env string) string) []namedSecret {
+	return []namedSecret{
+		{field("sensor_feed_webhook_url", "DISCORD_SENSOR_FEED_WEBHOOK_URL"), &d.SensorFeedWebhook},
+		{field("window_alert_webhook_url", "DISCORD_WINDOW_ALERT_WEBHOOK_URL"), &d.WindowAlertWebhook},
+		{field("humidity_alert_webhook_url", "DISCORD_HUMIDITY_ALERT_WEBHOOK_URL"), &d.HumidityAlertWebhook},
+		{field("device_alert_webhook_url", "DISCORD_DEVICE_ALERT_WEBHOOK_URL"), &d.DeviceAlertWebhook},
+		{field("debug_webhook_url", "DISCORD_DEBUG_WEBHOOK_URL"), &d.DebugWebhook},
+	}
+}
+
+// Validate reports every invalid or missing setting at once
+func (c *Config) Validate() error {
+	return errors.Join(c.validate()...)
+}
+
+func (c *Config) validate() []error {
+	var errs []error
+	var check checkFunc = func(ok bool, field, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+		}
+	}
+
+	w := c.Weather
+	// the default location may be left out when others are listed
+	if w.Location.isSet() || len(w.Locations) == 0 {
+		check(w.Location.located(), "weather",
+			"must provide either {LATITUDE, LONGITUDE} or {OFFICE, GRID_X, GRID_Y}")
+		w.Location.validate(check, envField("weather"))
+	}
+	for i, location := range w.Locations {
+		prefix := fmt.Sprintf("weather.locations[%d]", i)
+		check(location.located(), prefix, "must provide either {latitude, longitude} or {office, grid_x, grid_y}")
+		location.validate(check, fileField(prefix))
+	}
+	names := map[string]bool{}
+	devices := map[uint64]string{}
+	for _, location := range w.AllLocations() {
+		check(!names[location.Name], "weather.locations", "location %q is configured twice", location.Name)
+		names[location.Name] = true
+		for _, deviceID := range location.Devices {
+			other, assigned := devices[deviceID]
+			check(!assigned, "weather.locations", "device %d is assigned to both %q and %q",
+				deviceID, other, location.Name)
+			devices[deviceID] = location.Name
+		}
+	}
+	check(w.GridMaxAge > 0, "weather.grid_max_age (NWS_GRID_MAX_AGE)", "must be positive")
+
+	check(c.Postgres.User != "", "postgres.user (POSTGRES_USER)", "required")
+	check(c.Postgres.Database != "", "postgres.database (POSTGRES_DB)", "required")
+	check(c.Postgres.Port > 0 && c.Postgres.Port < 65536, "postgres.port (POSTGRES_PORT)",
+		"%d is not a valid port", c.Postgres.Port)
+
+	c.Discord.validate(check, envField("discord"))
+	c.Alerts.validate(check, envField("alerts"))
+	c.Units.validate(check, envField("units"))
+
+	s := c.Server
+	check(s.ListenAddr != "", "server.listen_addr (LISTEN_ADDR)", "required")
+	check(s.ReadTimeout > 0, "server.read_timeout (HTTP_READ_TIMEOUT)", "must be positive")
+	check(s.WriteTimeout > 0, "server.write_timeout (HTTP_WRITE_TIMEOUT)", "must be positive")
+	check(s.ShutdownTimeout > 0, "server.shutdown_timeout (SHUTDOWN_TIMEOUT)", "must be positive")
+	check((s.TLSCertFile == "") == (s.TLSKeyFile == ""), "server.tls_cert_file (TLS_CERT_FILE)",
+		"TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	switch s.GinMode {
+	case "", "release", "debug", "test":
+	default:
+		check(false, "server.gin_mode (GIN_MODE)", "unknown mode %q, use debug, test or release", s.GinMode)
+	}
+
+	if _, err := logging.New(io.Discard, c.Log.Level, c.Log.Format); err != nil {
+		check(false, "log (LOG_LEVEL, LOG_FORMAT)", "%v", err)
+	}
+	switch c.Log.TraceExporter {
+	case "", "none", "otlp", "console", "stdout":
+	default:
+		check(false, "log.trace_exporter (OTEL_TRACES_EXPORTER)",
+			"unknown exporter %q, use none, otlp or console", c.Log.TraceExporter)
+	}
+
+	c.validateTenants(check)
+	return errs
+}
+
+// checkFunc reports the problem described by format with field if not ok
+type checkFunc func(ok bool, field, format string, args ...any)
+
+// validateTenants checks that every API key, device and location belongs to a single household
+func (c *Config) validateTenants(check checkFunc) {
+	for i, key := range c.Server.APIKeys {
+		check(key.Value != "", fmt.Sprintf("server.api_keys[%d] (API_KEY)", i), "must not be empty")
+	}
+	check(len(c.Tenants) == 0 || len(c.Server.APIKeys) > 0, "server.api_keys (API_KEY)",
+		"required when tenants are configured")
+
+	names := map[string]bool{}
+	keys := map[string]string{}
+	for _, key := range c.Server.APIKeys {
+		keys[key.Value] = DefaultTenant
+	}
+	deviceTenants := map[uint64]string{}
+	locationTenants := map[string]string{}
+	locations := c.Weather.AllLocations()
+	for i, tenant := range c.Tenants {
+		prefix := fmt.Sprintf("tenants[%d]", i)
+		check(tenant.Name != "", prefix+".name", "required")
+		check(tenant.Name != DefaultTenant, prefix+".name", "%q is the household of the top-level settings", DefaultTenant)
+		check(!names[tenant.Name], prefix+".name", "tenant %q is configured twice", tenant.Name)
+		names[tenant.Name] = true
+
+		check(len(tenant.APIKeys) > 0, prefix+".api_keys", "required")
+		for j, key := range tenant.APIKeys {
+			field := fmt.Sprintf("%s.api_keys[%d]", prefix, j)
+			check(key.Value != "", field, "must not be empty")
+			if other, used := keys[key.Value]; used && key.Value != "" {
+				// the key is a secret, keep it out of the error
+				check(false, field, "also used by %q", other)
+			}
+			keys[key.Value] = tenant.Name
+		}
+		for _, deviceID := range tenant.Devices {
+			other, assigned := deviceTenants[deviceID]
+			check(!assigned, prefix+".devices", "device %d also belongs to %q", deviceID, other)
+			deviceTenants[deviceID] = tenant.Name
+		}
+		for _, name := range tenant.Locations {
+			known := false
+			for _, location := range locations {
+				known = known || location.Name == name
+			}
+			check(known, prefix+".locations", "unknown location %q", name)
+			check(len(locations) == 0 || name != locations[0].Name, prefix+".locations",
+				"%q is the default location, it can't belong to a household", name)
+			other, assigned := locationTenants[name]
+			check(!assigned, prefix+".locations", "location %q also belongs to %q", name, other)
+			locationTenants[name] = tenant.Name
+		}
+
+		tenant.Discord.validate(check, fileField(prefix+".discord"))
+		tenant.Alerts.validate(check, fileField(prefix+".alerts"))
+		tenant.Units.validate(check, fileField(prefix+".units"))
+	}
+
+	// a device's location must be one of its household's
+	for deviceID, location := range c.Weather.DeviceLocations() {
+		tenant, ok := deviceTenants[deviceID]
+		if !ok {
+			tenant = DefaultTenant
+		}
+		owner, ok := locationTenants[location]
+		if !ok {
+			owner = DefaultTenant
+		}
+		check(tenant == owner, "weather.locations", "device %d of %q is in location %q of %q",
+			deviceID, tenant, location, owner)
+	}
+}
+
+func (d Discord) validate(check checkFunc, field func(key, env string) string) {
+	webhooks := []struct {
+		key, env string
+		url      string
+		required bool
+	}{
+		{"sensor_feed_webhook_url", "DISCORD_SENSOR_FEED_WEBHOOK_URL", d.SensorFeedWebhook.Value, true},
+		{"window_alert_webhook_url", "DISCORD_WINDOW_ALERT_WEBHOOK_URL", d.WindowAlertWebhook.Value, true},
+		{"humidity_alert_webhook_url", "DISCORD_HUMIDITY_ALERT_WEBHOOK_URL", d.HumidityAlertWebhook.Value, true},
+		{"device_alert_webhook_url", "DISCORD_DEVICE_ALERT_WEBHOOK_URL", d.DeviceAlertWebhook.Value, false},
+		{"debug_webhook_url", "DISCORD_DEBUG_WEBHOOK_URL", d.DebugWebhook.Value, true},
+	}
+	for _, webhook := range webhooks {
+		if webhook.url == "" {
+			check(!webhook.required, field(webhook.key, webhook.env), "required")
+			continue
+		}
+		// the URL is a secret, keep it out of the error
+		check(validURL(webhook.url), field(webhook.key, webhook.env), "not an http(s) URL")
+	}
+}
+
+func (a Alerts) validate(check checkFunc, field func(key, env string) string) {
+	if _, err := mold.ParseModel(a.MoldModel); err != nil {
+		check(false, field("mold_model", "MOLD_MODEL"), "%v", err)
+	}
+	check(a.MoldAlertIndex >= 0 && a.MoldAlertIndex <= 6, field("mold_alert_index", "MOLD_ALERT_INDEX"),
+		"%g is outside the index range 0 to 6", a.MoldAlertIndex)
+	for deviceID, u := range a.WindowUValues {
+		check(u > 0, field("window_u_values", "WINDOW_U_VALUES"), "U-value %g of device %d must be positive", u, deviceID)
+	}
+	check(a.WindowUValue >= 0, field("window_u_value", "WINDOW_U_VALUE"), "must not be negative")
+	check(a.CondensationMargin >= 0, field("condensation_margin", "CONDENSATION_MARGIN"), "must not be negative")
+	check(a.DeviceOfflineAfter >= 0, field("device_offline_after", "DEVICE_OFFLINE_AFTER"), "must not be negative")
+}
+
+func (u Units) validate(check checkFunc, field func(key, env string) string) {
+	for _, system := range []struct{ key, env, value string }{
+		{"default", "UNITS", u.Default},
+		{"sensor_feed", "DISCORD_SENSOR_FEED_UNITS", u.SensorFeed},
+		{"window_alert", "DISCORD_WINDOW_ALERT_UNITS", u.WindowAlert},
+	} {
+		if _, err := units.Parse(system.value); err != nil {
+			check(false, field(system.key, system.env), "%v", err)
+		}
+	}
+}
+
+// validate checks the values that are set, field names a key with its environment variable
+func (l Location) validate(check checkFunc, field func(key, env string) string) {
+	check(l.Name != "", field("name", "LOCATION_NAME"), "required")
+	if l.Latitude != "" {
+		check(inRange(l.Latitude, 90), field("latitude", "LATITUDE"),
+			"%q is not a number between -90 and 90", l.Latitude)
+	}
+	if l.Longitude != "" {
+		check(inRange(l.Longitude, 180), field("longitude", "LONGITUDE"),
+			"%q is not a number between -180 and 180", l.Longitude)
+	}
+	if l.GridX != "" {
+		_, err := strconv.Atoi(l.GridX)
+		check(err == nil, field("grid_x", "GRID_X"), "%q is not a whole number", l.GridX)
+	}
+	if l.GridY != "" {
+		_, err := strconv.Atoi(l.GridY)
+		check(err == nil, field("grid_y", "GRID_Y"), "%q is not a whole number", l.GridY)
+	}
+}
+
+// DSN is the Postgres connection string, its values are quoted so a password may hold
+// spaces, quotes and backslashes
+func (p Postgres) DSN() string {
+	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s",
+		dsnValue(p.Host), dsnValue(p.User), dsnValue(p.Password.Value), dsnValue(p.Database), p.Port,
+		dsnValue(p.SSLMode))
+}
+
+var dsnEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// dsnValue quotes a keyword/value connection string value as libpq does
+func dsnValue(value string) string {
+	return "'" + dsnEscaper.Replace(value) + "'"
+}
+
+// UnitSystems returns the default unit system and those of the sensor feed and window
+// alert channels, which fall back to the default
+func (u Units) UnitSystems() (defaultUnits, sensorFeed, windowAlert units.System) {
+	defaultUnits, _ = units.Parse(u.Default)
+	sensorFeed, windowAlert = defaultUnits, defaultUnits
+	if u.SensorFeed != "" {
+		sensorFeed, _ = units.Parse(u.SensorFeed)
+	}
+	if u.WindowAlert != "" {
+		windowAlert, _ = units.Parse(u.WindowAlert)
+	}
+	return
+}
+
+func inRange(value string, limit float64) bool {
+	f, err := strconv.ParseFloat(value, 64)
+	return err == nil && f >= -limit && f <= limit
+}
+
+func validURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+}
@@ -0,0 +1,330 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func lookup(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+func validEnv() map[string]string {
+	return map[string]string{
+		"LATITUDE":                           "40.73",
+		"LONGITUDE":                          "-73.95",
+		"POSTGRES_USER":                      "user",
+		"POSTGRES_PASSWORD":                  "pw",
+		"POSTGRES_DB":                        "db",
+		"DISCORD_SENSOR_FEED_WEBHOOK_URL":    "https://discord.com/api/webhooks/1/feed",
+		"DISCORD_WINDOW_ALERT_WEBHOOK_URL":   "https://discord.com/api/webhooks/1/window",
+		"DISCORD_HUMIDITY_ALERT_WEBHOOK_URL": "https://discord.com/api/webhooks/1/humidity",
+		"DISCORD_DEBUG_WEBHOOK_URL":          "https://discord.com/api/webhooks/1/debug",
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestLoad_Env(t *testing.T) {
+	env := validEnv()
+	env["DEVICE_OFFLINE_AFTER"] = "30m"
+	env["WINDOW_U_VALUES"] = "1234=2.8, 5678=1.1"
+
+	config, err := Load("", lookup(env))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if config.Weather.Latitude != "40.73" || config.Alerts.DeviceOfflineAfter != 30*time.Minute {
+		t.Errorf("expected the environment to be applied, got %+v", config)
+	}
+	if config.Alerts.WindowUValues[5678] != 1.1 {
+		t.Errorf("expected U-value 1.1 for device 5678, got %v", config.Alerts.WindowUValues)
+	}
+	if config.Server.ListenAddr != ":5000" || config.Server.ShutdownTimeout != 30*time.Second {
+		t.Errorf("expected the server defaults, got %+v", config.Server)
+	}
+	if config.Discord.DeviceAlertWebhook.Value != env["DISCORD_DEBUG_WEBHOOK_URL"] {
+		t.Errorf("expected device alerts to fall back to the debug webhook, got %q",
+			config.Discord.DeviceAlertWebhook.Value)
+	}
+	expected := "host='postgres' user='user' password='pw' dbname='db' port=5432 sslmode='disable'"
+	if dsn := config.Postgres.DSN(); dsn != expected {
+		t.Errorf("expected DSN %q, got %q", expected, dsn)
+	}
+}
+
+func TestLoad_FileWithEnvOverride(t *testing.T) {
+	password := writeFile(t, "postgres-password", "from-file\n")
+	path := writeFile(t, "go-dew.yaml", `
+weather:
+  office: OKX
+  grid_x: 33
+  grid_y: 35
+postgres:
+  user: user
+  password:
+    file: `+password+`
+  database: db
+discord:
+  sensor_feed_webhook_url: https://discord.com/api/webhooks/1/feed
+  window_alert_webhook_url: https://discord.com/api/webhooks/1/window
+  humidity_alert_webhook_url: https://discord.com/api/webhooks/1/humidity
+  debug_webhook_url: https://discord.com/api/webhooks/1/debug
+alerts:
+  mold_alert_index: 3
+  window_u_values:
+    1234: 2.8
+units:
+  default: imperial
+server:
+  shutdown_timeout: 1m
+`)
+	env := map[string]string{"MOLD_ALERT_INDEX": "2"}
+
+	config, err := Load(path, lookup(env))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if config.Weather.GridX != "33" || config.Units.Default != "imperial" {
+		t.Errorf("expected the file to be applied, got %+v", config)
+	}
+	if config.Postgres.Password.Value != "from-file" {
+		t.Errorf("expected the password from its file, got %q", config.Postgres.Password.Value)
+	}
+	if config.Alerts.MoldAlertIndex != 2 {
+		t.Errorf("expected the environment to override the file, got %g", config.Alerts.MoldAlertIndex)
+	}
+	if config.Server.ShutdownTimeout != time.Minute || config.Server.ReadTimeout != 10*time.Second {
+		t.Errorf("expected durations from the file and defaults, got %+v", config.Server)
+	}
+}
+
+func TestLoad_SecretFileEnv(t *testing.T) {
+	webhook := writeFile(t, "feed-webhook", "https://discord.com/api/webhooks/2/feed")
+	env := validEnv()
+	delete(env, "DISCORD_SENSOR_FEED_WEBHOOK_URL")
+	env["DISCORD_SENSOR_FEED_WEBHOOK_URL_FILE"] = webhook
+
+	config, err := Load("", lookup(env))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if config.Discord.SensorFeedWebhook.Value != "https://discord.com/api/webhooks/2/feed" {
+		t.Errorf("expected the webhook from its file, got %q", config.Discord.SensorFeedWebhook.Value)
+	}
+
+	env["DISCORD_SENSOR_FEED_WEBHOOK_URL"] = "https://discord.com/api/webhooks/3/feed"
+	_, err = Load("", lookup(env))
+	expected := "set either DISCORD_SENSOR_FEED_WEBHOOK_URL or DISCORD_SENSOR_FEED_WEBHOOK_URL_FILE, not both"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+}
+
+func TestPostgres_DSN(t *testing.T) {
+	p := Postgres{Host: "postgres", User: "dew", Password: Secret{Value: `it's a \ secret`},
+		Database: "db", Port: 5432, SSLMode: "disable"}
+
+	expected := `host='postgres' user='dew' password='it\'s a \\ secret' dbname='db' port=5432 sslmode='disable'`
+	if dsn := p.DSN(); dsn != expected {
+		t.Errorf("expected DSN %q, got %q", expected, dsn)
+	}
+}
+
+func TestLoad_UnknownKey(t *testing.T) {
+	path := writeFile(t, "go-dew.yaml", "alerts:\n  mold_alert_idx: 3\n")
+
+	_, err := Load(path, lookup(validEnv()))
+	if err == nil || !strings.Contains(err.Error(), "field mold_alert_idx not found") {
+		t.Errorf("expected an unknown field error, got %v", err)
+	}
+}
+
+func TestLoad_ReportsEveryProblem(t *testing.T) {
+	env := validEnv()
+	delete(env, "LONGITUDE")
+	delete(env, "DISCORD_DEBUG_WEBHOOK_URL")
+	env["LATITUDE"] = "140.7"
+	env["DISCORD_WINDOW_ALERT_WEBHOOK_URL"] = "discord.com/api/webhooks/1/window"
+	env["MOLD_ALERT_INDEX"] = "7"
+	env["UNITS"] = "kelvin"
+	env["HTTP_WRITE_TIMEOUT"] = "soon"
+
+	_, err := Load("", lookup(env))
+	expected := strings.Join([]string{
+		`invalid HTTP_WRITE_TIMEOUT: time: invalid duration "soon"`,
+		"weather: must provide either {LATITUDE, LONGITUDE} or {OFFICE, GRID_X, GRID_Y}",
+		`weather.latitude (LATITUDE): "140.7" is not a number between -90 and 90`,
+		"discord.window_alert_webhook_url (DISCORD_WINDOW_ALERT_WEBHOOK_URL): not an http(s) URL",
+		"discord.debug_webhook_url (DISCORD_DEBUG_WEBHOOK_URL): required",
+		"alerts.mold_alert_index (MOLD_ALERT_INDEX): 7 is outside the index range 0 to 6",
+		`units.default (UNITS): unknown unit system "kelvin", use metric or imperial`,
+	}, "\n")
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error:\n%s\ngot:\n%v", expected, err)
+	}
+}
+
+func TestLoad_Locations(t *testing.T) {
+	path := writeFile(t, "go-dew.yaml", `
+weather:
+  name: office
+  latitude: "40.73"
+  longitude: "-73.95"
+  devices: [1]
+  locations:
+    - name: home
+      office: BOX
+      grid_x: 71
+      grid_y: 90
+      devices: [2, 3]
+`)
+	env := validEnv()
+	delete(env, "LATITUDE")
+	delete(env, "LONGITUDE")
+
+	config, err := Load(path, lookup(env))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	locations := config.Weather.AllLocations()
+	if len(locations) != 2 || locations[0].Name != "office" || locations[1].Office != "BOX" {
+		t.Errorf("expected the default location office followed by home, got %+v", locations)
+	}
+	devices := config.Weather.DeviceLocations()
+	if devices[1] != "office" || devices[3] != "home" || len(devices) != 3 {
+		t.Errorf("expected devices 1 in office and 2, 3 at home, got %v", devices)
+	}
+}
+
+func TestLoad_InvalidLocations(t *testing.T) {
+	path := writeFile(t, "go-dew.yaml", `
+weather:
+  locations:
+    - name: home
+      latitude: "42.36"
+      longitude: "-71.06"
+      devices: [1]
+    - name: home
+      latitude: "95"
+      devices: [1]
+`)
+	env := validEnv()
+	delete(env, "LATITUDE")
+	delete(env, "LONGITUDE")
+
+	_, err := Load(path, lookup(env))
+	expected := strings.Join([]string{
+		"weather.locations[1]: must provide either {latitude, longitude} or {office, grid_x, grid_y}",
+		`weather.locations[1].latitude: "95" is not a number between -90 and 90`,
+		`weather.locations: location "home" is configured twice`,
+		`weather.locations: device 1 is assigned to both "home" and "home"`,
+	}, "\n")
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error:\n%s\ngot:\n%v", expected, err)
+	}
+}
+
+func TestLoad_Tenants(t *testing.T) {
+	path := writeFile(t, "go-dew.yaml", `
+weather:
+  locations:
+    - name: home
+      office: BOX
+      grid_x: 71
+      grid_y: 90
+      devices: [2]
+server:
+  api_keys: [default-key]
+tenants:
+  - name: smiths
+    api_keys: [smiths-key]
+    devices: [2, 3]
+    locations: [home]
+    discord:
+      sensor_feed_webhook_url: https://discord.com/api/webhooks/2/feed
+      window_alert_webhook_url: https://discord.com/api/webhooks/2/window
+      humidity_alert_webhook_url: https://discord.com/api/webhooks/2/humidity
+      debug_webhook_url: https://discord.com/api/webhooks/2/debug
+    units:
+      default: imperial
+`)
+	env := validEnv()
+	env["MOLD_ALERT_INDEX"] = "2"
+
+	config, err := Load(path, lookup(env))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(config.Tenants) != 1 || config.Tenants[0].APIKeys[0].Value != "smiths-key" {
+		t.Fatalf("expected the smiths household, got %+v", config.Tenants)
+	}
+	tenant := config.Tenants[0]
+	if tenant.Alerts.MoldAlertIndex != 2 || tenant.Units.Default != "imperial" {
+		t.Errorf("expected the top-level alerts and the household's units, got %+v and %+v", tenant.Alerts, tenant.Units)
+	}
+	if tenant.Discord.DeviceAlertWebhook.Value != "https://discord.com/api/webhooks/2/debug" {
+		t.Errorf("expected device alerts to fall back to the household's debug webhook, got %q",
+			tenant.Discord.DeviceAlertWebhook.Value)
+	}
+}
+
+func TestLoad_InvalidTenants(t *testing.T) {
+	path := writeFile(t, "go-dew.yaml", `
+weather:
+  locations:
+    - name: home
+      office: BOX
+      grid_x: 71
+      grid_y: 90
+      devices: [1]
+tenants:
+  - name: smiths
+    api_keys: [shared-key]
+    devices: [2]
+    locations: [home, cabin]
+    discord:
+      sensor_feed_webhook_url: https://discord.com/api/webhooks/2/feed
+      window_alert_webhook_url: https://discord.com/api/webhooks/2/window
+      humidity_alert_webhook_url: https://discord.com/api/webhooks/2/humidity
+      debug_webhook_url: https://discord.com/api/webhooks/2/debug
+  - name: smiths
+    api_keys: [shared-key]
+    devices: [2]
+    discord:
+      sensor_feed_webhook_url: https://discord.com/api/webhooks/3/feed
+      window_alert_webhook_url: https://discord.com/api/webhooks/3/window
+      humidity_alert_webhook_url: https://discord.com/api/webhooks/3/humidity
+`)
+
+	_, err := Load(path, lookup(validEnv()))
+	expected := strings.Join([]string{
+		"server.api_keys (API_KEY): required when tenants are configured",
+		`tenants[0].locations: unknown location "cabin"`,
+		`tenants[1].name: tenant "smiths" is configured twice`,
+		`tenants[1].api_keys[0]: also used by "smiths"`,
+		`tenants[1].devices: device 2 also belongs to "smiths"`,
+		"tenants[1].discord.debug_webhook_url: required",
+		`weather.locations: device 1 of "default" is in location "home" of "smiths"`,
+	}, "\n")
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error:\n%s\ngot:\n%v", expected, err)
+	}
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// environment applies the environment variables on top of the file, collecting
+// parse errors instead of stopping at the first one
+type environment struct {
+	lookup func(string) (string, bool)
+	errs   []error
+}
+
+func (e *environment) apply(c *Config) {
+	e.string("LOCATION_NAME", &c.Weather.Name)
+	e.string("LATITUDE", &c.Weather.Latitude)
+	e.string("LONGITUDE", &c.Weather.Longitude)
+	e.string("OFFICE", &c.Weather.Office)
+	e.string("GRID_X", &c.Weather.GridX)
+	e.string("GRID_Y", &c.Weather.GridY)
+	e.string("NWS_USER_AGENT", &c.Weather.UserAgent)
+	e.string("NWS_GRID_CACHE_FILE", &c.Weather.GridCacheFile)
+	e.duration("NWS_GRID_MAX_AGE", &c.Weather.GridMaxAge)
+
+	e.string("POSTGRES_HOST", &c.Postgres.Host)
+	e.int("POSTGRES_PORT", &c.Postgres.Port)
+	e.string("POSTGRES_USER", &c.Postgres.User)
+	e.secret("POSTGRES_PASSWORD", &c.Postgres.Password)
+	e.string("POSTGRES_DB", &c.Postgres.Database)
+	e.string("POSTGRES_SSLMODE", &c.Postgres.SSLMode)
+
+	e.secret("DISCORD_SENSOR_FEED_WEBHOOK_URL", &c.Discord.SensorFeedWebhook)
+	e.secret("DISCORD_WINDOW_ALERT_WEBHOOK_URL", &c.Discord.WindowAlertWebhook)
+	e.secret("DISCORD_HUMIDITY_ALERT_WEBHOOK_URL", &c.Discord.HumidityAlertWebhook)
+	e.secret("DISCORD_DEVICE_ALERT_WEBHOOK_URL", &c.Discord.DeviceAlertWebhook)
+	e.secret("DISCORD_DEBUG_WEBHOOK_URL", &c.Discord.DebugWebhook)
+
+	e.string("MOLD_MODEL", &c.Alerts.MoldModel)
+	e.float("MOLD_ALERT_INDEX", &c.Alerts.MoldAlertIndex)
+	if value, ok := e.get("WINDOW_U_VALUES"); ok {
+		uValues, err := parseUValues(value)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("invalid WINDOW_U_VALUES: %w", err))
+		} else {
+			c.Alerts.WindowUValues = uValues
+		}
+	}
+	e.float("WINDOW_U_VALUE", &c.Alerts.WindowUValue)
+	e.float("CONDENSATION_MARGIN", &c.Alerts.CondensationMargin)
+	e.duration("DEVICE_OFFLINE_AFTER", &c.Alerts.DeviceOfflineAfter)
+
+	e.string("UNITS", &c.Units.Default)
+	e.string("DISCORD_SENSOR_FEED_UNITS", &c.Units.SensorFeed)
+	e.string("DISCORD_WINDOW_ALERT_UNITS", &c.Units.WindowAlert)
+
+	var apiKey Secret
+	e.secret("API_KEY", &apiKey)
+	if apiKey != (Secret{}) {
+		c.Server.APIKeys = []Secret{apiKey}
+	}
+	e.string("LISTEN_ADDR", &c.Server.ListenAddr)
+	e.duration("HTTP_READ_TIMEOUT", &c.Server.ReadTimeout)
+	e.duration("HTTP_WRITE_TIMEOUT", &c.Server.WriteTimeout)
+	e.duration("SHUTDOWN_TIMEOUT", &c.Server.ShutdownTimeout)
+	e.string("TLS_CERT_FILE", &c.Server.TLSCertFile)
+	e.string("TLS_KEY_FILE", &c.Server.TLSKeyFile)
+	e.string("GIN_MODE", &c.Server.GinMode)
+
+	e.string("LOG_LEVEL", &c.Log.Level)
+	e.string("LOG_FORMAT", &c.Log.Format)
+	e.string("OTEL_TRACES_EXPORTER", &c.Log.TraceExporter)
+}
+
+// get treats empty variables as unset, as compose files often leave them blank
+func (e *environment) get(key string) (string, bool) {
+	value, ok := e.lookup(key)
+	return value, ok && value != ""
+}
+
+func (e *environment) string(key string, dst *string) {
+	if value, ok := e.get(key); ok {
+		*dst = value
+	}
+}
+
+func (e *environment) int(key string, dst *int) {
+	if value, ok := e.get(key); ok {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("invalid %s: %w", key, err))
+			return
+		}
+		*dst = n
+	}
+}
+
+func (e *environment) float(key string, dst *float64) {
+	if value, ok := e.get(key); ok {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("invalid %s: %w", key, err))
+			return
+		}
+		*dst = f
+	}
+}
+
+func (e *environment) duration(key string, dst *time.Duration) {
+	if value, ok := e.get(key); ok {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("invalid %s: %w", key, err))
+			return
+		}
+		*dst = d
+	}
+}
+
+// secret reads KEY, or the file named by KEY_FILE
+func (e *environment) secret(key string, dst *Secret) {
+	value, hasValue := e.get(key)
+	file, hasFile := e.get(key + "_FILE")
+	switch {
+	case hasValue && hasFile:
+		e.errs = append(e.errs, fmt.Errorf("set either %s or %s_FILE, not both", key, key))
+		*dst = Secret{Value: value}
+	case hasValue:
+		*dst = Secret{Value: value}
+	case hasFile:
+		*dst = Secret{File: file}
+	}
+}
+
+// parseUValues reads a list of device_id=u_value pairs, e.g. 1234=2.8,5678=1.1
+func parseUValues(value string) (map[uint64]float64, error) {
+	uValues := map[uint64]float64{}
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		device, u, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected device_id=u_value, got %q", pair)
+		}
+		deviceID, err := strconv.ParseUint(strings.TrimSpace(device), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid device id %q", device)
+		}
+		uValues[deviceID], err = strconv.ParseFloat(strings.TrimSpace(u), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid U-value %q", u)
+		}
+	}
+	return uValues, nil
+}
@@ -0,0 +1,23 @@
+// Package dashboard serves go-dew's web UI, which is built into the binary
+package dashboard
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed static
+var static embed.FS
+
+// Handler serves the dashboard's page and assets from the root of the handler, mount it
+// with http.StripPrefix. The page reads the household's data from the API in the
+// browser, with the API key the user enters.
+func Handler() http.Handler {
+	files, err := fs.Sub(static, "static")
+	if err != nil {
+		// static is embedded above, it can't be missing
+		panic(err)
+	}
+	return http.FileServer(http.FS(files))
+}
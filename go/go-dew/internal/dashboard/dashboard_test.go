@@ -0,0 +1,29 @@
+package dashboard
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	tests := map[string]string{
+		"/":          "<title>arDEWino</title>",
+		"/app.js":    "async function refresh()",
+		"/style.css": ".devices {",
+	}
+	for path, expected := range tests {
+		recorder := httptest.NewRecorder()
+		Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), expected) {
+			t.Errorf("expected %s to contain %q, got %d", path, expected, recorder.Code)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/missing.js", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing file, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,317 @@
+"use strict";
+
+// The dashboard is served at /dashboard/, the API one level up
+const apiBase = new URL("../", window.location.href);
+const refreshInterval = 60 * 1000;
+const keyStorage = "go-dew-api-key";
+
+const state = {
+  apiKey: localStorage.getItem(keyStorage) || "",
+  deviceID: null,
+  range: "24h",
+};
+
+class Unauthorized extends Error {}
+
+async function api(path) {
+  const headers = {};
+  if (state.apiKey) {
+    headers["Authorization"] = "Bearer " + state.apiKey;
+  }
+  const response = await fetch(new URL(path, apiBase), { headers });
+  if (response.status === 401) {
+    throw new Unauthorized();
+  }
+  if (!response.ok) {
+    let message = response.statusText;
+    try {
+      message = (await response.json()).error || message;
+    } catch (e) {
+      // keep the status text
+    }
+    const error = new Error(path + ": " + message);
+    error.status = response.status;
+    throw error;
+  }
+  return response.json();
+}
+
+// optional resolves to null when the endpoint has nothing yet, e.g. the weather during an NWS outage
+async function optional(path) {
+  try {
+    return await api(path);
+  } catch (error) {
+    if (error instanceof Unauthorized) {
+      throw error;
+    }
+    return null;
+  }
+}
+
+function element(tag, attributes, ...children) {
+  const el = document.createElement(tag);
+  for (const [name, value] of Object.entries(attributes || {})) {
+    el.setAttribute(name, value);
+  }
+  el.append(...children.filter((child) => child !== null && child !== undefined));
+  return el;
+}
+
+function formatNumber(value, digits) {
+  return Number(value).toFixed(digits);
+}
+
+// formatOptional shows "-" for values neither dewdrop nor go-dew knew, e.g. during an NWS outage
+function formatOptional(value, digits, suffix) {
+  return value === null || value === undefined ? "-" : formatNumber(value, digits) + suffix;
+}
+
+function formatAge(time) {
+  const minutes = Math.round((Date.now() - new Date(time).getTime()) / 60000);
+  if (minutes < 1) {
+    return "just now";
+  }
+  if (minutes < 60) {
+    return minutes + " min ago";
+  }
+  if (minutes < 48 * 60) {
+    return Math.round(minutes / 60) + " h ago";
+  }
+  return Math.round(minutes / 1440) + " days ago";
+}
+
+async function refresh() {
+  try {
+    const [readings, devices, mold, outdoor] = await Promise.all([
+      api("readings"),
+      optional("devices"),
+      optional("mold"),
+      optional("weather/outdoor"),
+    ]);
+    showMain();
+    renderOutdoor(outdoor);
+    renderDevices(readings, devices || [], mold || []);
+    if (state.deviceID !== null) {
+      await renderHistory();
+    }
+    document.getElementById("error").textContent = "";
+    document.getElementById("updated").textContent = "Updated " + new Date().toLocaleTimeString();
+  } catch (error) {
+    if (error instanceof Unauthorized) {
+      showLogin(state.apiKey ? "The API key was not accepted." : "");
+      return;
+    }
+    document.getElementById("error").textContent = error.message;
+  }
+}
+
+function renderOutdoor(outdoor) {
+  const el = document.getElementById("outdoor");
+  if (!outdoor) {
+    el.textContent = "Outdoor conditions not available yet";
+    return;
+  }
+  const unit = outdoor.temperature_unit;
+  el.textContent = `Outdoor (${outdoor.location}): ${formatNumber(outdoor.temperature, 1)} ${unit}, ` +
+    `dew point ${formatNumber(outdoor.dewpoint, 1)} ${unit}`;
+}
+
+function renderDevices(readings, devices, mold) {
+  const unit = readings.temperature_unit;
+  const statuses = new Map(devices.map((device) => [device.device_id, device]));
+  const moldStates = new Map(mold.map((m) => [m.device_id, m]));
+  const container = document.getElementById("devices");
+  container.replaceChildren();
+
+  if (readings.readings.length === 0) {
+    container.append(element("p", { class: "muted" }, "No readings yet."));
+    return;
+  }
+  if (state.deviceID === null) {
+    state.deviceID = readings.readings[0].device_id;
+  }
+
+  for (const reading of readings.readings) {
+    const status = statuses.get(reading.device_id);
+    const moldState = moldStates.get(reading.device_id);
+    const online = !status || status.online;
+
+    const alerts = [];
+    if (!online) {
+      alerts.push("Offline since " + new Date(status.offline_since || status.last_seen).toLocaleString());
+    }
+    if (reading.humidity_alert) {
+      alerts.push("Humidity alert");
+    }
+    if (reading.condensation_alert) {
+      alerts.push("Window condensation risk");
+    }
+    if (moldState && moldState.mold_index >= 1) {
+      alerts.push(`Mold index ${formatNumber(moldState.mold_index, 2)}: ${moldState.level}`);
+    }
+
+    const card = element("article", {
+      class: "device" + (reading.device_id === state.deviceID ? " selected" : ""),
+    },
+      element("h2", {}, "Device " + reading.device_id,
+        element("span", { class: "badge " + (online ? "ok" : "alert") }, online ? "online" : "offline")),
+      element("dl", {},
+        element("dt", {}, "Temperature"), element("dd", {}, `${formatNumber(reading.indoor_temperature, 1)} ${unit}`),
+        element("dt", {}, "Humidity"), element("dd", {}, `${formatNumber(reading.indoor_humidity, 1)} %`),
+        element("dt", {}, "Dew point"), element("dd", {}, `${formatNumber(reading.indoor_dewpoint, 1)} ${unit}`),
+        element("dt", {}, "Outdoor dew point"), element("dd", {}, formatOptional(reading.outdoor_dewpoint, 1, " " + unit)),
+        element("dt", {}, "Mold index"), element("dd", {}, moldState ? formatNumber(moldState.mold_index, 2) : "-"),
+      ),
+      element("p", { class: "recommendation " + (reading.open_windows ? "open" : "closed") },
+        reading.open_windows ? "Open the windows" : "Keep the windows closed"),
+      element("p", { class: "muted" }, "Last reading " + formatAge(reading.time)),
+      alerts.length > 0 ? element("ul", { class: "alerts" }, ...alerts.map((alert) => element("li", {}, alert))) : null,
+    );
+    card.addEventListener("click", () => {
+      state.deviceID = reading.device_id;
+      for (const other of container.querySelectorAll(".device")) {
+        other.classList.toggle("selected", other === card);
+      }
+      renderHistory().catch(showError);
+    });
+    container.append(card);
+  }
+}
+
+async function renderHistory() {
+  const history = await api(`readings/${state.deviceID}?range=${state.range}`);
+  document.getElementById("history").hidden = false;
+  document.getElementById("history-title").textContent = `Device ${state.deviceID}, last ${state.range}`;
+  const unit = history.temperature_unit;
+  const points = history.points;
+
+  drawChart(document.getElementById("temperature-chart"), points, [
+    { key: "indoor_temperature", class: "indoor-temperature" },
+    { key: "indoor_dewpoint", class: "indoor-dewpoint" },
+    { key: "outdoor_dewpoint", class: "outdoor-dewpoint" },
+  ], " " + unit);
+  drawChart(document.getElementById("humidity-chart"), points, [
+    { key: "indoor_humidity", class: "humidity" },
+  ], " %");
+}
+
+const svgNamespace = "http://www.w3.org/2000/svg";
+
+function svg(tag, attributes, text) {
+  const el = document.createElementNS(svgNamespace, tag);
+  for (const [name, value] of Object.entries(attributes)) {
+    el.setAttribute(name, value);
+  }
+  if (text !== undefined) {
+    el.textContent = text;
+  }
+  return el;
+}
+
+// drawChart plots the series of points as lines, breaking them where readings are missing
+function drawChart(chart, points, series, suffix) {
+  chart.replaceChildren();
+  const [, , width, height] = chart.getAttribute("viewBox").split(" ").map(Number);
+  const left = 50;
+  const bottom = 20;
+
+  if (points.length === 0) {
+    chart.append(svg("text", { x: width / 2, y: height / 2, "text-anchor": "middle" }, "No readings in this range"));
+    return;
+  }
+
+  const times = points.map((p) => new Date(p.time).getTime());
+  const values = series.flatMap((s) => points.map((p) => p[s.key])).filter((v) => v !== null);
+  let min = Math.floor(Math.min(...values));
+  let max = Math.ceil(Math.max(...values));
+  if (min === max) {
+    min -= 1;
+    max += 1;
+  }
+  const start = Math.min(...times);
+  const end = Math.max(...times, start + 1);
+  const x = (t) => left + ((t - start) / (end - start)) * (width - left - 5);
+  const y = (v) => 5 + ((max - v) / (max - min)) * (height - bottom - 5);
+
+  for (let i = 0; i <= 4; i++) {
+    const value = min + ((max - min) * i) / 4;
+    chart.append(svg("line", { class: "grid", x1: left, x2: width, y1: y(value), y2: y(value) }));
+    chart.append(svg("text", { x: left - 5, y: y(value) + 4, "text-anchor": "end" }, formatNumber(value, 1) + suffix));
+  }
+  const spanHours = (end - start) / 3600000;
+  for (let i = 0; i <= 4; i++) {
+    const t = start + ((end - start) * i) / 4;
+    const date = new Date(t);
+    const label = spanHours > 36 ? date.toLocaleDateString(undefined, { weekday: "short", day: "numeric" })
+      : date.toLocaleTimeString(undefined, { hour: "2-digit", minute: "2-digit" });
+    chart.append(svg("text", { x: x(t), y: height - 4, "text-anchor": i === 0 ? "start" : i === 4 ? "end" : "middle" }, label));
+  }
+
+  // a gap longer than a few buckets means the device was silent, unknown values break the line too
+  const maxGap = (end - start) / Math.max(points.length, 1) * 4;
+  for (const s of series) {
+    let d = "";
+    let previous = -1;
+    points.forEach((p, i) => {
+      if (p[s.key] === null) {
+        return;
+      }
+      const command = previous !== i - 1 || (i > 0 && times[i] - times[i - 1] > maxGap) ? "M" : "L";
+      d += `${command}${x(times[i]).toFixed(1)},${y(p[s.key]).toFixed(1)}`;
+      previous = i;
+    });
+    chart.append(svg("path", { class: s.class, d }));
+  }
+}
+
+function showError(error) {
+  if (error instanceof Unauthorized) {
+    showLogin("The API key was not accepted.");
+    return;
+  }
+  document.getElementById("error").textContent = error.message;
+}
+
+function showMain() {
+  document.getElementById("login").hidden = true;
+  document.getElementById("main").hidden = false;
+  document.getElementById("sign-out").hidden = !state.apiKey;
+}
+
+function showLogin(message) {
+  document.getElementById("main").hidden = true;
+  document.getElementById("login").hidden = false;
+  document.getElementById("login-error").textContent = message;
+}
+
+document.getElementById("login").addEventListener("submit", (event) => {
+  event.preventDefault();
+  state.apiKey = document.getElementById("api-key").value.trim();
+  localStorage.setItem(keyStorage, state.apiKey);
+  state.deviceID = null;
+  refresh();
+});
+
+document.getElementById("sign-out").addEventListener("click", () => {
+  localStorage.removeItem(keyStorage);
+  state.apiKey = "";
+  state.deviceID = null;
+  showLogin("");
+});
+
+for (const button of document.querySelectorAll(".ranges button")) {
+  button.addEventListener("click", () => {
+    state.range = button.dataset.range;
+    for (const other of document.querySelectorAll(".ranges button")) {
+      other.classList.toggle("selected", other === button);
+    }
+    renderHistory().catch(showError);
+  });
+}
+
+refresh();
+setInterval(() => {
+  if (!document.getElementById("main").hidden) {
+    refresh();
+  }
+}, refreshInterval);
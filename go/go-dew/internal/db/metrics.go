@@ -0,0 +1,48 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mugglemath/go-dew/internal/metrics"
+	"gorm.io/gorm"
+)
+
+const startKey = "metrics:start"
+
+// queryMetrics is a gorm plugin recording the latency of every statement by type and table
+type queryMetrics struct{}
+
+func (queryMetrics) Name() string {
+	return "metrics"
+}
+
+func (queryMetrics) Initialize(db *gorm.DB) error {
+	c := db.Callback()
+	return errors.Join(
+		c.Create().Before("gorm:create").Register("metrics:before_create", startTimer),
+		c.Create().After("gorm:create").Register("metrics:after_create", observe("create")),
+		c.Query().Before("gorm:query").Register("metrics:before_query", startTimer),
+		c.Query().After("gorm:query").Register("metrics:after_query", observe("query")),
+		c.Update().Before("gorm:update").Register("metrics:before_update", startTimer),
+		c.Update().After("gorm:update").Register("metrics:after_update", observe("update")),
+		c.Delete().Before("gorm:delete").Register("metrics:before_delete", startTimer),
+		c.Delete().After("gorm:delete").Register("metrics:after_delete", observe("delete")),
+		c.Row().Before("gorm:row").Register("metrics:before_row", startTimer),
+		c.Row().After("gorm:row").Register("metrics:after_row", observe("row")),
+		c.Raw().Before("gorm:raw").Register("metrics:before_raw", startTimer),
+		c.Raw().After("gorm:raw").Register("metrics:after_raw", observe("raw")),
+	)
+}
+
+func startTimer(tx *gorm.DB) {
+	tx.InstanceSet(startKey, time.Now())
+}
+
+func observe(operation string) func(tx *gorm.DB) {
+	return func(tx *gorm.DB) {
+		if start, ok := tx.InstanceGet(startKey); ok {
+			metrics.ObserveDBQuery(operation+":"+tx.Statement.Table, start.(time.Time), tx.Error)
+		}
+	}
+}
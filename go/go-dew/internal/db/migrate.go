@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"gorm.io/gorm"
+)
+
+// migrations bring a database created by an older go-dew up to date. Each one must be
+// safe to run again, they all run on every startup.
+var migrations = []string{
+	`CREATE TABLE IF NOT EXISTS mold_state (
+    device_id BIGINT PRIMARY KEY,
+    tenant_id TEXT NOT NULL DEFAULT 'default',
+    mold_index REAL NOT NULL DEFAULT 0,
+    dry_hours REAL NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`,
+	// households, rows from before them belong to the default one
+	`ALTER TABLE data ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default'`,
+	`ALTER TABLE mold_state ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default'`,
+	`CREATE INDEX IF NOT EXISTS data_tenant_device_time_idx ON data (tenant_id, device_id, time DESC)`,
+	// readings before calibration and filtering
+	`ALTER TABLE data ADD COLUMN IF NOT EXISTS raw_indoor_temperature REAL`,
+	`ALTER TABLE data ADD COLUMN IF NOT EXISTS raw_indoor_humidity REAL`,
+}
+
+// Migrate applies the migrations in one transaction
+func (c *clientImpl) Migrate(ctx context.Context) error {
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, migration := range migrations {
+			if err := tx.Exec(migration).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+	slog.Info("database schema up to date")
+	return nil
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestMigrate_Success(t *testing.T) {
+	// setup mock
+	client, mock := setupTestDB(t)
+
+	// setup test
+	mock.ExpectBegin()
+	for _, migration := range migrations {
+		mock.ExpectExec(regexp.QuoteMeta(migration)).WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	mock.ExpectCommit()
+
+	if err := client.Migrate(context.Background()); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestMigrate_Error(t *testing.T) {
+	// setup mock
+	client, mock := setupTestDB(t)
+
+	// setup test
+	mock.ExpectBegin()
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS mold_state").WillReturnError(errors.New("permission denied"))
+	mock.ExpectRollback()
+
+	err := client.Migrate(context.Background())
+	if err == nil || err.Error() != "failed to migrate database: permission denied" {
+		t.Errorf("expected failed to migrate database: permission denied, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -2,22 +2,41 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mugglemath/dewdrop-go/pkg/logging"
+	"github.com/mugglemath/dewdrop-go/pkg/tracing"
 
 	"github.com/mugglemath/go-dew/internal/model"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type clientImpl struct {
 	db *gorm.DB
 }
 
+// Client stores the data of every household, each query only sees the rows of tenantID
 type Client interface {
-	InsertSensorFeedData(ctx context.Context, sensorData model.SensorData) error
-	GetLastOpenWindowsValue(ctx context.Context) (bool, error)
-	CheckRecentHumidityAlert(ctx context.Context) (bool, error)
-	CheckForEmptyTable(ctx context.Context, tableName string) (bool, error)
+	InsertSensorFeedData(ctx context.Context, tenantID string, sensorData model.SensorData) error
+	GetLastOpenWindowsValue(ctx context.Context, tenantID string, deviceID uint64) (bool, error)
+	CheckRecentHumidityAlert(ctx context.Context, tenantID string, deviceID uint64) (bool, error)
+	CheckForEmptyTable(ctx context.Context, tenantID, tableName string) (bool, error)
+	GetReadingsSince(ctx context.Context, tenantID string, deviceID uint64, since time.Time) ([]model.Reading, error)
+	GetLatestReadings(ctx context.Context, tenantID string) ([]model.StoredReading, error)
+	GetHistory(ctx context.Context, tenantID string, deviceID uint64, since time.Time, bucket time.Duration) ([]model.HistoryPoint, error)
+	GetMoldState(ctx context.Context, tenantID string, deviceID uint64) (*model.MoldState, error)
+	GetMoldStates(ctx context.Context, tenantID string) ([]model.MoldState, error)
+	SaveMoldState(ctx context.Context, tenantID string, state model.MoldState) error
+	GetLastSeen(ctx context.Context, tenantID string) ([]model.DeviceStatus, error)
+	Ping(ctx context.Context) error
+	Migrate(ctx context.Context) error
+	Close() error
 }
 
 func New(db *gorm.DB) Client {
@@ -32,22 +51,31 @@ func ConnectToPostgres(dsn string, db *gorm.DB) (*gorm.DB, Client, error) {
 			return nil, nil, err
 		}
 	}
-	fmt.Println("Successfully connected to PostgreSQL!")
+	if err := db.Use(queryMetrics{}); err != nil {
+		return nil, nil, fmt.Errorf("failed to register query metrics: %w", err)
+	}
+	if err := db.Use(queryTracing{}); err != nil {
+		return nil, nil, fmt.Errorf("failed to register query tracing: %w", err)
+	}
+	slog.Info("connected to PostgreSQL")
 	return db, &clientImpl{db: db}, nil
 }
 
-func (c *clientImpl) InsertSensorFeedData(ctx context.Context, sensorData model.SensorData) error {
+func (c *clientImpl) InsertSensorFeedData(ctx context.Context, tenantID string, sensorData model.SensorData) error {
+	sensorData.TenantID = tenantID
 	if err := c.db.WithContext(ctx).Create(&sensorData).Error; err != nil {
 		return fmt.Errorf("failed to insert sensor data: %w", err)
 	}
-	fmt.Println("Successfully inserted sensor data into PostgreSQL!")
+	logging.FromContext(ctx).Debug("inserted sensor data", "device_id", sensorData.DeviceID)
 	return nil
 }
 
-func (c *clientImpl) GetLastOpenWindowsValue(ctx context.Context) (bool, error) {
+// GetLastOpenWindowsValue returns the advice stored with the last reading of a device
+func (c *clientImpl) GetLastOpenWindowsValue(ctx context.Context, tenantID string, deviceID uint64) (bool, error) {
 	var lastOpenWindows bool
 	err := c.db.WithContext(ctx).Model(&model.SensorData{}).
 		Select("open_windows").
+		Where("tenant_id = ? AND device_id = ?", tenantID, deviceID).
 		Order("time DESC").
 		Limit(1).
 		Scan(&lastOpenWindows).Error
@@ -57,11 +85,13 @@ func (c *clientImpl) GetLastOpenWindowsValue(ctx context.Context) (bool, error)
 	return lastOpenWindows, nil
 }
 
-func (c *clientImpl) CheckRecentHumidityAlert(ctx context.Context) (bool, error) {
+// CheckRecentHumidityAlert reports whether a device had a humidity alert in the last hour
+func (c *clientImpl) CheckRecentHumidityAlert(ctx context.Context, tenantID string, deviceID uint64) (bool, error) {
 	var alertExists bool
 	err := c.db.WithContext(ctx).Model(&model.SensorData{}).
 		Select("COUNT(*) > 0").
-		Where("humidity_alert = ? AND time >= NOW() - INTERVAL '1 hour'", true).
+		Where("tenant_id = ? AND device_id = ? AND humidity_alert = ? AND time >= NOW() - INTERVAL '1 hour'",
+			tenantID, deviceID, true).
 		Scan(&alertExists).Error
 	if err != nil {
 		return false, fmt.Errorf("failed to check recent humidity alert: %w", err)
@@ -69,12 +99,137 @@ func (c *clientImpl) CheckRecentHumidityAlert(ctx context.Context) (bool, error)
 	return alertExists, nil
 }
 
-func (c *clientImpl) CheckForEmptyTable(ctx context.Context, tableName string) (bool, error) {
+func (c *clientImpl) CheckForEmptyTable(ctx context.Context, tenantID, tableName string) (bool, error) {
 	var exists bool
-	query := fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM %s LIMIT 1)", tableName)
-	err := c.db.WithContext(ctx).Raw(query).Scan(&exists).Error
+	query := fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM %s WHERE tenant_id = ? LIMIT 1)", tableName)
+	err := c.db.WithContext(ctx).Raw(query, tenantID).Scan(&exists).Error
 	if err != nil {
 		return false, fmt.Errorf("error checking table size: %w", err)
 	}
 	return !exists, nil
 }
+
+// GetReadingsSince returns the temperature and humidity history of a device, oldest first
+func (c *clientImpl) GetReadingsSince(ctx context.Context, tenantID string, deviceID uint64, since time.Time) ([]model.Reading, error) {
+	var readings []model.Reading
+	err := c.db.WithContext(ctx).Model(&model.SensorData{}).
+		Select("time, indoor_temperature, indoor_humidity").
+		Where("tenant_id = ? AND device_id = ? AND time >= ?", tenantID, deviceID, since).
+		Order("time").
+		Scan(&readings).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve readings: %w", err)
+	}
+	return readings, nil
+}
+
+// GetLatestReadings returns the latest reading of every device of tenantID
+func (c *clientImpl) GetLatestReadings(ctx context.Context, tenantID string) ([]model.StoredReading, error) {
+	var readings []model.StoredReading
+	err := c.db.WithContext(ctx).Model(&model.SensorData{}).
+		Select("DISTINCT ON (device_id) device_id, time, indoor_temperature, indoor_humidity, indoor_dewpoint, "+
+			"outdoor_dewpoint, dewpoint_delta, open_windows, humidity_alert").
+		Where("tenant_id = ?", tenantID).
+		Order("device_id, time DESC").
+		Scan(&readings).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve latest readings: %w", err)
+	}
+	return readings, nil
+}
+
+// GetHistory averages the readings of a device since since over buckets of the given
+// length, oldest first
+func (c *clientImpl) GetHistory(ctx context.Context, tenantID string, deviceID uint64, since time.Time,
+	bucket time.Duration) ([]model.HistoryPoint, error) {
+	var points []model.HistoryPoint
+	err := c.db.WithContext(ctx).Model(&model.SensorData{}).
+		Select("time_bucket(?::interval, time) AS bucket, AVG(indoor_temperature) AS indoor_temperature, "+
+			"AVG(indoor_humidity) AS indoor_humidity, AVG(indoor_dewpoint) AS indoor_dewpoint, "+
+			"AVG(outdoor_dewpoint) AS outdoor_dewpoint", fmt.Sprintf("%d seconds", int(bucket.Seconds()))).
+		Where("tenant_id = ? AND device_id = ? AND time >= ?", tenantID, deviceID, since).
+		Group("bucket").
+		Order("bucket").
+		Scan(&points).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve history: %w", err)
+	}
+	return points, nil
+}
+
+// GetMoldState returns nil if the device has no stored mold index yet
+func (c *clientImpl) GetMoldState(ctx context.Context, tenantID string, deviceID uint64) (*model.MoldState, error) {
+	var state model.MoldState
+	err := c.db.WithContext(ctx).Where("tenant_id = ? AND device_id = ?", tenantID, deviceID).Take(&state).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve mold state: %w", err)
+	}
+	return &state, nil
+}
+
+func (c *clientImpl) GetMoldStates(ctx context.Context, tenantID string) ([]model.MoldState, error) {
+	var states []model.MoldState
+	if err := c.db.WithContext(ctx).Where("tenant_id = ?", tenantID).Order("device_id").Find(&states).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve mold states: %w", err)
+	}
+	return states, nil
+}
+
+// SaveMoldState stores the state for tenantID, a device that moved to another household
+// takes its state along
+func (c *clientImpl) SaveMoldState(ctx context.Context, tenantID string, state model.MoldState) error {
+	state.TenantID = tenantID
+	err := c.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "device_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"mold_index", "dry_hours", "updated_at", "tenant_id"}),
+	}).Create(&state).Error
+	if err != nil {
+		return fmt.Errorf("failed to save mold state: %w", err)
+	}
+	return nil
+}
+
+// GetLastSeen returns the time of the latest reading of every device of tenantID
+func (c *clientImpl) GetLastSeen(ctx context.Context, tenantID string) ([]model.DeviceStatus, error) {
+	var devices []model.DeviceStatus
+	err := c.db.WithContext(ctx).Model(&model.SensorData{}).
+		Select("device_id, MAX(time) AS last_seen").
+		Where("tenant_id = ?", tenantID).
+		Group("device_id").
+		Order("device_id").
+		Scan(&devices).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve last seen times: %w", err)
+	}
+	return devices, nil
+}
+
+// Ping checks that the database is reachable
+func (c *clientImpl) Ping(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(ctx, tracerName, "ping", trace.WithSpanKind(trace.SpanKindClient))
+	defer func() { tracing.End(span, err) }()
+
+	sqlDB, err := c.db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database connection: %w", err)
+	}
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+	return nil
+}
+
+// Close closes the connection pool, queries still running are not waited for
+func (c *clientImpl) Close() error {
+	sqlDB, err := c.db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database connection: %w", err)
+	}
+	if err := sqlDB.Close(); err != nil {
+		return fmt.Errorf("failed to close database: %w", err)
+	}
+	return nil
+}
@@ -3,10 +3,19 @@ package db
 import (
 	"context"
 	"errors"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/mugglemath/dewdrop-go/pkg/tracing"
+	"github.com/mugglemath/go-dew/internal/metrics"
 	"github.com/mugglemath/go-dew/internal/model"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -49,15 +58,18 @@ func TestInsertSensorFeedData_Success(t *testing.T) {
 	// setup mock
 	client, mock := setupTestDB(t)
 
+	outdoor, raw := 1.0, 1.2
 	sensorData := model.SensorData{
-		DeviceID:          1,
-		IndoorTemperature: 1.0,
-		IndoorHumidity:    1.0,
-		IndoorDewpoint:    1.0,
-		OutdoorDewpoint:   1.0,
-		DewpointDelta:     1.0,
-		OpenWindows:       true,
-		HumidityAlert:     false}
+		DeviceID:             1,
+		IndoorTemperature:    1.0,
+		IndoorHumidity:       1.0,
+		RawIndoorTemperature: &raw,
+		RawIndoorHumidity:    &raw,
+		IndoorDewpoint:       1.0,
+		OutdoorDewpoint:      &outdoor,
+		DewpointDelta:        &outdoor,
+		OpenWindows:          true,
+		HumidityAlert:        false}
 
 	// setup test
 	mock.ExpectBegin()
@@ -66,15 +78,18 @@ func TestInsertSensorFeedData_Success(t *testing.T) {
 			sensorData.DeviceID,
 			sensorData.IndoorTemperature,
 			sensorData.IndoorHumidity,
+			raw,
+			raw,
 			sensorData.IndoorDewpoint,
-			sensorData.OutdoorDewpoint,
-			sensorData.DewpointDelta,
+			outdoor,
+			outdoor,
 			sensorData.OpenWindows,
-			sensorData.HumidityAlert).
+			sensorData.HumidityAlert,
+			"smiths").
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
-	err := client.InsertSensorFeedData(context.Background(), sensorData)
+	err := client.InsertSensorFeedData(context.Background(), "smiths", sensorData)
 
 	if err != nil {
 		t.Errorf("expected no error, got %v", err)
@@ -89,15 +104,18 @@ func TestInsertSensorFeedData_Error(t *testing.T) {
 	// setup mock
 	client, mock := setupTestDB(t)
 
+	outdoor, raw := 1.0, 1.2
 	sensorData := model.SensorData{
-		DeviceID:          1,
-		IndoorTemperature: 1.0,
-		IndoorHumidity:    1.0,
-		IndoorDewpoint:    1.0,
-		OutdoorDewpoint:   1.0,
-		DewpointDelta:     1.0,
-		OpenWindows:       true,
-		HumidityAlert:     false}
+		DeviceID:             1,
+		IndoorTemperature:    1.0,
+		IndoorHumidity:       1.0,
+		RawIndoorTemperature: &raw,
+		RawIndoorHumidity:    &raw,
+		IndoorDewpoint:       1.0,
+		OutdoorDewpoint:      &outdoor,
+		DewpointDelta:        &outdoor,
+		OpenWindows:          true,
+		HumidityAlert:        false}
 
 	// setup test
 	mock.ExpectBegin()
@@ -105,15 +123,18 @@ func TestInsertSensorFeedData_Error(t *testing.T) {
 		sensorData.DeviceID,
 		sensorData.IndoorTemperature,
 		sensorData.IndoorHumidity,
+		raw,
+		raw,
 		sensorData.IndoorDewpoint,
-		sensorData.OutdoorDewpoint,
-		sensorData.DewpointDelta,
+		outdoor,
+		outdoor,
 		sensorData.OpenWindows,
-		sensorData.HumidityAlert).
+		sensorData.HumidityAlert,
+		"smiths").
 		WillReturnError(errors.New("insert error"))
 	mock.ExpectRollback()
 
-	err := client.InsertSensorFeedData(context.Background(), sensorData)
+	err := client.InsertSensorFeedData(context.Background(), "smiths", sensorData)
 
 	if err == nil {
 		t.Errorf("expected an error but got none")
@@ -133,11 +154,11 @@ func TestGetLastOpenWindowsValue_True(t *testing.T) {
 	// setup test
 	expectedOpenWindows := true
 
-	mock.ExpectQuery(`SELECT "open_windows" FROM "data" ORDER BY time DESC LIMIT \$1`).
-		WithArgs(1).
+	mock.ExpectQuery(`SELECT "open_windows" FROM "data" WHERE tenant_id = \$1 AND device_id = \$2 ORDER BY time DESC LIMIT \$3`).
+		WithArgs("smiths", 1234, 1).
 		WillReturnRows(sqlmock.NewRows([]string{"open_windows"}).AddRow(expectedOpenWindows))
 
-	openWindowsValue, err := client.GetLastOpenWindowsValue(context.Background())
+	openWindowsValue, err := client.GetLastOpenWindowsValue(context.Background(), "smiths", 1234)
 	if err != nil {
 		t.Errorf("expected no error but got %v", err)
 	}
@@ -158,11 +179,11 @@ func TestGetLastOpenWindowsValue_False(t *testing.T) {
 	// setup test
 	expectedOpenWindows := false
 
-	mock.ExpectQuery(`SELECT "open_windows" FROM "data" ORDER BY time DESC LIMIT \$1`).
-		WithArgs(1).
+	mock.ExpectQuery(`SELECT "open_windows" FROM "data" WHERE tenant_id = \$1 AND device_id = \$2 ORDER BY time DESC LIMIT \$3`).
+		WithArgs("smiths", 1234, 1).
 		WillReturnRows(sqlmock.NewRows([]string{"open_windows"}).AddRow(expectedOpenWindows))
 
-	openWindowsValue, err := client.GetLastOpenWindowsValue(context.Background())
+	openWindowsValue, err := client.GetLastOpenWindowsValue(context.Background(), "smiths", 1234)
 	if err != nil {
 		t.Errorf("expected no error but got %v", err)
 	}
@@ -181,11 +202,11 @@ func TestGetLastOpenWindowsValue_Error(t *testing.T) {
 	client, mock := setupTestDB(t)
 
 	// setup test
-	mock.ExpectQuery(`SELECT "open_windows" FROM "data" ORDER BY time DESC LIMIT \$1`).
-		WithArgs(1).
+	mock.ExpectQuery(`SELECT "open_windows" FROM "data" WHERE tenant_id = \$1 AND device_id = \$2 ORDER BY time DESC LIMIT \$3`).
+		WithArgs("smiths", 1234, 1).
 		WillReturnError(errors.New("query error"))
 
-	_, err := client.GetLastOpenWindowsValue(context.Background())
+	_, err := client.GetLastOpenWindowsValue(context.Background(), "smiths", 1234)
 
 	if err == nil {
 		t.Errorf("expected an error but got none")
@@ -205,11 +226,11 @@ func TestCheckRecentHumidityAlert_True(t *testing.T) {
 	// setup test
 	expectedAlert := true
 
-	mock.ExpectQuery(`SELECT COUNT\(\*\) > 0 FROM "data" WHERE humidity_alert = \$1 AND time >= NOW\(\) - INTERVAL '1 hour'`).
-		WithArgs(true).
+	mock.ExpectQuery(`SELECT COUNT\(\*\) > 0 FROM "data" WHERE tenant_id = \$1 AND device_id = \$2 AND humidity_alert = \$3 AND time >= NOW\(\) - INTERVAL '1 hour'`).
+		WithArgs("smiths", 1234, true).
 		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(expectedAlert))
 
-	alertValue, err := client.CheckRecentHumidityAlert(context.Background())
+	alertValue, err := client.CheckRecentHumidityAlert(context.Background(), "smiths", 1234)
 	if err != nil {
 		t.Errorf("expected no error but got %v", err)
 	}
@@ -230,11 +251,11 @@ func TestCheckRecentHumidityAlert_False(t *testing.T) {
 	// setup test
 	expectedAlert := false
 
-	mock.ExpectQuery(`SELECT COUNT\(\*\) > 0 FROM "data" WHERE humidity_alert = \$1 AND time >= NOW\(\) - INTERVAL '1 hour'`).
-		WithArgs(true).
+	mock.ExpectQuery(`SELECT COUNT\(\*\) > 0 FROM "data" WHERE tenant_id = \$1 AND device_id = \$2 AND humidity_alert = \$3 AND time >= NOW\(\) - INTERVAL '1 hour'`).
+		WithArgs("smiths", 1234, true).
 		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(expectedAlert))
 
-	alertValue, err := client.CheckRecentHumidityAlert(context.Background())
+	alertValue, err := client.CheckRecentHumidityAlert(context.Background(), "smiths", 1234)
 	if err != nil {
 		t.Errorf("expected no error but got %v", err)
 	}
@@ -253,11 +274,11 @@ func TestCheckRecentHumidityAlert_Error(t *testing.T) {
 	client, mock := setupTestDB(t)
 
 	// setup test
-	mock.ExpectQuery(`SELECT COUNT\(\*\) > 0 FROM "data" WHERE humidity_alert = \$1 AND time >= NOW\(\) - INTERVAL '1 hour'`).
-		WithArgs(true).
+	mock.ExpectQuery(`SELECT COUNT\(\*\) > 0 FROM "data" WHERE tenant_id = \$1 AND device_id = \$2 AND humidity_alert = \$3 AND time >= NOW\(\) - INTERVAL '1 hour'`).
+		WithArgs("smiths", 1234, true).
 		WillReturnError(errors.New("query error"))
 
-	_, err := client.CheckRecentHumidityAlert(context.Background())
+	_, err := client.CheckRecentHumidityAlert(context.Background(), "smiths", 1234)
 
 	if err == nil {
 		t.Errorf("expected an error but got none")
@@ -278,10 +299,11 @@ func TestCheckForEmptyTable_True(t *testing.T) {
 	expectedExists := false
 	expectedCheck := true
 
-	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM data LIMIT 1\)`).
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM data WHERE tenant_id = \$1 LIMIT 1\)`).
+		WithArgs("smiths").
 		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(expectedExists))
 
-	alertValue, err := client.CheckForEmptyTable(context.Background(), "data")
+	alertValue, err := client.CheckForEmptyTable(context.Background(), "smiths", "data")
 	if err != nil {
 		t.Errorf("expected no error but got %v", err)
 	}
@@ -303,10 +325,11 @@ func TestCheckForEmptyTable_False(t *testing.T) {
 	expectedExists := true
 	expectedCheck := false
 
-	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM data LIMIT 1\)`).
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM data WHERE tenant_id = \$1 LIMIT 1\)`).
+		WithArgs("smiths").
 		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(expectedExists))
 
-	alertValue, err := client.CheckForEmptyTable(context.Background(), "data")
+	alertValue, err := client.CheckForEmptyTable(context.Background(), "smiths", "data")
 	if err != nil {
 		t.Errorf("expected no error but got %v", err)
 	}
@@ -325,10 +348,11 @@ func TestCheckForEmptyTable_Error(t *testing.T) {
 	client, mock := setupTestDB(t)
 
 	// setup test
-	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM data LIMIT 1\)`).
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM data WHERE tenant_id = \$1 LIMIT 1\)`).
+		WithArgs("smiths").
 		WillReturnError(errors.New("query error"))
 
-	_, err := client.CheckForEmptyTable(context.Background(), "data")
+	_, err := client.CheckForEmptyTable(context.Background(), "smiths", "data")
 	if err == nil {
 		t.Errorf("expected an error but got none")
 	} else if err.Error() != "error checking table size: query error" {
@@ -340,6 +364,87 @@ func TestCheckForEmptyTable_Error(t *testing.T) {
 	}
 }
 
+func TestQueryMetrics(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm DB: %v", err)
+	}
+	_, client, err := ConnectToPostgres("", gormDB)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	mock.ExpectQuery(`SELECT \* FROM "mold_state" WHERE tenant_id = \$1 ORDER BY device_id`).
+		WithArgs("smiths").
+		WillReturnRows(sqlmock.NewRows([]string{"device_id"}))
+	if _, err := client.GetMoldStates(context.Background(), "smiths"); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	expected := `go_dew_db_query_duration_seconds_count{operation="query:mold_state",result="success"} 1`
+	if !strings.Contains(recorder.Body.String(), expected) {
+		t.Errorf("expected %q in the metrics", expected)
+	}
+}
+
+func TestQueryTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	shutdown, err := tracing.Install(context.Background(), "go-dew", sdktrace.WithSyncer(exporter))
+	if err != nil {
+		t.Fatalf("failed to install tracing: %v", err)
+	}
+	defer shutdown(context.Background())
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm DB: %v", err)
+	}
+	_, client, err := ConnectToPostgres("", gormDB)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	mock.ExpectQuery(`SELECT device_id, MAX\(time\) AS last_seen FROM "data"`).
+		WillReturnError(errors.New("query error"))
+	ctx, parent := tracing.Start(context.Background(), "test", "request")
+	_, _ = client.GetLastSeen(ctx, "smiths")
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "row data" || span.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("expected row data as a child of the request, got %s", span.Name)
+	}
+	if span.Status.Code != codes.Error || span.Status.Description != "query error" {
+		t.Errorf("expected an error status, got %v", span.Status)
+	}
+	attrs := map[attribute.Key]string{}
+	for _, attr := range span.Attributes {
+		attrs[attr.Key] = attr.Value.AsString()
+	}
+	if attrs["db.system"] != "postgresql" || attrs["db.sql.table"] != "data" ||
+		!strings.HasPrefix(attrs["db.statement"], "SELECT device_id, MAX(time)") {
+		t.Errorf("unexpected attributes %v", attrs)
+	}
+}
+
 func setupTestDB(t *testing.T) (*clientImpl, sqlmock.Sqlmock) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -356,3 +461,369 @@ func setupTestDB(t *testing.T) (*clientImpl, sqlmock.Sqlmock) {
 	client := &clientImpl{db: gormDB}
 	return client, mock
 }
+
+func TestGetReadingsSince_Success(t *testing.T) {
+	// setup mock
+	client, mock := setupTestDB(t)
+
+	// setup test
+	since := time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+	first := since.Add(time.Minute)
+	second := since.Add(2 * time.Minute)
+
+	mock.ExpectQuery(`SELECT time, indoor_temperature, indoor_humidity FROM "data" WHERE tenant_id = \$1 AND device_id = \$2 AND time >= \$3 ORDER BY time`).
+		WithArgs("smiths", uint64(7), since).
+		WillReturnRows(sqlmock.NewRows([]string{"time", "indoor_temperature", "indoor_humidity"}).
+			AddRow(first, 18.5, 68.0).
+			AddRow(second, 18.4, 69.0))
+
+	readings, err := client.GetReadingsSince(context.Background(), "smiths", 7, since)
+	if err != nil {
+		t.Errorf("expected no error but got %v", err)
+	}
+
+	expected := []model.Reading{
+		{Time: first, IndoorTemperature: 18.5, IndoorHumidity: 68.0},
+		{Time: second, IndoorTemperature: 18.4, IndoorHumidity: 69.0},
+	}
+	if len(readings) != len(expected) || readings[0] != expected[0] || readings[1] != expected[1] {
+		t.Errorf("expected %v but got %v", expected, readings)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetReadingsSince_Error(t *testing.T) {
+	// setup mock
+	client, mock := setupTestDB(t)
+
+	// setup test
+	mock.ExpectQuery(`SELECT time, indoor_temperature, indoor_humidity FROM "data"`).
+		WillReturnError(errors.New("query error"))
+
+	_, err := client.GetReadingsSince(context.Background(), "smiths", 7, time.Now())
+	if err == nil {
+		t.Errorf("expected an error but got none")
+	} else if err.Error() != "failed to retrieve readings: query error" {
+		t.Errorf("expected failed to retrieve readings: query error but got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetMoldState_Found(t *testing.T) {
+	// setup mock
+	client, mock := setupTestDB(t)
+
+	// setup test
+	updatedAt := time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT \* FROM "mold_state" WHERE tenant_id = \$1 AND device_id = \$2 LIMIT \$3`).
+		WithArgs("smiths", uint64(7), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"device_id", "mold_index", "dry_hours", "updated_at"}).
+			AddRow(7, 1.25, 3.0, updatedAt))
+
+	state, err := client.GetMoldState(context.Background(), "smiths", 7)
+	if err != nil {
+		t.Errorf("expected no error but got %v", err)
+	}
+
+	expected := model.MoldState{DeviceID: 7, MoldIndex: 1.25, DryHours: 3, UpdatedAt: updatedAt}
+	if state == nil || *state != expected {
+		t.Errorf("expected %v but got %v", expected, state)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetMoldState_NotFound(t *testing.T) {
+	// setup mock
+	client, mock := setupTestDB(t)
+
+	// setup test
+	mock.ExpectQuery(`SELECT \* FROM "mold_state" WHERE tenant_id = \$1 AND device_id = \$2 LIMIT \$3`).
+		WithArgs("smiths", uint64(7), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"device_id", "mold_index", "dry_hours", "updated_at"}))
+
+	state, err := client.GetMoldState(context.Background(), "smiths", 7)
+	if err != nil {
+		t.Errorf("expected no error but got %v", err)
+	}
+
+	if state != nil {
+		t.Errorf("expected nil but got %v", state)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetMoldStates_Success(t *testing.T) {
+	// setup mock
+	client, mock := setupTestDB(t)
+
+	// setup test
+	mock.ExpectQuery(`SELECT \* FROM "mold_state" WHERE tenant_id = \$1 ORDER BY device_id`).
+		WithArgs("smiths").
+		WillReturnRows(sqlmock.NewRows([]string{"device_id", "mold_index", "dry_hours", "updated_at"}).
+			AddRow(1, 0.0, 40.0, time.Now()).
+			AddRow(2, 2.5, 0.0, time.Now()))
+
+	states, err := client.GetMoldStates(context.Background(), "smiths")
+	if err != nil {
+		t.Errorf("expected no error but got %v", err)
+	}
+
+	if len(states) != 2 || states[1].MoldIndex != 2.5 {
+		t.Errorf("expected two states but got %v", states)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSaveMoldState_Success(t *testing.T) {
+	// setup mock
+	client, mock := setupTestDB(t)
+
+	// setup test
+	state := model.MoldState{DeviceID: 7, MoldIndex: 1.25, DryHours: 3, UpdatedAt: time.Now()}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "mold_state" \("device_id","mold_index","dry_hours","updated_at","tenant_id"\) VALUES \(\$1,\$2,\$3,\$4,\$5\) ON CONFLICT \("device_id"\) DO UPDATE SET "mold_index"="excluded"."mold_index","dry_hours"="excluded"."dry_hours","updated_at"="excluded"."updated_at","tenant_id"="excluded"."tenant_id"`).
+		WithArgs(state.DeviceID, state.MoldIndex, state.DryHours, sqlmock.AnyArg(), "smiths").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if err := client.SaveMoldState(context.Background(), "smiths", state); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSaveMoldState_Error(t *testing.T) {
+	// setup mock
+	client, mock := setupTestDB(t)
+
+	// setup test
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "mold_state"`).
+		WillReturnError(errors.New("insert error"))
+	mock.ExpectRollback()
+
+	err := client.SaveMoldState(context.Background(), "smiths", model.MoldState{DeviceID: 7})
+	if err == nil {
+		t.Errorf("expected an error but got none")
+	} else if err.Error() != "failed to save mold state: insert error" {
+		t.Errorf("expected failed to save mold state: insert error but got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPing(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	// gorm pings once when opening
+	mock.ExpectPing()
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm DB: %v", err)
+	}
+	client := &clientImpl{db: gormDB}
+
+	mock.ExpectPing()
+	if err := client.Ping(context.Background()); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	err = client.Ping(context.Background())
+	expected := "failed to ping database: connection refused"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetLastSeen_Success(t *testing.T) {
+	// setup mock
+	client, mock := setupTestDB(t)
+
+	// setup test
+	first := time.Date(2024, 12, 1, 12, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+
+	mock.ExpectQuery(`SELECT device_id, MAX\(time\) AS last_seen FROM "data" WHERE tenant_id = \$1 GROUP BY "device_id" ORDER BY device_id`).
+		WithArgs("smiths").
+		WillReturnRows(sqlmock.NewRows([]string{"device_id", "last_seen"}).
+			AddRow(uint64(1), first).
+			AddRow(uint64(2), second))
+
+	devices, err := client.GetLastSeen(context.Background(), "smiths")
+	if err != nil {
+		t.Errorf("expected no error but got %v", err)
+	}
+	if len(devices) != 2 || devices[0].DeviceID != 1 || !devices[0].LastSeen.Equal(first) ||
+		devices[1].DeviceID != 2 || !devices[1].LastSeen.Equal(second) {
+		t.Errorf("expected devices 1 and 2 but got %v", devices)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetLastSeen_Error(t *testing.T) {
+	// setup mock
+	client, mock := setupTestDB(t)
+
+	// setup test
+	mock.ExpectQuery(`SELECT device_id, MAX\(time\) AS last_seen FROM "data"`).
+		WillReturnError(errors.New("query error"))
+
+	_, err := client.GetLastSeen(context.Background(), "smiths")
+	if err == nil || err.Error() != "failed to retrieve last seen times: query error" {
+		t.Errorf("expected failed to retrieve last seen times: query error but got %v", err)
+	}
+}
+
+func TestGetLatestReadings_Success(t *testing.T) {
+	// setup mock
+	client, mock := setupTestDB(t)
+
+	// setup test
+	at := time.Date(2024, 12, 1, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT DISTINCT ON \(device_id\) device_id, time, indoor_temperature, .* FROM "data" WHERE tenant_id = \$1 ORDER BY device_id, time DESC`).
+		WithArgs("smiths").
+		WillReturnRows(sqlmock.NewRows([]string{"device_id", "time", "indoor_temperature", "indoor_humidity",
+			"indoor_dewpoint", "outdoor_dewpoint", "dewpoint_delta", "open_windows", "humidity_alert"}).
+			AddRow(uint64(1), at, 21.5, 55.0, 12.1, 8.0, 4.1, true, false).
+			AddRow(uint64(2), at, 20.0, 50.0, 9.3, nil, nil, false, false))
+
+	readings, err := client.GetLatestReadings(context.Background(), "smiths")
+	if err != nil {
+		t.Errorf("expected no error but got %v", err)
+	}
+	if len(readings) != 2 {
+		t.Fatalf("expected 2 readings but got %v", readings)
+	}
+	reading := readings[0]
+	if reading.DeviceID != 1 || !reading.Time.Equal(at) || reading.IndoorTemperature != 21.5 || reading.IndoorHumidity != 55 ||
+		reading.IndoorDewpoint != 12.1 || reading.OutdoorDewpoint == nil || *reading.OutdoorDewpoint != 8 ||
+		reading.DewpointDelta == nil || *reading.DewpointDelta != 4.1 || !reading.OpenWindows {
+		t.Errorf("unexpected reading %+v", reading)
+	}
+	// readings stored while the outdoor dew point was unknown
+	if readings[1].OutdoorDewpoint != nil || readings[1].DewpointDelta != nil {
+		t.Errorf("expected unknown outdoor values but got %+v", readings[1])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetLatestReadings_Error(t *testing.T) {
+	// setup mock
+	client, mock := setupTestDB(t)
+
+	// setup test
+	mock.ExpectQuery(`SELECT DISTINCT ON \(device_id\)`).
+		WillReturnError(errors.New("query error"))
+
+	_, err := client.GetLatestReadings(context.Background(), "smiths")
+	if err == nil || err.Error() != "failed to retrieve latest readings: query error" {
+		t.Errorf("expected failed to retrieve latest readings: query error but got %v", err)
+	}
+}
+
+func TestGetHistory_Success(t *testing.T) {
+	// setup mock
+	client, mock := setupTestDB(t)
+
+	// setup test
+	since := time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+	first := since.Add(5 * time.Minute)
+
+	mock.ExpectQuery(`SELECT time_bucket\(\$1::interval, time\) AS bucket, AVG\(indoor_temperature\) AS indoor_temperature, .* FROM "data" WHERE tenant_id = \$2 AND device_id = \$3 AND time >= \$4 GROUP BY "bucket" ORDER BY bucket`).
+		WithArgs("300 seconds", "smiths", uint64(7), since).
+		WillReturnRows(sqlmock.NewRows([]string{"bucket", "indoor_temperature", "indoor_humidity",
+			"indoor_dewpoint", "outdoor_dewpoint"}).
+			AddRow(since, 18.5, 68.0, 12.5, 6.0).
+			AddRow(first, 18.4, 69.0, 12.6, 6.1))
+
+	points, err := client.GetHistory(context.Background(), "smiths", 7, since, 5*time.Minute)
+	if err != nil {
+		t.Errorf("expected no error but got %v", err)
+	}
+	if len(points) != 2 || !points[1].Time.Equal(first) || points[1].IndoorHumidity != 69 {
+		t.Errorf("expected 2 points ending at %v but got %v", first, points)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetHistory_Error(t *testing.T) {
+	// setup mock
+	client, mock := setupTestDB(t)
+
+	// setup test
+	mock.ExpectQuery(`SELECT time_bucket`).
+		WillReturnError(errors.New("query error"))
+
+	_, err := client.GetHistory(context.Background(), "smiths", 7, time.Now(), time.Hour)
+	if err == nil || err.Error() != "failed to retrieve history: query error" {
+		t.Errorf("expected failed to retrieve history: query error but got %v", err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm DB: %v", err)
+	}
+	client := &clientImpl{db: gormDB}
+
+	mock.ExpectClose().WillReturnError(errors.New("connection reset"))
+	err = client.Close()
+	expected := "failed to close database: connection reset"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/mugglemath/dewdrop-go/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const (
+	tracerName = "github.com/mugglemath/go-dew/internal/db"
+	spanKey    = "tracing:span"
+)
+
+// queryTracing is a gorm plugin starting a client span for every statement
+type queryTracing struct{}
+
+func (queryTracing) Name() string {
+	return "tracing"
+}
+
+func (queryTracing) Initialize(db *gorm.DB) error {
+	c := db.Callback()
+	return errors.Join(
+		c.Create().Before("gorm:create").Register("tracing:before_create", startSpan("create")),
+		c.Create().After("gorm:create").Register("tracing:after_create", endSpan),
+		c.Query().Before("gorm:query").Register("tracing:before_query", startSpan("query")),
+		c.Query().After("gorm:query").Register("tracing:after_query", endSpan),
+		c.Update().Before("gorm:update").Register("tracing:before_update", startSpan("update")),
+		c.Update().After("gorm:update").Register("tracing:after_update", endSpan),
+		c.Delete().Before("gorm:delete").Register("tracing:before_delete", startSpan("delete")),
+		c.Delete().After("gorm:delete").Register("tracing:after_delete", endSpan),
+		c.Row().Before("gorm:row").Register("tracing:before_row", startSpan("row")),
+		c.Row().After("gorm:row").Register("tracing:after_row", endSpan),
+		c.Raw().Before("gorm:raw").Register("tracing:before_raw", startSpan("raw")),
+		c.Raw().After("gorm:raw").Register("tracing:after_raw", endSpan),
+	)
+}
+
+func startSpan(operation string) func(tx *gorm.DB) {
+	return func(tx *gorm.DB) {
+		name := operation
+		if tx.Statement.Table != "" {
+			name += " " + tx.Statement.Table
+		}
+		ctx, span := tracing.Start(tx.Statement.Context, tracerName, name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", "postgresql"),
+				attribute.String("db.operation", operation),
+				attribute.String("db.sql.table", tx.Statement.Table),
+			))
+		tx.Statement.Context = ctx
+		tx.InstanceSet(spanKey, span)
+	}
+}
+
+func endSpan(tx *gorm.DB) {
+	value, ok := tx.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	span.SetAttributes(attribute.String("db.statement", tx.Statement.SQL.String()))
+	err := tx.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// not found is an answer, not a failure
+		err = nil
+	}
+	tracing.End(span, err)
+}
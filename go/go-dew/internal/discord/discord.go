@@ -2,39 +2,59 @@ package discord
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"time"
+
+	"github.com/mugglemath/dewdrop-go/pkg/tracing"
+	"github.com/mugglemath/go-dew/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const tracerName = "github.com/mugglemath/go-dew/internal/discord"
+
 type Client interface {
-	SendSensorFeed(message string) error
-	SendWindowAlert(message string) error
-	SendHumidityAlert(message string) error
+	SendSensorFeed(ctx context.Context, message string) error
+	SendWindowAlert(ctx context.Context, message string) error
+	SendHumidityAlert(ctx context.Context, message string) error
+	SendDeviceAlert(ctx context.Context, message string) error
 	PanicHandler(debugStack string, req *http.Request)
+	Ping(ctx context.Context) error
+	SetConfig(config *Config)
 }
 
 type clientImpl struct {
-	config Config
+	config atomic.Pointer[Config]
 }
 
 type Config struct {
 	SensorFeedWebhook    string
 	WindowAlertWebhook   string
 	HumidityAlertWebhook string
+	DeviceAlertWebhook   string
 	DebugWebhook         string
 }
 
 func New(config *Config) Client {
-	return &clientImpl{
-		config: *config,
-	}
+	c := &clientImpl{}
+	c.SetConfig(config)
+	return c
+}
+
+// SetConfig switches to new webhooks, messages already being sent go to the old ones
+func (c *clientImpl) SetConfig(config *Config) {
+	copied := *config
+	c.config.Store(&copied)
 }
 
 func (c *clientImpl) PanicHandler(debugStack string, req *http.Request) {
@@ -44,45 +64,119 @@ func (c *clientImpl) PanicHandler(debugStack string, req *http.Request) {
 	req.Body = io.NopCloser(&buf)
 
 	fileContent := fmt.Sprintf("Method: %s\nURL: %s\nHeaders: %v\nBody: %s\nDebug Stack:\n%s",
-		req.Method, req.URL.String(), req.Header, string(body), debugStack)
+		req.Method, req.URL.String(), redactHeaders(req.Header), string(body), debugStack)
 
 	logDir := "./logs"
 	filePath, err := createFile(fileContent, logDir)
 	if err != nil {
-		log.Printf("Failed to create log file: %s", err)
-		log.Print(fileContent)
+		slog.Error("failed to create panic log file", "error", err, "details", fileContent)
 		return
 	}
 
 	message := fmt.Sprintf("Panic occurred! Method: %s, URL: %s", req.Method, req.URL.String())
 
-	err = sendMessageWithAttachment(c.config.DebugWebhook, message, filePath)
+	err = sendMessageWithAttachment(c.config.Load().DebugWebhook, message, filePath)
+	metrics.Notification("debug", err)
 	if err != nil {
-		log.Printf("Failed to send panic to debug channel: %s", err)
-		log.Print(fileContent)
+		slog.Error("failed to send panic to debug channel", "error", err, "details", fileContent)
+	}
+}
+
+// sensitiveHeaders carry credentials that must not end up in a debug channel
+var sensitiveHeaders = []string{"Authorization", "X-API-Key", "Cookie"}
+
+// redactHeaders returns a copy of header with the credentials replaced
+func redactHeaders(header http.Header) http.Header {
+	redacted := header.Clone()
+	for _, name := range sensitiveHeaders {
+		if redacted.Get(name) != "" {
+			redacted.Set(name, "[redacted]")
+		}
 	}
+	return redacted
 }
 
-func (c *clientImpl) SendSensorFeed(message string) error {
-	return sendMessage(c.config.SensorFeedWebhook, message)
+func (c *clientImpl) SendSensorFeed(ctx context.Context, message string) error {
+	return send(ctx, "sensor_feed", c.config.Load().SensorFeedWebhook, message)
 }
 
-func (c *clientImpl) SendWindowAlert(message string) error {
-	return sendMessage(c.config.WindowAlertWebhook, message)
+func (c *clientImpl) SendWindowAlert(ctx context.Context, message string) error {
+	return send(ctx, "window_alert", c.config.Load().WindowAlertWebhook, message)
 }
 
-func (c *clientImpl) SendHumidityAlert(message string) error {
-	return sendMessage(c.config.HumidityAlertWebhook, message)
+func (c *clientImpl) SendHumidityAlert(ctx context.Context, message string) error {
+	return send(ctx, "humidity_alert", c.config.Load().HumidityAlertWebhook, message)
 }
 
-func sendMessage(webHookURL string, message string) error {
+// SendDeviceAlert reports devices going offline and coming back
+func (c *clientImpl) SendDeviceAlert(ctx context.Context, message string) error {
+	return send(ctx, "device_alert", c.config.Load().DeviceAlertWebhook, message)
+}
+
+// send posts a message to a channel, recording a span and the delivery metric
+func send(ctx context.Context, channel, webHookURL, message string) (err error) {
+	ctx, span := tracing.Start(ctx, tracerName, "discord.send", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("discord.channel", channel)))
+	defer func() { tracing.End(span, err) }()
+
+	err = sendMessage(ctx, webHookURL, message)
+	metrics.Notification(channel, err)
+	return err
+}
+
+// Ping checks that every configured webhook exists. A GET on a webhook returns its
+// details without posting anything to the channel.
+func (c *clientImpl) Ping(ctx context.Context) error {
+	config := c.config.Load()
+	webhooks := []struct{ channel, url string }{
+		{"sensor_feed", config.SensorFeedWebhook},
+		{"window_alert", config.WindowAlertWebhook},
+		{"humidity_alert", config.HumidityAlertWebhook},
+		{"device_alert", config.DeviceAlertWebhook},
+		{"debug", config.DebugWebhook},
+	}
+	var errs []error
+	for _, webhook := range webhooks {
+		if webhook.url == "" {
+			continue
+		}
+		if err := pingWebhook(ctx, webhook.url); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", webhook.channel, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func pingWebhook(ctx context.Context, webHookURL string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, webHookURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func sendMessage(ctx context.Context, webHookURL string, message string) error {
 	data := map[string]string{"content": message}
 	jsonBytes, err := json.Marshal(data)
 	if err != nil {
 		return fmt.Errorf("failed to marshal message data: %w", err)
 	}
 
-	resp, err := http.Post(webHookURL, "application/json", bytes.NewBuffer(jsonBytes))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webHookURL, bytes.NewBuffer(jsonBytes))
+	if err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to send message: %w", err)
 	}
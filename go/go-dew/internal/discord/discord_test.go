@@ -1,9 +1,12 @@
 package discord
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -31,7 +34,7 @@ func TestSendSensorFeed_Success(t *testing.T) {
 	}
 	client := New(config)
 
-	err := client.SendSensorFeed("Test sensor feed message")
+	err := client.SendSensorFeed(context.Background(), "Test sensor feed message")
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -46,7 +49,7 @@ func TestSendWindowAlert_Success(t *testing.T) {
 	}
 	client := New(config)
 
-	err := client.SendWindowAlert("Test window alert message")
+	err := client.SendWindowAlert(context.Background(), "Test window alert message")
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -61,7 +64,22 @@ func TestSendHumidityAlert_Success(t *testing.T) {
 	}
 	client := New(config)
 
-	err := client.SendHumidityAlert("Test humidity alert message")
+	err := client.SendHumidityAlert(context.Background(), "Test humidity alert message")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+func TestSendDeviceAlert_Success(t *testing.T) {
+	mockServer := setupMockServer(http.StatusNoContent, "")
+	defer mockServer.Close()
+
+	config := &Config{
+		DeviceAlertWebhook: mockServer.URL,
+	}
+	client := New(config)
+
+	err := client.SendDeviceAlert(context.Background(), "Test device alert message")
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -76,7 +94,7 @@ func TestSendSensorFeed_BadRequest(t *testing.T) {
 	}
 	client := New(config)
 
-	err := client.SendSensorFeed("Test sensor feed message")
+	err := client.SendSensorFeed(context.Background(), "Test sensor feed message")
 	if err == nil {
 		t.Fatal("Expected an error but got none")
 	}
@@ -91,7 +109,7 @@ func TestSendWindowAlert_InternalServerError(t *testing.T) {
 	}
 	client := New(config)
 
-	err := client.SendWindowAlert("Test window alert message")
+	err := client.SendWindowAlert(context.Background(), "Test window alert message")
 	if err == nil {
 		t.Fatal("Expected an error but got none")
 	}
@@ -107,7 +125,7 @@ func TestSendHumidityAlert_MalformedJSON(t *testing.T) {
 	}
 	client := New(config)
 
-	err := client.SendHumidityAlert("Test humidity alert message")
+	err := client.SendHumidityAlert(context.Background(), "Test humidity alert message")
 	if err == nil {
 		t.Error("Expected an error due to malformed JSON but got none")
 	}
@@ -119,8 +137,84 @@ func TestSendSensorFeed_EmptyWebhookURL(t *testing.T) {
 	}
 	client := New(config)
 
-	err := client.SendSensorFeed("Test sensor feed message")
+	err := client.SendSensorFeed(context.Background(), "Test sensor feed message")
 	if err == nil {
 		t.Error("Expected an error due to empty webhook URL but got none")
 	}
 }
+
+func TestPing_Success(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("Expected GET method, got %s", r.Method)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer mockServer.Close()
+
+	config := &Config{
+		SensorFeedWebhook:  mockServer.URL,
+		WindowAlertWebhook: mockServer.URL,
+	}
+	client := New(config)
+
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+func TestPing_UnknownWebhook(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer mockServer.Close()
+
+	config := &Config{
+		WindowAlertWebhook: mockServer.URL,
+	}
+	client := New(config)
+
+	err := client.Ping(context.Background())
+	expected := "window_alert: webhook returned status 404"
+	if err == nil || !strings.Contains(err.Error(), expected) {
+		t.Errorf("Expected error containing %q, got %v", expected, err)
+	}
+}
+
+func TestSetConfig(t *testing.T) {
+	oldServer := setupMockServer(http.StatusInternalServerError, "")
+	defer oldServer.Close()
+	newServer := setupMockServer(http.StatusNoContent, "")
+	defer newServer.Close()
+
+	client := New(&Config{
+		WindowAlertWebhook: oldServer.URL,
+	})
+	client.SetConfig(&Config{
+		WindowAlertWebhook: newServer.URL,
+	})
+
+	err := client.SendWindowAlert(context.Background(), "Test window alert message")
+	if err != nil {
+		t.Fatalf("Expected the new webhook to be used, got %v", err)
+	}
+}
+
+func TestRedactHeaders(t *testing.T) {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer secret-key")
+	header.Set("X-API-Key", "secret-key")
+	header.Set("Cookie", "session=secret")
+	header.Set("X-Request-ID", "poll-1")
+
+	redacted := redactHeaders(header)
+	if strings.Contains(fmt.Sprint(redacted), "secret") {
+		t.Errorf("Expected the credentials to be redacted, got %v", redacted)
+	}
+	if redacted.Get("X-Request-ID") != "poll-1" {
+		t.Errorf("Expected the other headers to be kept, got %v", redacted)
+	}
+	if header.Get("X-API-Key") != "secret-key" {
+		t.Errorf("Expected the request's headers to be left alone, got %v", header)
+	}
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/mugglemath/dewdrop-go/pkg/calculations"
+	"github.com/mugglemath/dewdrop-go/pkg/logging"
+	"github.com/mugglemath/go-dew/internal/model"
+)
+
+// condensationHysteresis keeps a margin hovering around the threshold from alerting repeatedly
+const condensationHysteresis = 1.0
+
+// checkCondensation alerts once when the inner glass surface of the device's room, at
+// the outdoor temperature of location l, gets within CondensationMargin of the indoor
+// dew point, and again only after it recovered
+func (h *handlerImpl) checkCondensation(ctx context.Context, tenant *TenantConfig, data model.SensorData, l *location) {
+	logger := logging.FromContext(ctx)
+	uValue, ok := tenant.WindowUValues[data.DeviceID]
+	if !ok {
+		uValue = tenant.DefaultWindowUValue
+	}
+	outdoor := l.outdoorDewPoint.Load()
+	if uValue == 0 || outdoor == nil {
+		return
+	}
+
+	risk, err := calculations.WindowCondensationRisk(data.IndoorTemperature, data.IndoorHumidity,
+		outdoor.Temperature, uValue)
+	if err != nil {
+		logger.Warn("failed to estimate condensation risk", "device_id", data.DeviceID, "error", err)
+		return
+	}
+
+	h.condensationMu.Lock()
+	defer h.condensationMu.Unlock()
+
+	alerted := h.condensationAlerts[data.DeviceID]
+	switch {
+	case !alerted && risk.Margin < tenant.CondensationMargin:
+		h.condensationAlerts[data.DeviceID] = true
+		message := data.CondensationAlertMessage(risk, outdoor.Temperature, uValue, tenant.Units.WindowAlert)
+		h.notify(func() {
+			if err := h.notifier(tenant.ID).SendWindowAlert(context.WithoutCancel(ctx), message); err != nil {
+				logger.Error("failed to send condensation alert to Discord", "error", err)
+			}
+		})
+	case alerted && risk.Margin > tenant.CondensationMargin+condensationHysteresis:
+		h.condensationAlerts[data.DeviceID] = false
+	}
+}
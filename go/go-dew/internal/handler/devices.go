@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mugglemath/dewdrop-go/pkg/logging"
+	"github.com/mugglemath/go-dew/internal/metrics"
+	"github.com/mugglemath/go-dew/internal/model"
+)
+
+const deviceCheckInterval = time.Minute
+
+// HandleDevices returns the last seen status of every device of the household, or of one
+// with /devices/:device_id
+func (h *handlerImpl) HandleDevices(ctx *gin.Context) {
+	tenant := h.requestTenant(ctx)
+	if param := ctx.Param("device_id"); param != "" {
+		deviceID, err := strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid device id"})
+			return
+		}
+		device, ok := h.heartbeat.Device(deviceID)
+		if !ok || h.deviceTenant(deviceID).ID != tenant.ID {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "unknown device"})
+			return
+		}
+		ctx.JSON(http.StatusOK, device)
+		return
+	}
+	devices := h.heartbeat.Devices()
+	devices = slices.DeleteFunc(devices, func(device model.DeviceStatus) bool {
+		return h.deviceTenant(device.DeviceID).ID != tenant.ID
+	})
+	ctx.JSON(http.StatusOK, devices)
+}
+
+// MonitorDevices restores the last seen times of every household from the database,
+// then alerts on devices going silent until ctx is cancelled
+func (h *handlerImpl) MonitorDevices(ctx context.Context) {
+	now := time.Now()
+	for _, tenant := range h.tenants {
+		devices, err := h.dbClient.GetLastSeen(ctx, tenant.ID)
+		if err != nil {
+			slog.Error("failed to restore last seen times", "tenant", tenant.ID, "error", err)
+		}
+		for _, device := range devices {
+			h.heartbeat.Restore(device.DeviceID, device.LastSeen, now)
+		}
+	}
+	for _, device := range h.heartbeat.Devices() {
+		metrics.SetDeviceOnline(device.DeviceID, device.Online)
+	}
+
+	ticker := time.NewTicker(deviceCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			h.checkDevices(ctx, now)
+		}
+	}
+}
+
+func (h *handlerImpl) checkDevices(ctx context.Context, now time.Time) {
+	for _, device := range h.heartbeat.Check(now) {
+		slog.Warn("device offline", "device_id", device.DeviceID, "last_seen", device.LastSeen)
+		metrics.SetDeviceOnline(device.DeviceID, false)
+		h.notify(func() {
+			discordClient := h.notifier(h.deviceTenant(device.DeviceID).ID)
+			if err := discordClient.SendDeviceAlert(context.WithoutCancel(ctx), device.OfflineMessage()); err != nil {
+				slog.Error("failed to send device alert to Discord", "error", err)
+			}
+		})
+	}
+}
+
+// deviceSeen records a reading and sends a recovery message if the device was offline
+func (h *handlerImpl) deviceSeen(ctx context.Context, deviceID uint64, now time.Time) {
+	metrics.SetDeviceOnline(deviceID, true)
+	recovered := h.heartbeat.Seen(deviceID, now)
+	if recovered == nil {
+		return
+	}
+	logger := logging.FromContext(ctx)
+	logger.Info("device back online", "device_id", deviceID, "offline_since", recovered.OfflineSince)
+	h.notify(func() {
+		discordClient := h.notifier(h.deviceTenant(deviceID).ID)
+		if err := discordClient.SendDeviceAlert(context.WithoutCancel(ctx), recovered.RecoveryMessage(now)); err != nil {
+			logger.Error("failed to send device alert to Discord", "error", err)
+		}
+	})
+}
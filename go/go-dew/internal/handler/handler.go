@@ -3,90 +3,369 @@ package handler
 import (
 	"context"
 	"fmt"
-	"log"
 	"net/http"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mugglemath/dewdrop-go/pkg/logging"
+	"github.com/mugglemath/dewdrop-go/pkg/units"
 	"github.com/mugglemath/go-dew/internal/db"
 	"github.com/mugglemath/go-dew/internal/discord"
+	"github.com/mugglemath/go-dew/internal/heartbeat"
+	"github.com/mugglemath/go-dew/internal/metrics"
 	"github.com/mugglemath/go-dew/internal/model"
-	"github.com/mugglemath/go-dew/internal/weather"
+	"github.com/mugglemath/go-dew/internal/mold"
+	"golang.org/x/sync/singleflight"
 )
 
 type Handler interface {
 	HandleOutdoorDewpoint(ctx *gin.Context)
+	HandleOutdoorConditions(ctx *gin.Context)
 	HandleSensorData(ctx *gin.Context)
+	HandleMoldIndex(ctx *gin.Context)
+	HandleHealthz(ctx *gin.Context)
+	HandleReadyz(ctx *gin.Context)
+	HandleDevices(ctx *gin.Context)
+	HandleReadings(ctx *gin.Context)
+	Authenticate(ctx *gin.Context)
+	Notifier(ctx *gin.Context) discord.Client
+	MonitorDevices(ctx context.Context)
 	UpdateOutdoorDewPoint(ctx context.Context)
 	Initialize(ctx context.Context) error
+	Shutdown(ctx context.Context) error
+	SetConfig(config *Config)
 }
 
 type handlerImpl struct {
-	dbClient        db.Client
-	discordClient   discord.Client
-	weatherClient   weather.Client
-	outdoorDewPoint atomic.Pointer[DewPoint]
+	dbClient db.Client
+	// tenants holds the Discord client of each household, the first is the default
+	tenants []Tenant
+	// locations holds the outdoor conditions of each location, the first is the default
+	locations []*location
+	settings  atomic.Pointer[Config]
+	moldMu    sync.Mutex
+
+	condensationMu     sync.Mutex
+	condensationAlerts map[uint64]bool
+
+	heartbeat *heartbeat.Monitor
+
+	started           time.Time
+	notifierMu        sync.Mutex
+	notifierCheckedAt time.Time
+	notifierErr       error
+	notifierPing      singleflight.Group
+
+	// notifications are sent after the response, Shutdown waits for them
+	pending sync.WaitGroup
+}
+
+// Config holds the tunables of the handler, the zero value uses the defaults
+type Config struct {
+	// Tenants holds the households, the first is the default one. It has the devices and
+	// locations no other household lists.
+	Tenants []TenantConfig
+
+	// DeviceLocations maps device IDs to the name of their location, devices not listed
+	// use the default location of their household
+	DeviceLocations map[uint64]string
+
+	// OfflineAfter is how long a device may stay silent before it is reported offline
+	OfflineAfter time.Duration
+
+	// Version is reported by the health endpoints
+	Version string
+}
+
+// TenantConfig holds the API keys, devices and alert rules of a household
+type TenantConfig struct {
+	ID string
+	// APIKeys authenticate the household's requests
+	APIKeys []string
+	Devices []uint64
+	// Locations names the household's locations, the first is the default for its devices
+	Locations []string
+
+	MoldModel      mold.Model
+	MoldAlertIndex float64
+
+	// WindowUValues maps device IDs to the U-value of the windows in their room in W/(m²K),
+	// devices not listed use DefaultWindowUValue and are skipped if that is 0
+	WindowUValues       map[uint64]float64
+	DefaultWindowUValue float64
+	// CondensationMargin is how close in C the glass may get to the dew point before alerting
+	CondensationMargin float64
+
+	Units ChannelUnits
+}
+
+// ChannelUnits selects the unit system of each Discord channel, and of API responses
+// that don't ask for one with the units query parameter
+type ChannelUnits struct {
+	SensorFeed  units.System
+	WindowAlert units.System
+	API         units.System
 }
 
 type DewPoint struct {
-	Value      float64
-	LastUpdate time.Time
+	Value       float64
+	Temperature float64
+	LastUpdate  time.Time
 }
 
 const (
 	humidityAlertThreshold = 60.0
 	updateInterval         = 15 * time.Minute
+	defaultMoldAlertIndex  = 1.0
+
+	defaultCondensationMargin = 2.0
+	defaultOfflineAfter       = 10 * time.Minute
 )
 
-func New(dbClient db.Client, discordClient discord.Client, weatherClient weather.Client) Handler {
-	return &handlerImpl{
-		dbClient:      dbClient,
-		discordClient: discordClient,
-		weatherClient: weatherClient,
+// New serves the households and locations, at least one of each is required
+func New(dbClient db.Client, tenants []Tenant, locations []Location, config *Config) Handler {
+	h := &handlerImpl{
+		dbClient: dbClient,
+		tenants:  tenants,
+
+		condensationAlerts: map[uint64]bool{},
+		heartbeat:          heartbeat.New(defaultOfflineAfter),
+		started:            time.Now(),
 	}
+	for _, l := range locations {
+		h.locations = append(h.locations, &location{name: l.Name, weatherClient: l.Weather})
+	}
+	h.SetConfig(config)
+	return h
 }
 
+// SetConfig replaces the households, alert rules and units, requests already running finish
+// with the old ones
+func (h *handlerImpl) SetConfig(config *Config) {
+	var c Config
+	if config != nil {
+		c = *config
+	}
+	if len(c.Tenants) == 0 {
+		c.Tenants = []TenantConfig{{ID: h.tenants[0].ID}}
+	}
+	c.Tenants = h.knownTenants(c.Tenants)
+	for i := range c.Tenants {
+		t := &c.Tenants[i]
+		if t.MoldModel == (mold.Model{}) {
+			t.MoldModel = mold.Pine
+		}
+		if t.MoldAlertIndex == 0 {
+			t.MoldAlertIndex = defaultMoldAlertIndex
+		}
+		if t.CondensationMargin == 0 {
+			t.CondensationMargin = defaultCondensationMargin
+		}
+	}
+	if c.OfflineAfter == 0 {
+		c.OfflineAfter = defaultOfflineAfter
+	}
+	h.heartbeat.SetOfflineAfter(c.OfflineAfter)
+	h.warnUnknownLocations(&c)
+	h.settings.Store(&c)
+}
+
+func (h *handlerImpl) config() *Config {
+	return h.settings.Load()
+}
+
+// Initialize fetches the outdoor conditions of every location once. Without them go-dew
+// still starts, degraded, and keeps storing readings while the weather is retried in
+// the background.
 func (h *handlerImpl) Initialize(ctx context.Context) error {
-	return h.updateOutdoorDewPoint(ctx)
+	for _, l := range h.locations {
+		conditions, err := l.weatherClient.GetOutdoorConditions(ctx)
+		metrics.WeatherFetch(l.name, err, time.Now())
+		if err != nil {
+			logging.FromContext(ctx).Warn("starting without outdoor conditions", "location", l.name, "error", err)
+			continue
+		}
+		l.storeOutdoorConditions(conditions)
+	}
+	return nil
 }
 
-// UpdateOutdoorDewPoint asynchronously updates dewPoint if value is stale
+// Shutdown waits for pending notifications until ctx is done. Call it once no more
+// requests are served and MonitorDevices has returned.
+func (h *handlerImpl) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		h.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("gave up waiting for notifications: %w", ctx.Err())
+	}
+}
+
+// notify runs send in the background, where it doesn't hold up the response
+func (h *handlerImpl) notify(send func()) {
+	h.pending.Add(1)
+	go func() {
+		defer h.pending.Done()
+		send()
+	}()
+}
+
+// UpdateOutdoorDewPoint asynchronously updates the dewPoint of every location whose
+// value is stale or missing
 func (h *handlerImpl) UpdateOutdoorDewPoint(ctx context.Context) {
-	if time.Now().After(h.outdoorDewPoint.Load().LastUpdate.Add(updateInterval)) {
-		go func() {
-			_ = h.updateOutdoorDewPoint(ctx)
-		}()
+	for _, l := range h.locations {
+		h.refreshOutdoor(ctx, l)
+	}
+}
+
+// outdoorConditions answers 503 until the location's weather has been fetched once
+func (h *handlerImpl) outdoorConditions(ctx *gin.Context, l *location) (*DewPoint, bool) {
+	outdoor := l.outdoorDewPoint.Load()
+	if outdoor == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "outdoor conditions not available yet"})
+		return nil, false
 	}
+	return outdoor, true
 }
 
 // HandleOutdoorDewpoint may return a stale value up to twice the call interval
-// (e.g. 2 minutes if called every 1 minute)
+// (e.g. 2 minutes if called every 1 minute). Without a units parameter it returns
+// the bare value in C that dewdrop expects. The location parameter selects one of
+// the household's locations, its default one otherwise.
 func (h *handlerImpl) HandleOutdoorDewpoint(ctx *gin.Context) {
-	h.UpdateOutdoorDewPoint(ctx)
-	dewPoint := h.outdoorDewPoint.Load().Value
-	ctx.JSON(http.StatusOK, dewPoint)
+	tenant := h.requestTenant(ctx)
+	l, ok := h.requestLocation(ctx, tenant)
+	if !ok {
+		return
+	}
+	h.refreshOutdoor(ctx.Request.Context(), l)
+	outdoor, ok := h.outdoorConditions(ctx, l)
+	if !ok {
+		return
+	}
+	dewPoint := outdoor.Value
+	if _, ok := ctx.GetQuery("units"); !ok {
+		ctx.JSON(http.StatusOK, dewPoint)
+		return
+	}
+
+	u, ok := h.requestUnits(ctx, tenant)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"location":         l.name,
+		"dewpoint":         u.Temperature(dewPoint),
+		"temperature_unit": u.TemperatureUnit(),
+	})
+}
+
+// HandleOutdoorConditions returns the cached outdoor temperature and dew point of the
+// location parameter, or the household's default location
+func (h *handlerImpl) HandleOutdoorConditions(ctx *gin.Context) {
+	tenant := h.requestTenant(ctx)
+	l, ok := h.requestLocation(ctx, tenant)
+	if !ok {
+		return
+	}
+	h.refreshOutdoor(ctx.Request.Context(), l)
+	u, ok := h.requestUnits(ctx, tenant)
+	if !ok {
+		return
+	}
+	outdoor, ok := h.outdoorConditions(ctx, l)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"location":         l.name,
+		"temperature":      u.Temperature(outdoor.Temperature),
+		"dewpoint":         u.Temperature(outdoor.Value),
+		"temperature_unit": u.TemperatureUnit(),
+		"units":            u.String(),
+		"last_update":      outdoor.LastUpdate,
+	})
+}
+
+// requestUnits reads the units query parameter, falling back to the household's default
+func (h *handlerImpl) requestUnits(ctx *gin.Context, tenant *TenantConfig) (units.System, bool) {
+	value := ctx.Query("units")
+	if value == "" {
+		return tenant.Units.API, true
+	}
+	u, err := units.Parse(value)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return u, false
+	}
+	return u, true
 }
 
 func (h *handlerImpl) HandleSensorData(ctx *gin.Context) {
+	logger := logging.FromContext(ctx)
+	// notifications are sent after the response, keep the trace but not the cancellation
+	background := context.WithoutCancel(ctx.Request.Context())
 	var data model.SensorData
 	if err := ctx.ShouldBindJSON(&data); err != nil {
+		metrics.Ingest("invalid")
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	tenant := h.requestTenant(ctx)
+	if owner := h.deviceTenant(data.DeviceID); owner.ID != tenant.ID {
+		metrics.Ingest("forbidden")
+		logger.Warn("rejected a reading of another household's device", "device_id", data.DeviceID,
+			"tenant", tenant.ID)
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "device belongs to another household"})
+		return
+	}
+	discordClient := h.notifier(tenant.ID)
+	h.deviceSeen(ctx.Request.Context(), data.DeviceID, time.Now())
+	logger = logger.With("device_id", data.DeviceID, "tenant", tenant.ID)
+
+	// the delta is taken against the outdoor dew point of the device's own location,
+	// which the device may not have asked for
+	l := h.deviceLocation(tenant, data.DeviceID)
+	h.refreshOutdoor(ctx.Request.Context(), l)
+	if outdoor := l.outdoorDewPoint.Load(); outdoor != nil {
+		value, delta := outdoor.Value, data.IndoorDewpoint-outdoor.Value
+		data.OutdoorDewpoint, data.DewpointDelta = &value, &delta
+	}
+
+	// the stored advice has to match the stored outdoor dew point, which may not be the one
+	// the device decided on. Without any outdoor dew point the device's advice is kept.
+	if advice, err := data.VentilationAdvice(); err == nil && advice.OpenWindows != data.OpenWindows {
+		logger.Info("device and server advice differ, storing the server's", "device_open_windows", data.OpenWindows,
+			"server_open_windows", advice.OpenWindows, "reason", advice.Reason)
+		data.OpenWindows = advice.OpenWindows
+	}
+	metrics.SetReading(data.DeviceID, data.IndoorTemperature, data.IndoorHumidity, data.IndoorDewpoint,
+		data.OpenWindows, time.Now())
 
 	// if database is empty, initialize it
-	empty, err := h.dbClient.CheckForEmptyTable(ctx, "data")
+	empty, err := h.dbClient.CheckForEmptyTable(ctx, tenant.ID, "data")
 	if err != nil {
+		metrics.Ingest("db_error")
+		logger.Error("failed to check row count", "error", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check row count"})
 		return
 	}
 
 	if empty {
-		if err := h.dbClient.InsertSensorFeedData(ctx, data); err != nil {
+		if err := h.dbClient.InsertSensorFeedData(ctx, tenant.ID, data); err != nil {
+			metrics.Ingest("db_error")
+			logger.Error("failed to initialize database with initial row", "error", err)
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to initialize database with initial row"})
 			return
 		}
+		metrics.Ingest("initialized")
 		ctx.JSON(http.StatusOK, gin.H{"status": "success", "message": "Database initialized with first entry", "data": data})
 		return
 	}
@@ -94,78 +373,73 @@ func (h *handlerImpl) HandleSensorData(ctx *gin.Context) {
 	// send Discord feed if it's time
 	now := time.Now()
 	if now.Minute() == 0 {
-		go func() {
-			if err := h.discordClient.SendSensorFeed(data.FeedMessage()); err != nil {
-				log.Println("failed to send data to Discord feed")
+		h.notify(func() {
+			if err := discordClient.SendSensorFeed(background, data.FeedMessage(tenant.Units.SensorFeed)); err != nil {
+				logger.Error("failed to send data to Discord feed", "error", err)
 			}
-		}()
+		})
 	}
 
 	// handle window alert with discord
 	currentOpenWindows := data.OpenWindows
-	lastOpenWindows, err := h.dbClient.GetLastOpenWindowsValue(ctx)
+	lastOpenWindows, err := h.dbClient.GetLastOpenWindowsValue(ctx, tenant.ID, data.DeviceID)
 	if err != nil {
-		fmt.Printf("failed to get last keep windows value: ")
+		metrics.Ingest("db_error")
+		logger.Error("failed to get last open windows value", "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get last open windows value"})
 		return
 	}
 	if currentOpenWindows != lastOpenWindows {
-		go func() {
-			if err := h.discordClient.SendSensorFeed(data.FeedMessage()); err != nil {
-				log.Println("failed to send sensor feed to Discord")
+		h.notify(func() {
+			if err := discordClient.SendSensorFeed(background, data.FeedMessage(tenant.Units.SensorFeed)); err != nil {
+				logger.Error("failed to send sensor feed to Discord", "error", err)
 			}
-		}()
-		go func() {
-			if err := h.discordClient.SendWindowAlert(data.WindowAlertMessage()); err != nil {
-				log.Println("failed to send window alert to Discord")
+		})
+		h.notify(func() {
+			if err := discordClient.SendWindowAlert(background, data.WindowAlertMessage(tenant.Units.WindowAlert)); err != nil {
+				logger.Error("failed to send window alert to Discord", "error", err)
 			}
-		}()
+		})
 	}
 
 	// handle humidity alert with discord
 	if data.IndoorHumidity > humidityAlertThreshold {
-		recentHumidityAlert, err := h.dbClient.CheckRecentHumidityAlert(ctx)
-		fmt.Printf("recent humidity alert: %t", recentHumidityAlert)
+		recentHumidityAlert, err := h.dbClient.CheckRecentHumidityAlert(ctx, tenant.ID, data.DeviceID)
 		if err != nil {
-			log.Printf("failed to check recent humidity alert: %s", err)
+			metrics.Ingest("db_error")
+			logger.Error("failed to check recent humidity alert", "error", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check recent humidity alert"})
 			return
 		}
+		logger.Debug("checked recent humidity alert", "recent_humidity_alert", recentHumidityAlert)
 		if !recentHumidityAlert {
-			go func() {
-				if err := h.discordClient.SendSensorFeed(data.FeedMessage()); err != nil {
-					log.Println("failed to send sensor feed to Discord")
+			h.notify(func() {
+				if err := discordClient.SendSensorFeed(background, data.FeedMessage(tenant.Units.SensorFeed)); err != nil {
+					logger.Error("failed to send sensor feed to Discord", "error", err)
 				}
-			}()
+			})
 
-			go func() {
-				if err := h.discordClient.SendHumidityAlert(data.HumidityAlertMessage()); err != nil {
-					log.Println("failed to send humidity alert to Discord")
+			h.notify(func() {
+				if err := discordClient.SendHumidityAlert(background, data.HumidityAlertMessage()); err != nil {
+					logger.Error("failed to send humidity alert to Discord", "error", err)
 				}
-			}()
+			})
 		}
 	}
 
-	if err := h.dbClient.InsertSensorFeedData(ctx, data); err != nil {
+	if err := h.dbClient.InsertSensorFeedData(ctx, tenant.ID, data); err != nil {
+		metrics.Ingest("db_error")
+		logger.Error("failed to insert sensor data", "error", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to insert data into ClickHouse"})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"status": "success", "message": "POST request received"})
-}
-
-// updateOutdoorDewPoint atomically updates dewPoint from National Weather Service
-func (h *handlerImpl) updateOutdoorDewPoint(ctx context.Context) (err error) {
-	for i := 0; i < 10; i++ {
-		var dewPoint DewPoint
-		dewPoint.Value, err = h.weatherClient.GetOutdoorDewPoint(ctx)
-		if err == nil {
-			dewPoint.LastUpdate = time.Now()
-			h.outdoorDewPoint.Store(&dewPoint)
-			break
-		}
-		time.Sleep(time.Second * 5)
+	if err := h.updateMoldIndex(ctx.Request.Context(), tenant, data, time.Now()); err != nil {
+		logger.Error("failed to update mold index", "error", err)
 	}
-	if h.outdoorDewPoint.Load() != nil && err == nil {
-		log.Println("Updated outdoor dew point cache value:", h.outdoorDewPoint.Load().Value)
-	}
-	return
+
+	h.checkCondensation(ctx.Request.Context(), tenant, data, l)
+
+	metrics.Ingest("ok")
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "message": "POST request received"})
 }
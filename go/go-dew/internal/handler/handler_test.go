@@ -0,0 +1,282 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mugglemath/go-dew/internal/db"
+	"github.com/mugglemath/go-dew/internal/discord"
+	"github.com/mugglemath/go-dew/internal/model"
+	"github.com/mugglemath/go-dew/internal/weather"
+)
+
+// fakeDB stubs the database, the methods it doesn't implement panic through the nil interface
+type fakeDB struct {
+	db.Client
+}
+
+func (fakeDB) Ping(ctx context.Context) error {
+	return nil
+}
+
+// memoryDB keeps the readings of each household in memory
+type memoryDB struct {
+	fakeDB
+	mu   sync.Mutex
+	rows map[string][]model.SensorData
+}
+
+func newMemoryDB() *memoryDB {
+	return &memoryDB{rows: map[string][]model.SensorData{}}
+}
+
+// Rows returns the readings stored for the household
+func (m *memoryDB) Rows(tenantID string) []model.SensorData {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]model.SensorData(nil), m.rows[tenantID]...)
+}
+
+func (m *memoryDB) InsertSensorFeedData(ctx context.Context, tenantID string, sensorData model.SensorData) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sensorData.TenantID = tenantID
+	m.rows[tenantID] = append(m.rows[tenantID], sensorData)
+	return nil
+}
+
+func (m *memoryDB) CheckForEmptyTable(ctx context.Context, tenantID, tableName string) (bool, error) {
+	return len(m.Rows(tenantID)) == 0, nil
+}
+
+func (m *memoryDB) GetLastOpenWindowsValue(ctx context.Context, tenantID string, deviceID uint64) (bool, error) {
+	var last bool
+	for _, row := range m.Rows(tenantID) {
+		if row.DeviceID == deviceID {
+			last = row.OpenWindows
+		}
+	}
+	return last, nil
+}
+
+func (m *memoryDB) CheckRecentHumidityAlert(ctx context.Context, tenantID string, deviceID uint64) (bool, error) {
+	for _, row := range m.Rows(tenantID) {
+		if row.DeviceID == deviceID && row.HumidityAlert {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memoryDB) GetLatestReadings(ctx context.Context, tenantID string) ([]model.StoredReading, error) {
+	var readings []model.StoredReading
+	latest := map[uint64]int{}
+	for _, row := range m.Rows(tenantID) {
+		reading := model.StoredReading{DeviceID: row.DeviceID, Time: time.Now(), IndoorTemperature: row.IndoorTemperature,
+			IndoorHumidity: row.IndoorHumidity, IndoorDewpoint: row.IndoorDewpoint, OutdoorDewpoint: row.OutdoorDewpoint,
+			DewpointDelta: row.DewpointDelta, OpenWindows: row.OpenWindows, HumidityAlert: row.HumidityAlert}
+		if i, ok := latest[row.DeviceID]; ok {
+			readings[i] = reading
+			continue
+		}
+		latest[row.DeviceID] = len(readings)
+		readings = append(readings, reading)
+	}
+	return readings, nil
+}
+
+func (m *memoryDB) GetHistory(ctx context.Context, tenantID string, deviceID uint64, since time.Time,
+	bucket time.Duration) ([]model.HistoryPoint, error) {
+	var points []model.HistoryPoint
+	for _, row := range m.Rows(tenantID) {
+		if row.DeviceID == deviceID {
+			points = append(points, model.HistoryPoint{Time: time.Now(), IndoorTemperature: row.IndoorTemperature,
+				IndoorHumidity: row.IndoorHumidity, IndoorDewpoint: row.IndoorDewpoint, OutdoorDewpoint: row.OutdoorDewpoint})
+		}
+	}
+	return points, nil
+}
+
+func (m *memoryDB) GetMoldState(ctx context.Context, tenantID string, deviceID uint64) (*model.MoldState, error) {
+	return nil, nil
+}
+
+func (m *memoryDB) SaveMoldState(ctx context.Context, tenantID string, state model.MoldState) error {
+	return nil
+}
+
+func (m *memoryDB) GetReadingsSince(ctx context.Context, tenantID string, deviceID uint64, since time.Time) ([]model.Reading, error) {
+	return nil, nil
+}
+
+// fakeDiscord records the messages sent to each household
+type fakeDiscord struct {
+	discord.Client
+	ping  func(ctx context.Context) error
+	pings atomic.Int32
+
+	mu       sync.Mutex
+	messages []string
+}
+
+func (d *fakeDiscord) record(message string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.messages = append(d.messages, message)
+	return nil
+}
+
+func (d *fakeDiscord) Messages() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.messages...)
+}
+
+func (d *fakeDiscord) SendSensorFeed(ctx context.Context, message string) error {
+	return d.record(message)
+}
+
+func (d *fakeDiscord) SendWindowAlert(ctx context.Context, message string) error {
+	return d.record(message)
+}
+
+func (d *fakeDiscord) SendHumidityAlert(ctx context.Context, message string) error {
+	return d.record(message)
+}
+
+func (d *fakeDiscord) SendDeviceAlert(ctx context.Context, message string) error {
+	return d.record(message)
+}
+
+func (d *fakeDiscord) Ping(ctx context.Context) error {
+	d.pings.Add(1)
+	if d.ping == nil {
+		return nil
+	}
+	return d.ping(ctx)
+}
+
+// fakeWeather reports fixed outdoor conditions
+type fakeWeather struct {
+	conditions weather.Conditions
+}
+
+func (w fakeWeather) GetOutdoorDewPoint(ctx context.Context) (float64, error) {
+	return w.conditions.Dewpoint, nil
+}
+
+func (w fakeWeather) GetOutdoorConditions(ctx context.Context) (weather.Conditions, error) {
+	return w.conditions, nil
+}
+
+// newTestRouter serves the household endpoints like cmd/server does
+func newTestRouter(h Handler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.ContextWithFallback = true
+	api := r.Group("/", h.Authenticate)
+	api.GET("/weather/outdoor-dewpoint", h.HandleOutdoorDewpoint)
+	api.GET("/weather/outdoor", h.HandleOutdoorConditions)
+	api.POST("/arduino/sensor-feed", h.HandleSensorData)
+	api.GET("/devices", h.HandleDevices)
+	api.GET("/devices/:device_id", h.HandleDevices)
+	api.GET("/readings", h.HandleReadings)
+	api.GET("/readings/:device_id", h.HandleReadings)
+	return r
+}
+
+// serve sends a request with the API key, if any, and a JSON body for POSTs
+func serve(r http.Handler, method, path, apiKey, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandleSensorData_StoresServerAdvice(t *testing.T) {
+	database := newMemoryDB()
+	h := New(database, []Tenant{{ID: "default", Discord: &fakeDiscord{}}},
+		[]Location{{Name: "default", Weather: fakeWeather{weather.Conditions{Temperature: 25, Dewpoint: 20}}}}, nil)
+	if err := h.Initialize(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	r := newTestRouter(h)
+
+	// the device decided on a much drier outdoor dew point than the location's
+	body := `{"device_id": 1, "indoor_temperature": 22, "indoor_humidity": 60, "indoor_dewpoint": 13.9,
+		"outdoor_dewpoint": 5, "dewpoint_delta": 8.9, "open_windows": true}`
+	if w := serve(r, http.MethodPost, "/arduino/sensor-feed", "", body); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if err := h.Shutdown(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	rows := database.Rows("default")
+	if len(rows) != 1 {
+		t.Fatalf("expected 1 stored reading, got %d", len(rows))
+	}
+	row := rows[0]
+	if row.OutdoorDewpoint == nil || *row.OutdoorDewpoint != 20 || row.OpenWindows {
+		t.Errorf("expected the location's dew point 20 with closed windows, got %v and open_windows %v",
+			row.OutdoorDewpoint, row.OpenWindows)
+	}
+}
+
+func TestHandleSensorData_KeepsDeviceAdviceWithoutOutdoor(t *testing.T) {
+	database := newMemoryDB()
+	h := New(database, []Tenant{{ID: "default", Discord: &fakeDiscord{}}}, []Location{{Name: "default"}}, nil).(*handlerImpl)
+	// the location has no weather client, keep the handler from refreshing it
+	h.locations[0].refreshing.Store(true)
+	r := newTestRouter(h)
+
+	body := `{"device_id": 1, "indoor_temperature": 22, "indoor_humidity": 60, "indoor_dewpoint": 13.9, "open_windows": true}`
+	if w := serve(r, http.MethodPost, "/arduino/sensor-feed", "", body); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	h.Shutdown(context.Background())
+
+	rows := database.Rows("default")
+	if len(rows) != 1 || rows[0].OutdoorDewpoint != nil || rows[0].DewpointDelta != nil || !rows[0].OpenWindows {
+		t.Errorf("expected the device's advice without outdoor values, got %+v", rows)
+	}
+}
+
+func TestHandleSensorData_StoresRawValues(t *testing.T) {
+	database := newMemoryDB()
+	h := New(database, []Tenant{{ID: "default", Discord: &fakeDiscord{}}},
+		[]Location{{Name: "default", Weather: fakeWeather{weather.Conditions{Temperature: 20, Dewpoint: 10}}}}, nil)
+	if err := h.Initialize(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	r := newTestRouter(h)
+
+	body := `{"device_id": 1, "indoor_temperature": 22, "indoor_humidity": 58, "indoor_dewpoint": 13.4,
+		"raw_indoor_temperature": 22.8, "raw_indoor_humidity": 62}`
+	if w := serve(r, http.MethodPost, "/arduino/sensor-feed", "", body); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	h.Shutdown(context.Background())
+
+	rows := database.Rows("default")
+	if len(rows) != 1 || rows[0].RawIndoorTemperature == nil || *rows[0].RawIndoorTemperature != 22.8 ||
+		rows[0].RawIndoorHumidity == nil || *rows[0].RawIndoorHumidity != 62 {
+		t.Fatalf("expected the raw values 22.8 and 62 to be stored, got %+v", rows)
+	}
+	if rows[0].IndoorTemperature != 22 || rows[0].IndoorHumidity != 58 {
+		t.Errorf("expected the corrected values 22 and 58 to be stored, got %+v", rows[0])
+	}
+}
@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// the weather cache is refreshed every updateInterval while requests come in
+	maxWeatherAge         = 4 * updateInterval
+	notifierCheckInterval = 5 * time.Minute
+	healthCheckTimeout    = 5 * time.Second
+)
+
+type healthCheck struct {
+	Status     string     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	AgeSeconds *float64   `json:"age_seconds,omitempty"`
+	CheckedAt  *time.Time `json:"checked_at,omitempty"`
+	// Locations holds the weather check of each location, if there are several
+	Locations map[string]healthCheck `json:"locations,omitempty"`
+}
+
+// HandleHealthz reports that the server is up and answering requests
+func (h *handlerImpl) HandleHealthz(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{
+		"status":         "ok",
+		"version":        h.config().Version,
+		"uptime_seconds": int64(time.Since(h.started).Seconds()),
+	})
+}
+
+// HandleReadyz checks the dependencies. An unreachable database makes the server
+// unavailable, while stale weather or unreachable Discord webhooks only degrade it.
+func (h *handlerImpl) HandleReadyz(ctx *gin.Context) {
+	checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	checks := map[string]healthCheck{
+		"database": h.checkDatabase(checkCtx),
+		"weather":  h.checkWeather(),
+		"notifier": h.checkNotifier(checkCtx),
+	}
+
+	status, code := "ready", http.StatusOK
+	for name, check := range checks {
+		if check.Status == "ok" {
+			continue
+		}
+		if name == "database" {
+			status, code = "unavailable", http.StatusServiceUnavailable
+			break
+		}
+		status = "degraded"
+	}
+
+	ctx.JSON(code, gin.H{
+		"status":  status,
+		"version": h.config().Version,
+		"checks":  checks,
+	})
+}
+
+func (h *handlerImpl) checkDatabase(ctx context.Context) healthCheck {
+	if err := h.dbClient.Ping(ctx); err != nil {
+		return healthCheck{Status: "error", Error: err.Error()}
+	}
+	return healthCheck{Status: "ok"}
+}
+
+// checkWeather reports the worst of the locations
+func (h *handlerImpl) checkWeather() healthCheck {
+	if len(h.locations) == 1 {
+		return checkOutdoor(h.locations[0])
+	}
+	overall := healthCheck{Status: "ok", Locations: map[string]healthCheck{}}
+	for _, l := range h.locations {
+		check := checkOutdoor(l)
+		overall.Locations[l.name] = check
+		if check.Status == "error" || (check.Status == "stale" && overall.Status == "ok") {
+			overall.Status = check.Status
+		}
+	}
+	return overall
+}
+
+func checkOutdoor(l *location) healthCheck {
+	outdoor := l.outdoorDewPoint.Load()
+	if outdoor == nil {
+		return healthCheck{Status: "error", Error: "no weather data yet"}
+	}
+	age := time.Since(outdoor.LastUpdate).Seconds()
+	if age > maxWeatherAge.Seconds() {
+		return healthCheck{Status: "stale", AgeSeconds: &age}
+	}
+	return healthCheck{Status: "ok", AgeSeconds: &age}
+}
+
+// checkNotifier reuses the last result for notifierCheckInterval to stay clear of
+// Discord's rate limits when probed often. Concurrent probes share one ping, which
+// runs outside the lock and isn't cut short when a prober gives up.
+func (h *handlerImpl) checkNotifier(ctx context.Context) healthCheck {
+	h.notifierMu.Lock()
+	result := notifierResult{checkedAt: h.notifierCheckedAt, err: h.notifierErr}
+	h.notifierMu.Unlock()
+
+	if time.Since(result.checkedAt) > notifierCheckInterval {
+		select {
+		case r := <-h.notifierPing.DoChan("ping", func() (any, error) { return h.pingNotifier(ctx), nil }):
+			result = r.Val.(notifierResult)
+		case <-ctx.Done():
+			return healthCheck{Status: "error", Error: ctx.Err().Error()}
+		}
+	}
+
+	if result.err != nil {
+		return healthCheck{Status: "error", Error: result.err.Error(), CheckedAt: &result.checkedAt}
+	}
+	return healthCheck{Status: "ok", CheckedAt: &result.checkedAt}
+}
+
+type notifierResult struct {
+	checkedAt time.Time
+	err       error
+}
+
+// pingNotifier pings the default household's webhooks and caches the result, unless the
+// ping timed out or was cancelled, which says nothing about Discord
+func (h *handlerImpl) pingNotifier(ctx context.Context) notifierResult {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), healthCheckTimeout)
+	defer cancel()
+
+	result := notifierResult{err: h.tenants[0].Discord.Ping(ctx), checkedAt: time.Now()}
+	if errors.Is(result.err, context.DeadlineExceeded) || errors.Is(result.err, context.Canceled) {
+		return result
+	}
+	h.notifierMu.Lock()
+	h.notifierCheckedAt, h.notifierErr = result.checkedAt, result.err
+	h.notifierMu.Unlock()
+	return result
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newHealthTestHandler(notifier *fakeDiscord) *handlerImpl {
+	return New(fakeDB{}, []Tenant{{ID: "default", Discord: notifier}}, []Location{{Name: "default"}}, nil).(*handlerImpl)
+}
+
+func TestCheckNotifier_SharesOnePing(t *testing.T) {
+	release := make(chan struct{})
+	notifier := &fakeDiscord{ping: func(ctx context.Context) error {
+		<-release
+		return nil
+	}}
+	h := newHealthTestHandler(notifier)
+
+	var wg sync.WaitGroup
+	checks := make([]healthCheck, 5)
+	for i := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checks[i] = h.checkNotifier(context.Background())
+		}()
+	}
+	// let every probe wait on the ping before it returns
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if pings := notifier.pings.Load(); pings != 1 {
+		t.Errorf("expected 1 ping, got %d", pings)
+	}
+	for _, check := range checks {
+		if check.Status != "ok" {
+			t.Errorf("expected ok, got %+v", check)
+		}
+	}
+
+	h.checkNotifier(context.Background())
+	if pings := notifier.pings.Load(); pings != 1 {
+		t.Errorf("expected the result to be reused, got %d pings", pings)
+	}
+}
+
+func TestCheckNotifier_TimeoutNotCached(t *testing.T) {
+	notifier := &fakeDiscord{ping: func(ctx context.Context) error {
+		return context.DeadlineExceeded
+	}}
+	h := newHealthTestHandler(notifier)
+
+	if check := h.checkNotifier(context.Background()); check.Status != "error" {
+		t.Errorf("expected error, got %+v", check)
+	}
+	notifier.ping = nil
+	if check := h.checkNotifier(context.Background()); check.Status != "ok" || notifier.pings.Load() != 2 {
+		t.Errorf("expected a new ping after a timeout, got %+v after %d pings", check, notifier.pings.Load())
+	}
+}
+
+func TestCheckNotifier_ProberGivesUp(t *testing.T) {
+	release := make(chan struct{})
+	notifier := &fakeDiscord{ping: func(ctx context.Context) error {
+		<-release
+		return ctx.Err()
+	}}
+	h := newHealthTestHandler(notifier)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if check := h.checkNotifier(ctx); check.Status != "error" || check.Error != "context deadline exceeded" {
+		t.Errorf("expected the prober's deadline, got %+v", check)
+	}
+
+	// the ping itself carries on and its result is cached for the next probe
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if check := h.checkNotifier(context.Background()); check.Status == "ok" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if pings := notifier.pings.Load(); pings != 1 {
+		t.Errorf("expected the running ping to be cached, got %d pings", pings)
+	}
+}
@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mugglemath/dewdrop-go/pkg/logging"
+	"github.com/mugglemath/go-dew/internal/metrics"
+	"github.com/mugglemath/go-dew/internal/weather"
+)
+
+// Location is a named place with its own outdoor weather. The first one passed to New
+// is the default, for requests without a location and devices not assigned to one.
+type Location struct {
+	Name    string
+	Weather weather.Client
+}
+
+// location caches the outdoor conditions of a Location
+type location struct {
+	name            string
+	weatherClient   weather.Client
+	outdoorDewPoint atomic.Pointer[DewPoint]
+	refreshing      atomic.Bool
+}
+
+// location returns the location called name, or nil if there is none
+func (h *handlerImpl) location(name string) *location {
+	for _, l := range h.locations {
+		if l.name == name {
+			return l
+		}
+	}
+	return nil
+}
+
+// ownsLocation reports whether the household may use the location. The default
+// household owns every location no other household lists, and shares the default
+// location with households without any.
+func (h *handlerImpl) ownsLocation(tenant *TenantConfig, name string) bool {
+	config := h.config()
+	if tenant.ID != config.Tenants[0].ID {
+		if len(tenant.Locations) == 0 {
+			return name == h.locations[0].name
+		}
+		return slices.Contains(tenant.Locations, name)
+	}
+	for _, other := range config.Tenants[1:] {
+		if slices.Contains(other.Locations, name) {
+			return false
+		}
+	}
+	return true
+}
+
+// tenantLocation returns the household's location called name, its first location if
+// name is empty, or nil if the household has no such location
+func (h *handlerImpl) tenantLocation(tenant *TenantConfig, name string) *location {
+	if name == "" {
+		if len(tenant.Locations) == 0 {
+			return h.locations[0]
+		}
+		name = tenant.Locations[0]
+	}
+	if !h.ownsLocation(tenant, name) {
+		return nil
+	}
+	return h.location(name)
+}
+
+// deviceLocation returns the location the device is assigned to, or the default one of
+// its household
+func (h *handlerImpl) deviceLocation(tenant *TenantConfig, deviceID uint64) *location {
+	if l := h.tenantLocation(tenant, h.config().DeviceLocations[deviceID]); l != nil {
+		return l
+	}
+	if l := h.tenantLocation(tenant, ""); l != nil {
+		return l
+	}
+	return h.locations[0]
+}
+
+// warnUnknownLocations reports devices and households assigned to a location that wasn't
+// there at startup, they use the default location until go-dew is restarted
+func (h *handlerImpl) warnUnknownLocations(config *Config) {
+	for deviceID, name := range config.DeviceLocations {
+		if h.location(name) == nil {
+			slog.Warn("device assigned to an unknown location, using the default", "device_id", deviceID,
+				"location", name, "default_location", h.locations[0].name)
+		}
+	}
+	for _, tenant := range config.Tenants {
+		for _, name := range tenant.Locations {
+			if h.location(name) == nil {
+				slog.Warn("household assigned to an unknown location", "tenant", tenant.ID, "location", name)
+			}
+		}
+	}
+}
+
+// requestLocation reads the location query parameter, falling back to the household's
+// default location. Other households' locations are reported as unknown.
+func (h *handlerImpl) requestLocation(ctx *gin.Context, tenant *TenantConfig) (*location, bool) {
+	l := h.tenantLocation(tenant, ctx.Query("location"))
+	if l == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "unknown location"})
+		return nil, false
+	}
+	return l, true
+}
+
+// refreshOutdoor asynchronously updates the location's conditions if they are stale
+// or missing, one update at a time
+func (h *handlerImpl) refreshOutdoor(ctx context.Context, l *location) {
+	outdoor := l.outdoorDewPoint.Load()
+	if outdoor != nil && time.Now().Before(outdoor.LastUpdate.Add(updateInterval)) {
+		return
+	}
+	if !l.refreshing.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer l.refreshing.Store(false)
+		_ = h.updateOutdoorDewPoint(context.WithoutCancel(ctx), l)
+	}()
+}
+
+// updateOutdoorDewPoint atomically updates the location's dewPoint from National Weather Service
+func (h *handlerImpl) updateOutdoorDewPoint(ctx context.Context, l *location) (err error) {
+	logger := logging.FromContext(ctx).With("location", l.name)
+	for i := 0; i < 10; i++ {
+		var conditions weather.Conditions
+		conditions, err = l.weatherClient.GetOutdoorConditions(ctx)
+		metrics.WeatherFetch(l.name, err, time.Now())
+		if err != nil {
+			logger.Warn("failed to get outdoor conditions", "attempt", i+1, "error", err)
+		}
+		if err == nil {
+			l.storeOutdoorConditions(conditions)
+			break
+		}
+		time.Sleep(time.Second * 5)
+	}
+	if l.outdoorDewPoint.Load() != nil && err == nil {
+		logger.Info("updated outdoor dew point cache", "dewpoint", l.outdoorDewPoint.Load().Value)
+	}
+	return
+}
+
+func (l *location) storeOutdoorConditions(conditions weather.Conditions) {
+	l.outdoorDewPoint.Store(&DewPoint{
+		Value:       conditions.Dewpoint,
+		Temperature: conditions.Temperature,
+		LastUpdate:  time.Now(),
+	})
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mugglemath/dewdrop-go/pkg/logging"
+	"github.com/mugglemath/go-dew/internal/metrics"
+	"github.com/mugglemath/go-dew/internal/model"
+	"github.com/mugglemath/go-dew/internal/mold"
+)
+
+const (
+	// moldHistory is how far back the index is replayed for a device without stored state
+	moldHistory = 30 * 24 * time.Hour
+	// moldMaxGap limits how long a single reading is assumed to hold, e.g. across outages
+	moldMaxGap = time.Hour
+)
+
+// HandleMoldIndex returns the mold index of every device of the household, or of one
+// with /mold/:device_id
+func (h *handlerImpl) HandleMoldIndex(ctx *gin.Context) {
+	tenant := h.requestTenant(ctx)
+	if param := ctx.Param("device_id"); param != "" {
+		deviceID, err := strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid device id"})
+			return
+		}
+		state, err := h.dbClient.GetMoldState(ctx, tenant.ID, deviceID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get mold index"})
+			return
+		}
+		if state == nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "no mold index for device"})
+			return
+		}
+		state.Level = mold.Level(state.MoldIndex)
+		ctx.JSON(http.StatusOK, state)
+		return
+	}
+
+	states, err := h.dbClient.GetMoldStates(ctx, tenant.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get mold index"})
+		return
+	}
+	for i := range states {
+		states[i].Level = mold.Level(states[i].MoldIndex)
+	}
+	ctx.JSON(http.StatusOK, states)
+}
+
+// updateMoldIndex advances the device's mold index by the time since its last update
+// and alerts when it crosses the household's threshold
+func (h *handlerImpl) updateMoldIndex(ctx context.Context, tenant *TenantConfig, data model.SensorData, now time.Time) error {
+	h.moldMu.Lock()
+	defer h.moldMu.Unlock()
+
+	state, err := h.dbClient.GetMoldState(ctx, tenant.ID, data.DeviceID)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		if state, err = h.replayMoldIndex(ctx, tenant, data.DeviceID, now); err != nil {
+			return err
+		}
+	}
+
+	previous := state.MoldIndex
+	next := tenant.MoldModel.Step(mold.State{Index: state.MoldIndex, DryHours: state.DryHours},
+		data.IndoorTemperature, data.IndoorHumidity, min(now.Sub(state.UpdatedAt), moldMaxGap))
+	state.MoldIndex, state.DryHours, state.UpdatedAt = next.Index, next.DryHours, now
+	state.Level = mold.Level(next.Index)
+
+	if err := h.dbClient.SaveMoldState(ctx, tenant.ID, *state); err != nil {
+		return err
+	}
+	metrics.SetMoldIndex(state.DeviceID, state.MoldIndex)
+
+	if alertIndex := tenant.MoldAlertIndex; previous < alertIndex && next.Index >= alertIndex {
+		h.notify(func() {
+			if err := h.notifier(tenant.ID).SendHumidityAlert(context.WithoutCancel(ctx), state.AlertMessage()); err != nil {
+				logging.FromContext(ctx).Error("failed to send mold alert to Discord", "error", err)
+			}
+		})
+	}
+	return nil
+}
+
+// replayMoldIndex rebuilds the index from stored readings
+func (h *handlerImpl) replayMoldIndex(ctx context.Context, tenant *TenantConfig, deviceID uint64,
+	now time.Time) (*model.MoldState, error) {
+	readings, err := h.dbClient.GetReadingsSince(ctx, tenant.ID, deviceID, now.Add(-moldHistory))
+	if err != nil {
+		return nil, err
+	}
+
+	state := &model.MoldState{DeviceID: deviceID, UpdatedAt: now}
+	if len(readings) == 0 {
+		return state, nil
+	}
+
+	var current mold.State
+	moldModel := tenant.MoldModel
+	for i := 1; i < len(readings); i++ {
+		previous := readings[i-1]
+		current = moldModel.Step(current, previous.IndoorTemperature, previous.IndoorHumidity,
+			min(readings[i].Time.Sub(previous.Time), moldMaxGap))
+	}
+	state.MoldIndex, state.DryHours = current.Index, current.DryHours
+	state.UpdatedAt = readings[len(readings)-1].Time
+	logging.FromContext(ctx).Info("replayed mold index", "device_id", deviceID, "readings", len(readings),
+		"mold_index", current.Index)
+	return state, nil
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mugglemath/go-dew/internal/model"
+)
+
+// historyRanges are the spans /readings/:device_id can chart, each averaged over buckets
+// that keep it at a few hundred points
+var historyRanges = map[string]struct {
+	span, bucket time.Duration
+}{
+	"24h": {24 * time.Hour, 5 * time.Minute},
+	"7d":  {7 * 24 * time.Hour, 30 * time.Minute},
+}
+
+// readingResponse is a device's latest reading along with the state of its alerts
+type readingResponse struct {
+	model.StoredReading
+	CondensationAlert bool `json:"condensation_alert"`
+}
+
+// HandleReadings returns the latest reading of every device of the household, or the
+// history of one with /readings/:device_id?range=24h (or 7d). Temperatures are given in
+// the units parameter, or the household's default.
+func (h *handlerImpl) HandleReadings(ctx *gin.Context) {
+	tenant := h.requestTenant(ctx)
+	u, ok := h.requestUnits(ctx, tenant)
+	if !ok {
+		return
+	}
+
+	if param := ctx.Param("device_id"); param != "" {
+		deviceID, err := strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid device id"})
+			return
+		}
+		name := ctx.DefaultQuery("range", "24h")
+		r, ok := historyRanges[name]
+		if !ok {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "unknown range, use 24h or 7d"})
+			return
+		}
+		points, err := h.dbClient.GetHistory(ctx, tenant.ID, deviceID, time.Now().Add(-r.span), r.bucket)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get readings"})
+			return
+		}
+		for i := range points {
+			p := &points[i]
+			p.IndoorTemperature = u.Temperature(p.IndoorTemperature)
+			p.IndoorDewpoint = u.Temperature(p.IndoorDewpoint)
+			p.OutdoorDewpoint = convert(u.Temperature, p.OutdoorDewpoint)
+		}
+		ctx.JSON(http.StatusOK, gin.H{
+			"device_id":        deviceID,
+			"range":            name,
+			"bucket_seconds":   r.bucket.Seconds(),
+			"temperature_unit": u.TemperatureUnit(),
+			"points":           points,
+		})
+		return
+	}
+
+	readings, err := h.dbClient.GetLatestReadings(ctx, tenant.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get readings"})
+		return
+	}
+	h.condensationMu.Lock()
+	responses := make([]readingResponse, len(readings))
+	for i, reading := range readings {
+		reading.IndoorTemperature = u.Temperature(reading.IndoorTemperature)
+		reading.IndoorDewpoint = u.Temperature(reading.IndoorDewpoint)
+		reading.OutdoorDewpoint = convert(u.Temperature, reading.OutdoorDewpoint)
+		reading.DewpointDelta = convert(u.TemperatureDelta, reading.DewpointDelta)
+		responses[i] = readingResponse{StoredReading: reading, CondensationAlert: h.condensationAlerts[reading.DeviceID]}
+	}
+	h.condensationMu.Unlock()
+	ctx.JSON(http.StatusOK, gin.H{
+		"temperature_unit": u.TemperatureUnit(),
+		"units":            u.String(),
+		"readings":         responses,
+	})
+}
+
+// convert converts a value that may be unknown
+func convert(to func(float64) float64, value *float64) *float64 {
+	if value == nil {
+		return nil
+	}
+	converted := to(*value)
+	return &converted
+}
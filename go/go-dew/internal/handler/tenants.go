@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mugglemath/go-dew/internal/discord"
+)
+
+// tenantKey holds the household of an authenticated request in the gin context
+const tenantKey = "tenant"
+
+// Tenant is a household with its own Discord channels. The first one passed to New is
+// the default household.
+type Tenant struct {
+	ID      string
+	Discord discord.Client
+}
+
+// Authenticate resolves the household of the request from its API key, sent as a bearer
+// token or in the X-API-Key header. Requests without a key belong to the default
+// household as long as it has no keys.
+func (h *handlerImpl) Authenticate(ctx *gin.Context) {
+	key := ctx.GetHeader("X-API-Key")
+	if authorization := ctx.GetHeader("Authorization"); authorization != "" {
+		key = strings.TrimPrefix(authorization, "Bearer ")
+	}
+	tenant := h.authenticate(h.config(), key)
+	if tenant == nil {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or missing API key"})
+		return
+	}
+	ctx.Set(tenantKey, tenant)
+	ctx.Next()
+}
+
+// authenticate compares key with the keys of every household in constant time
+func (h *handlerImpl) authenticate(config *Config, key string) *TenantConfig {
+	if key == "" {
+		if len(config.Tenants[0].APIKeys) == 0 {
+			return &config.Tenants[0]
+		}
+		return nil
+	}
+	var match *TenantConfig
+	for i := range config.Tenants {
+		for _, candidate := range config.Tenants[i].APIKeys {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(candidate)) == 1 {
+				match = &config.Tenants[i]
+			}
+		}
+	}
+	return match
+}
+
+// requestTenant returns the household set by Authenticate
+func (h *handlerImpl) requestTenant(ctx *gin.Context) *TenantConfig {
+	if tenant, ok := ctx.Get(tenantKey); ok {
+		return tenant.(*TenantConfig)
+	}
+	return &h.config().Tenants[0]
+}
+
+// deviceTenant returns the household listing the device, or the default household
+func (h *handlerImpl) deviceTenant(deviceID uint64) *TenantConfig {
+	config := h.config()
+	for i := 1; i < len(config.Tenants); i++ {
+		if slices.Contains(config.Tenants[i].Devices, deviceID) {
+			return &config.Tenants[i]
+		}
+	}
+	return &config.Tenants[0]
+}
+
+// Notifier returns the Discord client of the request's household, or the default
+// household's before Authenticate ran
+func (h *handlerImpl) Notifier(ctx *gin.Context) discord.Client {
+	return h.notifier(h.requestTenant(ctx).ID)
+}
+
+// notifier returns the Discord client of the household
+func (h *handlerImpl) notifier(tenantID string) discord.Client {
+	for _, tenant := range h.tenants {
+		if tenant.ID == tenantID {
+			return tenant.Discord
+		}
+	}
+	return h.tenants[0].Discord
+}
+
+// knownTenants drops the households that weren't there at startup, they have no
+// Discord client until go-dew is restarted
+func (h *handlerImpl) knownTenants(tenants []TenantConfig) []TenantConfig {
+	known := make([]TenantConfig, 0, len(tenants))
+	for _, tenant := range tenants {
+		if !slices.ContainsFunc(h.tenants, func(t Tenant) bool { return t.ID == tenant.ID }) {
+			slog.Warn("ignoring a household added after startup, restart to serve it", "tenant", tenant.ID)
+			continue
+		}
+		known = append(known, tenant)
+	}
+	return known
+}
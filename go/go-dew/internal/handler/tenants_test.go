@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mugglemath/go-dew/internal/discord"
+	"github.com/mugglemath/go-dew/internal/weather"
+)
+
+// tenantsTest serves the default household, which owns the default location and
+// unlisted devices, and the smiths with device 2 at the cabin
+type tenantsTest struct {
+	h        Handler
+	r        *gin.Engine
+	db       *memoryDB
+	defaults *fakeDiscord
+	smiths   *fakeDiscord
+}
+
+func newTenantsTest(t *testing.T) *tenantsTest {
+	t.Helper()
+	tt := &tenantsTest{db: newMemoryDB(), defaults: &fakeDiscord{}, smiths: &fakeDiscord{}}
+	tt.h = New(tt.db,
+		[]Tenant{{ID: "default", Discord: tt.defaults}, {ID: "smiths", Discord: tt.smiths}},
+		[]Location{
+			{Name: "default", Weather: fakeWeather{weather.Conditions{Temperature: 20, Dewpoint: 10}}},
+			{Name: "cabin", Weather: fakeWeather{weather.Conditions{Temperature: 15, Dewpoint: 5}}},
+		},
+		&Config{Tenants: []TenantConfig{
+			{ID: "default", APIKeys: []string{"default-key"}},
+			{ID: "smiths", APIKeys: []string{"smith-key"}, Devices: []uint64{2}, Locations: []string{"cabin"}},
+		}})
+	if err := tt.h.Initialize(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	tt.r = newTestRouter(tt.h)
+	return tt
+}
+
+// post sends a reading of the device with the API key
+func (tt *tenantsTest) post(apiKey, body string) *httptest.ResponseRecorder {
+	return serve(tt.r, http.MethodPost, "/arduino/sensor-feed", apiKey, body)
+}
+
+func TestAuthenticate(t *testing.T) {
+	tt := newTenantsTest(t)
+
+	tests := map[string]struct {
+		header, value string
+		status        int
+		dewpoint      float64
+	}{
+		"missing key":    {"", "", http.StatusUnauthorized, 0},
+		"wrong bearer":   {"Authorization", "Bearer other-key", http.StatusUnauthorized, 0},
+		"wrong header":   {"X-API-Key", "other-key", http.StatusUnauthorized, 0},
+		"bearer default": {"Authorization", "Bearer default-key", http.StatusOK, 10},
+		"header default": {"X-API-Key", "default-key", http.StatusOK, 10},
+		"bearer smiths":  {"Authorization", "Bearer smith-key", http.StatusOK, 5},
+		"header smiths":  {"X-API-Key", "smith-key", http.StatusOK, 5},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/weather/outdoor-dewpoint", nil)
+			if test.header != "" {
+				req.Header.Set(test.header, test.value)
+			}
+			w := httptest.NewRecorder()
+			tt.r.ServeHTTP(w, req)
+
+			if w.Code != test.status {
+				t.Fatalf("expected %d, got %d: %s", test.status, w.Code, w.Body)
+			}
+			var dewpoint float64
+			if test.status == http.StatusOK && (json.Unmarshal(w.Body.Bytes(), &dewpoint) != nil || dewpoint != test.dewpoint) {
+				t.Errorf("expected the household's dew point %v, got %s", test.dewpoint, w.Body)
+			}
+		})
+	}
+}
+
+func TestHandleSensorData_DeviceOwnership(t *testing.T) {
+	tt := newTenantsTest(t)
+
+	tests := []struct {
+		name, apiKey string
+		deviceID     string
+		status       int
+	}{
+		{"own device", "smith-key", "2", http.StatusOK},
+		{"other household's device", "default-key", "2", http.StatusForbidden},
+		{"unlisted device", "default-key", "7", http.StatusOK},
+		{"unlisted device of another household", "smith-key", "7", http.StatusForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			body := `{"device_id": ` + test.deviceID + `, "indoor_temperature": 21, "indoor_humidity": 50, "indoor_dewpoint": 10.2}`
+			if w := tt.post(test.apiKey, body); w.Code != test.status {
+				t.Errorf("expected %d, got %d: %s", test.status, w.Code, w.Body)
+			}
+		})
+	}
+
+	// each reading is stored for its owner only, with the outdoor dew point of its location
+	if rows := tt.db.Rows("smiths"); len(rows) != 1 || rows[0].DeviceID != 2 || *rows[0].OutdoorDewpoint != 5 {
+		t.Errorf("expected device 2 at the cabin for the smiths, got %+v", rows)
+	}
+	if rows := tt.db.Rows("default"); len(rows) != 1 || rows[0].DeviceID != 7 || *rows[0].OutdoorDewpoint != 10 {
+		t.Errorf("expected device 7 at the default location for the default household, got %+v", rows)
+	}
+}
+
+func TestOtherHouseholdsData(t *testing.T) {
+	tt := newTenantsTest(t)
+	for apiKey, deviceID := range map[string]string{"smith-key": "2", "default-key": "7"} {
+		body := `{"device_id": ` + deviceID + `, "indoor_temperature": 21, "indoor_humidity": 50, "indoor_dewpoint": 10.2}`
+		if w := tt.post(apiKey, body); w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+		}
+	}
+
+	// the other household's location is unknown
+	for path, apiKey := range map[string]string{
+		"/weather/outdoor?location=cabin":            "default-key",
+		"/weather/outdoor-dewpoint?location=cabin":   "default-key",
+		"/weather/outdoor?location=default":          "smith-key",
+		"/weather/outdoor-dewpoint?location=default": "smith-key",
+	} {
+		if w := serve(tt.r, http.MethodGet, path, apiKey, ""); w.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d: %s", path, w.Code, w.Body)
+		}
+	}
+
+	// and so are its devices
+	for path, apiKey := range map[string]string{"/devices/2": "default-key", "/devices/7": "smith-key"} {
+		if w := serve(tt.r, http.MethodGet, path, apiKey, ""); w.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d: %s", path, w.Code, w.Body)
+		}
+	}
+	var devices []struct {
+		DeviceID uint64 `json:"device_id"`
+	}
+	w := serve(tt.r, http.MethodGet, "/devices", "default-key", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &devices); err != nil || len(devices) != 1 || devices[0].DeviceID != 7 {
+		t.Errorf("expected only device 7, got %s", w.Body)
+	}
+
+	// and its readings
+	var readings struct {
+		Readings []struct {
+			DeviceID uint64 `json:"device_id"`
+		} `json:"readings"`
+	}
+	w = serve(tt.r, http.MethodGet, "/readings", "smith-key", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &readings); err != nil || len(readings.Readings) != 1 || readings.Readings[0].DeviceID != 2 {
+		t.Errorf("expected only device 2, got %s", w.Body)
+	}
+	var history struct {
+		Points []any `json:"points"`
+	}
+	w = serve(tt.r, http.MethodGet, "/readings/7", "smith-key", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &history); err != nil || w.Code != http.StatusOK || len(history.Points) != 0 {
+		t.Errorf("expected no history of device 7, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestHandleSensorData_NotifiesOwner(t *testing.T) {
+	tt := newTenantsTest(t)
+	// the first reading only initializes the table, the second one alerts on its humidity
+	for _, humidity := range []string{"50", "70"} {
+		body := `{"device_id": 2, "indoor_temperature": 21, "indoor_humidity": ` + humidity + `, "indoor_dewpoint": 15.3}`
+		if w := tt.post("smith-key", body); w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+		}
+	}
+	if err := tt.h.Shutdown(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(tt.smiths.Messages()) == 0 {
+		t.Errorf("expected the smiths to be alerted")
+	}
+	if messages := tt.defaults.Messages(); len(messages) != 0 {
+		t.Errorf("expected no messages for the default household, got %q", messages)
+	}
+}
+
+func TestNotifier(t *testing.T) {
+	tt := newTenantsTest(t)
+	var notifier discord.Client
+	r := gin.New()
+	r.GET("/panic", tt.h.Authenticate, func(c *gin.Context) { notifier = tt.h.Notifier(c) })
+
+	serve(r, http.MethodGet, "/panic", "smith-key", "")
+	if notifier != tt.smiths {
+		t.Errorf("expected the smiths' Discord client")
+	}
+	// before Authenticate the request has no household yet
+	if notifier = tt.h.Notifier(&gin.Context{}); notifier != tt.defaults {
+		t.Errorf("expected the default household's Discord client")
+	}
+}
@@ -0,0 +1,124 @@
+// Package heartbeat tracks when each device was last heard from
+package heartbeat
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mugglemath/go-dew/internal/model"
+)
+
+type Monitor struct {
+	mu           sync.Mutex
+	offlineAfter time.Duration
+	devices      map[uint64]*model.DeviceStatus
+}
+
+// New returns a monitor that considers a device offline after offlineAfter of silence
+func New(offlineAfter time.Duration) *Monitor {
+	return &Monitor{
+		offlineAfter: offlineAfter,
+		devices:      map[uint64]*model.DeviceStatus{},
+	}
+}
+
+// SetOfflineAfter changes the allowed silence, devices already offline stay offline
+// until they report again
+func (m *Monitor) SetOfflineAfter(offlineAfter time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.offlineAfter = offlineAfter
+}
+
+// Seen records a reading. If the device was offline it returns its status from before
+// the reading, so the recovery message can say how long it was gone.
+func (m *Monitor) Seen(deviceID uint64, at time.Time) (recovered *model.DeviceStatus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	device, ok := m.devices[deviceID]
+	if !ok {
+		device = &model.DeviceStatus{DeviceID: deviceID}
+		m.devices[deviceID] = device
+	}
+	if !device.Online && device.OfflineSince != nil {
+		previous := *device
+		recovered = &previous
+	}
+	if at.After(device.LastSeen) {
+		device.LastSeen = at
+	}
+	device.Online = true
+	device.OfflineSince = nil
+	return recovered
+}
+
+// Restore adds a device last seen before a restart. Devices already silent for longer
+// than offlineAfter are restored as offline without being reported by Check, so a
+// restart doesn't repeat alerts for long dead devices.
+func (m *Monitor) Restore(deviceID uint64, lastSeen, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.devices[deviceID]; ok {
+		return
+	}
+	device := &model.DeviceStatus{DeviceID: deviceID, LastSeen: lastSeen, Online: true}
+	if now.Sub(lastSeen) > m.offlineAfter {
+		since := lastSeen.Add(m.offlineAfter)
+		device.Online = false
+		device.OfflineSince = &since
+	}
+	m.devices[deviceID] = device
+}
+
+// Check marks devices silent for longer than offlineAfter as offline and returns the
+// ones that just went offline
+func (m *Monitor) Check(now time.Time) []model.DeviceStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var offline []model.DeviceStatus
+	for _, device := range m.devices {
+		if device.Online && now.Sub(device.LastSeen) > m.offlineAfter {
+			since := device.LastSeen.Add(m.offlineAfter)
+			device.Online = false
+			device.OfflineSince = &since
+			offline = append(offline, *device)
+		}
+	}
+	sortByID(offline)
+	return offline
+}
+
+// Devices returns the status of every known device, ordered by ID
+func (m *Monitor) Devices() []model.DeviceStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	devices := make([]model.DeviceStatus, 0, len(m.devices))
+	for _, device := range m.devices {
+		devices = append(devices, *device)
+	}
+	sortByID(devices)
+	return devices
+}
+
+func (m *Monitor) Device(deviceID uint64) (model.DeviceStatus, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	device, ok := m.devices[deviceID]
+	if !ok {
+		return model.DeviceStatus{}, false
+	}
+	return *device, true
+}
+
+func sortByID(devices []model.DeviceStatus) {
+	slices.SortFunc(devices, func(a, b model.DeviceStatus) int {
+		return cmp.Compare(a.DeviceID, b.DeviceID)
+	})
+}
@@ -0,0 +1,102 @@
+package heartbeat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheck(t *testing.T) {
+	start := time.Date(2024, 12, 1, 12, 0, 0, 0, time.UTC)
+	monitor := New(10 * time.Minute)
+	monitor.Seen(1, start)
+	monitor.Seen(2, start.Add(5*time.Minute))
+
+	if offline := monitor.Check(start.Add(10 * time.Minute)); len(offline) != 0 {
+		t.Errorf("expected no offline devices, got %v", offline)
+	}
+
+	offline := monitor.Check(start.Add(11 * time.Minute))
+	if len(offline) != 1 || offline[0].DeviceID != 1 {
+		t.Fatalf("expected device 1 offline, got %v", offline)
+	}
+	if expected := start.Add(10 * time.Minute); !offline[0].OfflineSince.Equal(expected) {
+		t.Errorf("expected offline since %s, got %s", expected, offline[0].OfflineSince)
+	}
+
+	// an offline device is only reported once
+	offline = monitor.Check(start.Add(20 * time.Minute))
+	if len(offline) != 1 || offline[0].DeviceID != 2 {
+		t.Errorf("expected only device 2 newly offline, got %v", offline)
+	}
+}
+
+func TestSeen_Recovery(t *testing.T) {
+	start := time.Date(2024, 12, 1, 12, 0, 0, 0, time.UTC)
+	monitor := New(10 * time.Minute)
+
+	if recovered := monitor.Seen(1, start); recovered != nil {
+		t.Errorf("expected a new device not to recover, got %v", recovered)
+	}
+	monitor.Check(start.Add(time.Hour))
+
+	recovered := monitor.Seen(1, start.Add(2*time.Hour))
+	if recovered == nil {
+		t.Fatal("expected device 1 to recover")
+	}
+	if !recovered.LastSeen.Equal(start) || recovered.Online {
+		t.Errorf("expected the status from before the reading, got %v", recovered)
+	}
+
+	device, ok := monitor.Device(1)
+	if !ok || !device.Online || device.OfflineSince != nil || !device.LastSeen.Equal(start.Add(2*time.Hour)) {
+		t.Errorf("expected device 1 online, got %v", device)
+	}
+	if recovered := monitor.Seen(1, start.Add(3*time.Hour)); recovered != nil {
+		t.Errorf("expected an online device not to recover, got %v", recovered)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	start := time.Date(2024, 12, 1, 12, 0, 0, 0, time.UTC)
+	now := start.Add(time.Hour)
+	monitor := New(10 * time.Minute)
+	monitor.Seen(1, now)
+	monitor.Restore(1, start, now)
+	monitor.Restore(2, start, now)
+	monitor.Restore(3, now.Add(-5*time.Minute), now)
+
+	devices := monitor.Devices()
+	if len(devices) != 3 || devices[0].DeviceID != 1 || devices[1].DeviceID != 2 || devices[2].DeviceID != 3 {
+		t.Fatalf("expected devices 1, 2 and 3, got %v", devices)
+	}
+	if !devices[0].LastSeen.Equal(now) || !devices[0].Online {
+		t.Errorf("expected restore not to override a newer reading, got %v", devices[0])
+	}
+	if devices[1].Online || !devices[1].OfflineSince.Equal(start.Add(10*time.Minute)) {
+		t.Errorf("expected device 2 restored offline, got %v", devices[1])
+	}
+
+	// device 2 was already offline before the restart, only device 3 is reported
+	offline := monitor.Check(now.Add(10 * time.Minute))
+	if len(offline) != 1 || offline[0].DeviceID != 3 {
+		t.Errorf("expected device 3 offline, got %v", offline)
+	}
+
+	if recovered := monitor.Seen(2, now); recovered == nil {
+		t.Error("expected a restored offline device to recover")
+	}
+}
+
+func TestSetOfflineAfter(t *testing.T) {
+	start := time.Date(2024, 12, 1, 12, 0, 0, 0, time.UTC)
+	monitor := New(10 * time.Minute)
+	monitor.Seen(1, start)
+
+	monitor.SetOfflineAfter(30 * time.Minute)
+	if offline := monitor.Check(start.Add(20 * time.Minute)); len(offline) != 0 {
+		t.Errorf("expected no offline devices, got %v", offline)
+	}
+	if offline := monitor.Check(start.Add(31 * time.Minute)); len(offline) != 1 {
+		t.Errorf("expected device 1 offline, got %v", offline)
+	}
+}
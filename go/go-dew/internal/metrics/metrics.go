@@ -0,0 +1,186 @@
+// Package metrics exposes go-dew's Prometheus metrics on its own registry
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "go_dew"
+
+var (
+	Registry = prometheus.NewRegistry()
+
+	indoorTemperature = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "indoor_temperature_celsius",
+		Help:      "Latest indoor temperature reported by each device.",
+	}, []string{"device_id"})
+	indoorHumidity = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "indoor_humidity_percent",
+		Help:      "Latest indoor relative humidity reported by each device.",
+	}, []string{"device_id"})
+	indoorDewpoint = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "indoor_dewpoint_celsius",
+		Help:      "Latest indoor dew point reported by each device.",
+	}, []string{"device_id"})
+	openWindows = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "open_windows",
+		Help:      "1 if the latest reading of each device recommends open windows.",
+	}, []string{"device_id"})
+	lastReading = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_reading_timestamp_seconds",
+		Help:      "Unix time of the latest reading from each device.",
+	}, []string{"device_id"})
+	moldIndex = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "mold_index",
+		Help:      "VTT mold growth index of each device.",
+	}, []string{"device_id"})
+	deviceOnline = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "device_online",
+		Help:      "0 once a device has been silent for longer than the offline timeout.",
+	}, []string{"device_id"})
+
+	ingestTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ingest_requests_total",
+		Help:      "Sensor feed requests by outcome.",
+	}, []string{"status"})
+
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Database query latency by operation.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "result"})
+
+	weatherFetchTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "weather_fetch_total",
+		Help:      "National Weather Service requests by location and result.",
+	}, []string{"location", "result"})
+	weatherLastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "weather_last_success_timestamp_seconds",
+		Help:      "Unix time of the last successful weather fetch by location.",
+	}, []string{"location"})
+	weatherAge = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "weather_age_seconds",
+		Help:      "Age of the stalest cached outdoor conditions.",
+	}, func() float64 {
+		var oldest int64
+		weatherLastSuccessTimes.Range(func(_, last any) bool {
+			if oldest == 0 || last.(int64) < oldest {
+				oldest = last.(int64)
+			}
+			return true
+		})
+		if oldest == 0 {
+			return 0
+		}
+		return time.Since(time.Unix(0, oldest)).Seconds()
+	})
+	// weatherLastSuccessTimes maps the locations to their last success in Unix nanoseconds
+	weatherLastSuccessTimes sync.Map
+	weatherCacheTotal       = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "weather_cache_total",
+		Help:      "Weather HTTP requests by cache result: hit, revalidated or miss.",
+	}, []string{"result"})
+
+	notificationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_total",
+		Help:      "Discord notifications by channel and result.",
+	}, []string{"channel", "result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		indoorTemperature, indoorHumidity, indoorDewpoint, openWindows, lastReading, moldIndex, deviceOnline,
+		ingestTotal, dbQueryDuration,
+		weatherFetchTotal, weatherLastSuccess, weatherAge, weatherCacheTotal,
+		notificationsTotal,
+	)
+}
+
+// Handler serves the registry in the Prometheus text or OpenMetrics format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{EnableOpenMetrics: true})
+}
+
+// SetReading updates the gauges of a device from its latest reading
+func SetReading(deviceID uint64, temperature, humidity, dewpoint float64, windowsOpen bool, at time.Time) {
+	id := strconv.FormatUint(deviceID, 10)
+	indoorTemperature.WithLabelValues(id).Set(temperature)
+	indoorHumidity.WithLabelValues(id).Set(humidity)
+	indoorDewpoint.WithLabelValues(id).Set(dewpoint)
+	openWindows.WithLabelValues(id).Set(boolValue(windowsOpen))
+	lastReading.WithLabelValues(id).Set(float64(at.Unix()))
+}
+
+func SetMoldIndex(deviceID uint64, index float64) {
+	moldIndex.WithLabelValues(strconv.FormatUint(deviceID, 10)).Set(index)
+}
+
+func SetDeviceOnline(deviceID uint64, online bool) {
+	deviceOnline.WithLabelValues(strconv.FormatUint(deviceID, 10)).Set(boolValue(online))
+}
+
+// Ingest counts a sensor feed request, e.g. with status ok, invalid or db_error
+func Ingest(status string) {
+	ingestTotal.WithLabelValues(status).Inc()
+}
+
+// ObserveDBQuery records the latency of a query started at start
+func ObserveDBQuery(operation string, start time.Time, err error) {
+	dbQueryDuration.WithLabelValues(operation, result(err)).Observe(time.Since(start).Seconds())
+}
+
+// WeatherFetch counts a weather request of the location and tracks the time of its last success
+func WeatherFetch(location string, err error, at time.Time) {
+	weatherFetchTotal.WithLabelValues(location, result(err)).Inc()
+	if err == nil {
+		weatherLastSuccess.WithLabelValues(location).Set(float64(at.Unix()))
+		weatherLastSuccessTimes.Store(location, at.UnixNano())
+	}
+}
+
+// WeatherCache counts how a weather HTTP request was answered
+func WeatherCache(result string) {
+	weatherCacheTotal.WithLabelValues(result).Inc()
+}
+
+// Notification counts a delivery attempt to a Discord channel
+func Notification(channel string, err error) {
+	notificationsTotal.WithLabelValues(channel, result(err)).Inc()
+}
+
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestSetReading(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+	SetReading(42, 21.5, 55, 12.1, true, at)
+
+	tests := map[string][2]float64{
+		"temperature":  {testutil.ToFloat64(indoorTemperature.WithLabelValues("42")), 21.5},
+		"humidity":     {testutil.ToFloat64(indoorHumidity.WithLabelValues("42")), 55},
+		"dewpoint":     {testutil.ToFloat64(indoorDewpoint.WithLabelValues("42")), 12.1},
+		"open windows": {testutil.ToFloat64(openWindows.WithLabelValues("42")), 1},
+		"last reading": {testutil.ToFloat64(lastReading.WithLabelValues("42")), 1700000000},
+	}
+	for name, values := range tests {
+		if values[0] != values[1] {
+			t.Errorf("%s: expected %v, got %v", name, values[1], values[0])
+		}
+	}
+}
+
+func TestCounters(t *testing.T) {
+	before := testutil.ToFloat64(ingestTotal.WithLabelValues("ok"))
+	Ingest("ok")
+	Ingest("ok")
+	if actual := testutil.ToFloat64(ingestTotal.WithLabelValues("ok")) - before; actual != 2 {
+		t.Errorf("expected 2 ingested, got %v", actual)
+	}
+
+	Notification("window_alert", errors.New("webhook down"))
+	if actual := testutil.ToFloat64(notificationsTotal.WithLabelValues("window_alert", "error")); actual != 1 {
+		t.Errorf("expected 1 failed notification, got %v", actual)
+	}
+
+	ObserveDBQuery("insert_sensor_feed", time.Now(), nil)
+	if count := testutil.CollectAndCount(dbQueryDuration); count != 1 {
+		t.Errorf("expected 1 histogram series, got %d", count)
+	}
+}
+
+func TestWeatherFetch(t *testing.T) {
+	WeatherFetch("home", errors.New("timeout"), time.Now())
+	if actual := testutil.ToFloat64(weatherLastSuccess.WithLabelValues("home")); actual != 0 {
+		t.Errorf("expected no successful fetch, got %v", actual)
+	}
+
+	WeatherFetch("home", nil, time.Now().Add(-time.Minute))
+	WeatherFetch("office", nil, time.Now())
+	if age := testutil.ToFloat64(weatherAge); age < 59 || age > 70 {
+		t.Errorf("expected the age of the stalest location, about a minute, got %v", age)
+	}
+	if actual := testutil.ToFloat64(weatherFetchTotal.WithLabelValues("home", "error")); actual != 1 {
+		t.Errorf("expected 1 failed fetch, got %v", actual)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	SetMoldIndex(7, 1.5)
+	SetDeviceOnline(7, false)
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(recorder.Body)
+
+	for _, expected := range []string{`go_dew_mold_index{device_id="7"} 1.5`, `go_dew_device_online{device_id="7"} 0`, "go_goroutines", "go_dew_weather_age_seconds"} {
+		if !strings.Contains(string(body), expected) {
+			t.Errorf("expected %q in the response", expected)
+		}
+	}
+}
@@ -1,50 +1,152 @@
 package model
 
 import (
+	"errors"
 	"fmt"
 	"time"
+
+	"github.com/mugglemath/dewdrop-go/pkg/calculations"
+	"github.com/mugglemath/dewdrop-go/pkg/units"
 )
 
 type SensorData struct {
 	DeviceID          uint64  `json:"device_id"`
 	IndoorTemperature float64 `json:"indoor_temperature"`
 	IndoorHumidity    float64 `json:"indoor_humidity"`
-	IndoorDewpoint    float64 `json:"indoor_dewpoint"`
-	OutdoorDewpoint   float64 `json:"outdoor_dewpoint"`
-	DewpointDelta     float64 `json:"dewpoint_delta"`
-	OpenWindows       bool    `json:"open_windows" gorm:"type:boolean"`
-	HumidityAlert     bool    `json:"humidity_alert" gorm:"type:boolean"`
+	// RawIndoorTemperature and RawIndoorHumidity are the readings before calibration and
+	// filtering, nil from dewdrop versions that don't send them
+	RawIndoorTemperature *float64 `json:"raw_indoor_temperature"`
+	RawIndoorHumidity    *float64 `json:"raw_indoor_humidity"`
+	IndoorDewpoint       float64  `json:"indoor_dewpoint"`
+	// OutdoorDewpoint and DewpointDelta are nil while neither dewdrop nor go-dew has
+	// the outdoor conditions
+	OutdoorDewpoint *float64 `json:"outdoor_dewpoint"`
+	DewpointDelta   *float64 `json:"dewpoint_delta"`
+	OpenWindows     bool     `json:"open_windows" gorm:"type:boolean"`
+	HumidityAlert   bool     `json:"humidity_alert" gorm:"type:boolean"`
+	// TenantID is the household the reading was posted for, devices can't set it
+	TenantID string `json:"-"`
 }
 
 func (s *SensorData) TableName() string {
 	return "data"
 }
 
-func (s *SensorData) FeedMessage() string {
+// StoredReading is a reading as stored, with the time it was received
+type StoredReading struct {
+	DeviceID          uint64    `json:"device_id"`
+	Time              time.Time `json:"time"`
+	IndoorTemperature float64   `json:"indoor_temperature"`
+	IndoorHumidity    float64   `json:"indoor_humidity"`
+	IndoorDewpoint    float64   `json:"indoor_dewpoint"`
+	OutdoorDewpoint   *float64  `json:"outdoor_dewpoint"`
+	DewpointDelta     *float64  `json:"dewpoint_delta"`
+	OpenWindows       bool      `json:"open_windows"`
+	HumidityAlert     bool      `json:"humidity_alert"`
+}
+
+// HistoryPoint averages the readings of a device over a time bucket starting at Time
+type HistoryPoint struct {
+	Time              time.Time `json:"time" gorm:"column:bucket"`
+	IndoorTemperature float64   `json:"indoor_temperature"`
+	IndoorHumidity    float64   `json:"indoor_humidity"`
+	IndoorDewpoint    float64   `json:"indoor_dewpoint"`
+	OutdoorDewpoint   *float64  `json:"outdoor_dewpoint"`
+}
+
+// FeedMessage formats the reading for the sensor feed channel in the given units
+func (s *SensorData) FeedMessage(u units.System) string {
 	isoTimestamp := time.Now().Format(time.RFC3339)
 	return fmt.Sprintf("%s\n"+
 		"Sent from: %d\n"+
-		"Indoor Temperature: %.2f C\n"+
+		"Indoor Temperature: %s\n"+
 		"Indoor Humidity: %.2f %%\n"+
-		"Indoor Dewpoint: %.2f C\n"+
-		"Outdoor Dewpoint: %.2f C\n"+
-		"Dewpoint Delta: %.2f C\n"+
+		"Indoor Dewpoint: %s\n"+
+		"Outdoor Dewpoint: %s\n"+
+		"Dewpoint Delta: %s\n"+
 		"Open Windows: %t\n"+
 		"Humidity Alert: %t",
-		isoTimestamp, s.DeviceID, s.IndoorTemperature, s.IndoorHumidity,
-		s.IndoorDewpoint, s.OutdoorDewpoint, s.DewpointDelta,
-		s.OpenWindows, s.HumidityAlert)
+		isoTimestamp, s.DeviceID, u.FormatTemperature(s.IndoorTemperature), s.IndoorHumidity,
+		u.FormatTemperature(s.IndoorDewpoint), formatOptional(u.FormatTemperature, s.OutdoorDewpoint),
+		formatOptional(u.FormatTemperatureDelta, s.DewpointDelta), s.OpenWindows, s.HumidityAlert) + s.metricsMessage(u)
 }
 
-func (s *SensorData) WindowAlertMessage() string {
+// IndoorMetrics derives absolute humidity, wet bulb etc. from the indoor reading at sea level pressure
+func (s *SensorData) IndoorMetrics() (calculations.Metrics, error) {
+	return calculations.ComputeMetrics(s.IndoorTemperature, s.IndoorHumidity, calculations.StandardPressure)
+}
+
+// VentilationAdvice recomputes the window decision from absolute humidity, independent of the device
+func (s *SensorData) VentilationAdvice() (calculations.VentilationAdvice, error) {
+	if s.OutdoorDewpoint == nil {
+		return calculations.VentilationAdvice{}, errors.New("no outdoor dew point")
+	}
+	return calculations.DefaultVentilationAdvisor.Advise(s.IndoorTemperature, s.IndoorHumidity, *s.OutdoorDewpoint)
+}
+
+// formatOptional formats a value that may be unknown
+func formatOptional(format func(float64) string, value *float64) string {
+	if value == nil {
+		return "unknown"
+	}
+	return format(*value)
+}
+
+func (s *SensorData) metricsMessage(u units.System) string {
+	metrics, err := s.IndoorMetrics()
+	if err != nil {
+		return ""
+	}
+	message := fmt.Sprintf("\n"+
+		"Absolute Humidity: %s\n"+
+		"Mixing Ratio: %s\n"+
+		"Wet Bulb: %s\n"+
+		"Heat Index: %s\n"+
+		"Humidex: %.2f",
+		u.FormatAbsoluteHumidity(metrics.AbsoluteHumidity), u.FormatMixingRatio(metrics.MixingRatio),
+		u.FormatTemperature(metrics.WetBulbTemperature), u.FormatTemperature(metrics.HeatIndex), metrics.Humidex)
+	if metrics.DewPoint < 0 && metrics.VaporPressure > 0 {
+		message += fmt.Sprintf("\nFrost Point: %s", u.FormatTemperature(metrics.FrostPoint))
+	}
+	return message
+}
+
+func (s *SensorData) WindowAlertMessage(u units.System) string {
 	isoTimestamp := time.Now().Format(time.RFC3339)
-	return fmt.Sprintf("%s\n@everyone\n"+
+	message := fmt.Sprintf("%s\n@everyone\n"+
 		"Sent from %d\n"+
-		"Indoor Dewpoint: %.2f C\n"+
-		"Outdoor Dewpoint: %.2f C\n"+
-		"Dewpoint Delta: %.2f C\n"+
+		"Indoor Dewpoint: %s\n"+
+		"Outdoor Dewpoint: %s\n"+
+		"Dewpoint Delta: %s\n"+
 		"Open Windows: %t\n",
-		isoTimestamp, s.DeviceID, s.IndoorDewpoint, s.OutdoorDewpoint, s.DewpointDelta, s.OpenWindows)
+		isoTimestamp, s.DeviceID, u.FormatTemperature(s.IndoorDewpoint), formatOptional(u.FormatTemperature, s.OutdoorDewpoint),
+		formatOptional(u.FormatTemperatureDelta, s.DewpointDelta), s.OpenWindows)
+	if advice, err := s.VentilationAdvice(); err == nil {
+		message += fmt.Sprintf("Reason: %s (%.0f%% confidence)\n"+
+			"Indoor Absolute Humidity: %s\n"+
+			"Outdoor Absolute Humidity: %s\n"+
+			"Predicted Humidity After Airing: %.1f %%\n",
+			advice.Reason, advice.Confidence*100, u.FormatAbsoluteHumidity(advice.IndoorAbsoluteHumidity),
+			u.FormatAbsoluteHumidity(advice.OutdoorAbsoluteHumidity), advice.PredictedHumidity)
+	}
+	return message
+}
+
+func (s *SensorData) CondensationAlertMessage(risk calculations.CondensationRisk, outdoorTemperature, uValue float64, u units.System) string {
+	isoTimestamp := time.Now().Format(time.RFC3339)
+	state := "approaching condensation"
+	if risk.Condensing {
+		state = "condensing"
+	}
+	return fmt.Sprintf("%s\n@everyone\n"+
+		"Sent from %d\n"+
+		"Windows %s\n"+
+		"Glass Surface Temperature: %s (U-value %.1f)\n"+
+		"Indoor Dewpoint: %s\n"+
+		"Margin: %s\n"+
+		"Outdoor Temperature: %s",
+		isoTimestamp, s.DeviceID, state, u.FormatTemperature(risk.SurfaceTemperature), uValue,
+		u.FormatTemperature(risk.DewPoint), u.FormatTemperatureDelta(risk.Margin), u.FormatTemperature(outdoorTemperature))
 }
 
 func (s *SensorData) HumidityAlertMessage() string {
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// DeviceStatus is when a device was last heard from and whether it is considered offline
+type DeviceStatus struct {
+	DeviceID     uint64     `json:"device_id"`
+	LastSeen     time.Time  `json:"last_seen"`
+	Online       bool       `json:"online"`
+	OfflineSince *time.Time `json:"offline_since,omitempty"`
+}
+
+func (d *DeviceStatus) OfflineMessage() string {
+	isoTimestamp := time.Now().Format(time.RFC3339)
+	return fmt.Sprintf("%s\n@everyone\n"+
+		"Device %d is offline\n"+
+		"Last Seen: %s",
+		isoTimestamp, d.DeviceID, d.LastSeen.Format(time.RFC3339))
+}
+
+// RecoveryMessage is sent when readings resume, d is the status while the device was offline
+func (d *DeviceStatus) RecoveryMessage(now time.Time) string {
+	isoTimestamp := now.Format(time.RFC3339)
+	return fmt.Sprintf("%s\n"+
+		"Device %d is back online\n"+
+		"Silent For: %s",
+		isoTimestamp, d.DeviceID, now.Sub(d.LastSeen).Round(time.Minute))
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// MoldState is the running VTT mold index of a device
+type MoldState struct {
+	DeviceID  uint64    `json:"device_id" gorm:"primaryKey;autoIncrement:false"`
+	MoldIndex float64   `json:"mold_index"`
+	DryHours  float64   `json:"dry_hours"`
+	Level     string    `json:"level" gorm:"-"`
+	UpdatedAt time.Time `json:"updated_at"`
+	TenantID  string    `json:"-"`
+}
+
+func (m *MoldState) TableName() string {
+	return "mold_state"
+}
+
+// Reading is a point of the sensor history used to replay the mold index
+type Reading struct {
+	Time              time.Time
+	IndoorTemperature float64
+	IndoorHumidity    float64
+}
+
+func (m *MoldState) AlertMessage() string {
+	isoTimestamp := time.Now().Format(time.RFC3339)
+	return fmt.Sprintf("%s\n@everyone\n"+
+		"Sent from %d\n"+
+		"Mold Index: %.2f (%s)",
+		isoTimestamp, m.DeviceID, m.MoldIndex, m.Level)
+}
@@ -0,0 +1,144 @@
+// Package mold implements the VTT mold growth model (Hukka & Viitanen 1999) for wood
+// surfaces. The index runs from 0 (no growth) to 6 (heavy growth covering the surface),
+// 1 is the first growth visible under a microscope and 3 the first visible to the eye.
+package mold
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	MaxIndex = 6
+
+	// growth is only possible between these temperatures in C
+	minTemperature = 0
+	maxTemperature = 50
+
+	maxStep = 10 * time.Minute
+)
+
+// Model holds the wood species and surface quality parameters
+type Model struct {
+	Name string
+	// Species is 0 for pine and 1 for spruce
+	Species float64
+	// Surface is 0 for resawn and 1 for kiln dried surfaces
+	Surface float64
+}
+
+var (
+	// Pine is resawn pine sapwood
+	Pine = Model{Name: "pine", Species: 0, Surface: 0}
+	// Spruce is resawn spruce
+	Spruce = Model{Name: "spruce", Species: 1, Surface: 0}
+	// KilnDriedPine is pine sapwood with its original kiln dried surface, the most
+	// sensitive material in the original study since drying draws nutrients to the surface
+	KilnDriedPine = Model{Name: "kiln-dried-pine", Species: 0, Surface: 1}
+)
+
+// ParseModel looks up a model by name, an empty name selects Pine
+func ParseModel(name string) (Model, error) {
+	switch strings.ToLower(name) {
+	case "", Pine.Name:
+		return Pine, nil
+	case Spruce.Name:
+		return Spruce, nil
+	case KilnDriedPine.Name:
+		return KilnDriedPine, nil
+	default:
+		return Model{}, fmt.Errorf("unknown mold model %q, use pine, spruce or kiln-dried-pine", name)
+	}
+}
+
+// State is carried between readings
+type State struct {
+	Index float64
+	// DryHours is how long conditions have been unfavorable for growth
+	DryHours float64
+}
+
+// CriticalHumidity is the relative humidity in % above which growth is possible
+func CriticalHumidity(temperature float64) float64 {
+	if temperature > 20 {
+		return 80
+	}
+	t := temperature
+	return -0.00267*t*t*t + 0.160*t*t - 3.13*t + 100
+}
+
+// Favorable reports whether mold can grow at temperature in C and relative humidity in %
+func Favorable(temperature, humidity float64) bool {
+	return temperature > minTemperature && temperature < maxTemperature && humidity >= CriticalHumidity(temperature)
+}
+
+// Step advances the state by elapsed, assuming the conditions were constant throughout
+func (m Model) Step(state State, temperature, humidity float64, elapsed time.Duration) State {
+	for elapsed > 0 {
+		step := min(elapsed, maxStep)
+		elapsed -= step
+		hours := step.Hours()
+
+		if !Favorable(temperature, humidity) {
+			state.Index -= decline(state.DryHours, state.DryHours+hours)
+			state.DryHours += hours
+		} else {
+			state.Index += m.growthRate(state.Index, temperature, humidity) * hours
+			state.DryHours = 0
+		}
+		state.Index = math.Max(0, math.Min(MaxIndex, state.Index))
+	}
+	return state
+}
+
+// growthRate returns dM/dt per hour
+func (m Model) growthRate(index, temperature, humidity float64) float64 {
+	lnT, lnRH := math.Log(temperature), math.Log(humidity)
+
+	// weeks until the index reaches 1 and 3 in constant conditions
+	weeksToOne := math.Exp(-0.68*lnT - 13.9*lnRH + 0.14*m.Species - 0.33*m.Surface + 66.02)
+	weeksToThree := math.Exp(-0.74*lnT - 12.72*lnRH + 0.06*m.Species + 61.50)
+
+	k1 := 1.0
+	if index >= 1 {
+		k1 = 2 / math.Max(weeksToThree/weeksToOne-1, 0.01)
+	}
+
+	// growth slows down towards the maximum reachable at this humidity
+	critical := CriticalHumidity(temperature)
+	x := (critical - humidity) / (critical - 100)
+	maxIndex := 1 + 7*x - 2*x*x
+	k2 := math.Max(1-math.Exp(2.3*(index-maxIndex)), 0)
+
+	return k1 * k2 / (7 * weeksToOne) / 24
+}
+
+// decline integrates the recession rate between two dry durations in hours
+func decline(from, to float64) float64 {
+	overlap := func(start, end float64) float64 {
+		return math.Max(0, math.Min(to, end)-math.Max(from, start))
+	}
+	return 0.032*overlap(0, 6) + 0.016*overlap(24, math.Inf(1))
+}
+
+// Level describes an index value the way the VTT model defines it
+func Level(index float64) string {
+	switch {
+	case index < 1:
+		return "no growth"
+	case index < 2:
+		return "microscopic growth"
+	case index < 3:
+		return "several microscopic colonies"
+	case index < 4:
+		return "visible growth"
+	case index < 5:
+		return "visible growth over 10% of the surface"
+	case index < 6:
+		return "visible growth over 50% of the surface"
+	default:
+		return "heavy growth covering the surface"
+	}
+}
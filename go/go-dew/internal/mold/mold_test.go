@@ -0,0 +1,128 @@
+package mold
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+const day = 24 * time.Hour
+
+func TestCriticalHumidity(t *testing.T) {
+	tests := map[float64]float64{
+		5:  88.0,
+		15: 80.0,
+		20: 80.0,
+		30: 80.0,
+	}
+	for temperature, expected := range tests {
+		if actual := CriticalHumidity(temperature); math.Abs(actual-expected) > 0.1 {
+			t.Errorf("CriticalHumidity(%v) = %.2f, expected %.1f", temperature, actual, expected)
+		}
+	}
+}
+
+func TestStep_ConstantConditions(t *testing.T) {
+	tests := []struct {
+		name        string
+		temperature float64
+		humidity    float64
+		duration    time.Duration
+		min, max    float64
+	}{
+		// the basement case, below the critical humidity nothing grows
+		{"basement at 68%", 15, 68, 60 * day, 0, 0},
+		{"damp wall", 22, 97, 7 * day, 0.6, 0.8},
+		{"damp wall reaches microscopic growth", 22, 97, 12 * day, 1, 1.6},
+		{"borderline humid", 20, 85, 30 * day, 0.3, 0.5},
+		{"cold", -2, 100, 60 * day, 0, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := Pine.Step(State{}, test.temperature, test.humidity, test.duration)
+			if state.Index < test.min || state.Index > test.max {
+				t.Errorf("expected index between %.1f and %.1f, got %.3f", test.min, test.max, state.Index)
+			}
+		})
+	}
+}
+
+func TestStep_IncrementalMatchesSingleStep(t *testing.T) {
+	once := Pine.Step(State{}, 22, 95, 10*day)
+	incremental := State{}
+	for i := 0; i < 10*24*60; i++ {
+		incremental = Pine.Step(incremental, 22, 95, time.Minute)
+	}
+	if math.Abs(once.Index-incremental.Index) > 0.01 {
+		t.Errorf("expected per-minute updates to match, got %.4f and %.4f", once.Index, incremental.Index)
+	}
+}
+
+func TestStep_LevelsOffAtHumidityMaximum(t *testing.T) {
+	// at 90% the index can never pass 1 + 7x - 2x² with x = 0.5
+	state := Pine.Step(State{}, 25, 90, 2*365*day)
+	if state.Index > 4 || state.Index < 3.5 {
+		t.Errorf("expected the index to level off below 4, got %.3f", state.Index)
+	}
+	state = Pine.Step(State{}, 25, 100, 2*365*day)
+	if state.Index < MaxIndex-0.01 {
+		t.Errorf("expected %d at saturation, got %.3f", MaxIndex, state.Index)
+	}
+}
+
+func TestStep_Decline(t *testing.T) {
+	state := State{Index: 2}
+
+	// 0.032/h for the first 6 hours
+	state = Pine.Step(state, 20, 50, 6*time.Hour)
+	if math.Abs(state.Index-(2-6*0.032)) > 1e-9 || math.Abs(state.DryHours-6) > 1e-9 {
+		t.Errorf("unexpected state after 6 hours: %+v", state)
+	}
+
+	// no change until a day has passed, then 0.016/h
+	state = Pine.Step(state, 20, 50, 18*time.Hour)
+	if math.Abs(state.Index-(2-6*0.032)) > 1e-9 {
+		t.Errorf("expected no decline between 6 and 24 hours, got %+v", state)
+	}
+	state = Pine.Step(state, 20, 50, 10*time.Hour)
+	if math.Abs(state.Index-(2-6*0.032-10*0.016)) > 1e-9 {
+		t.Errorf("unexpected state after 34 hours: %+v", state)
+	}
+
+	// favorable conditions reset the dry period
+	state = Pine.Step(state, 22, 97, time.Hour)
+	if state.DryHours != 0 {
+		t.Errorf("expected dry hours to reset, got %+v", state)
+	}
+
+	state = Pine.Step(State{Index: 0.1}, 20, 50, 30*day)
+	if state.Index != 0 {
+		t.Errorf("expected the index to stop at 0, got %.3f", state.Index)
+	}
+}
+
+func TestModels(t *testing.T) {
+	pine := Pine.Step(State{}, 22, 95, 20*day)
+	spruce := Spruce.Step(State{}, 22, 95, 20*day)
+	kilnDried := KilnDriedPine.Step(State{}, 22, 95, 20*day)
+	if !(pine.Index > spruce.Index && spruce.Index > 0) {
+		t.Errorf("expected pine to grow faster than spruce, got %.3f and %.3f", pine.Index, spruce.Index)
+	}
+	if kilnDried.Index <= pine.Index {
+		t.Errorf("expected kiln dried pine to grow faster, got %.3f and %.3f", kilnDried.Index, pine.Index)
+	}
+
+	if _, err := ParseModel("oak"); err == nil {
+		t.Error("expected an error for an unknown model")
+	}
+	if m, err := ParseModel("Spruce"); err != nil || m != Spruce {
+		t.Errorf("ParseModel(Spruce) = %v, %v", m, err)
+	}
+}
+
+func TestLevel(t *testing.T) {
+	if Level(0.5) != "no growth" || Level(3.2) != "visible growth" || Level(6) != "heavy growth covering the surface" {
+		t.Error("unexpected level descriptions")
+	}
+}
@@ -0,0 +1,194 @@
+package weather
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mugglemath/go-dew/internal/metrics"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// sharedTransport is used by every weather client and grid lookup, so locations on
+// the same grid share their responses
+var sharedTransport = NewCachingTransport(otelhttp.NewTransport(http.DefaultTransport))
+
+// CachingTransport is a private HTTP cache for GET requests, as NWS asks of its clients.
+// A response that is still fresh by Cache-Control max-age or Expires is answered from
+// memory. A stale one is revalidated with If-None-Match and If-Modified-Since, and
+// reused when the server answers 304 Not Modified.
+type CachingTransport struct {
+	transport http.RoundTripper
+	now       func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	status       int
+	header       http.Header
+	body         []byte
+	expires      time.Time
+	etag         string
+	lastModified string
+}
+
+func NewCachingTransport(transport http.RoundTripper) *CachingTransport {
+	return &CachingTransport{
+		transport: transport,
+		now:       time.Now,
+		entries:   map[string]*cacheEntry{},
+	}
+}
+
+func (t *CachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return t.transport.RoundTrip(req)
+	}
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+
+	key := req.URL.String()
+	t.mu.Lock()
+	entry := t.entries[key]
+	t.mu.Unlock()
+
+	if entry != nil && t.now().Before(entry.expires) {
+		metrics.WeatherCache("hit")
+		return entry.response(req), nil
+	}
+
+	outgoing := req
+	if entry != nil && (entry.etag != "" || entry.lastModified != "") {
+		outgoing = req.Clone(req.Context())
+		if entry.etag != "" {
+			outgoing.Header.Set("If-None-Match", entry.etag)
+		}
+		if entry.lastModified != "" {
+			outgoing.Header.Set("If-Modified-Since", entry.lastModified)
+		}
+	}
+
+	resp, err := t.transport.RoundTrip(outgoing)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && outgoing != req {
+		resp.Body.Close()
+		metrics.WeatherCache("revalidated")
+		// the 304 carries the new freshness, the stored response the rest
+		refreshed := *entry
+		refreshed.header = entry.header.Clone()
+		for _, name := range []string{"Cache-Control", "Expires", "Date", "Age", "ETag", "Last-Modified"} {
+			if value := resp.Header.Get(name); value != "" {
+				refreshed.header.Set(name, value)
+			}
+		}
+		refreshed.expires = t.expires(refreshed.header)
+		t.store(key, &refreshed)
+		return refreshed.response(req), nil
+	}
+
+	metrics.WeatherCache("miss")
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	if noStore(resp.Header) {
+		t.forget(key)
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	fetched := &cacheEntry{
+		status:       resp.StatusCode,
+		header:       resp.Header.Clone(),
+		body:         body,
+		expires:      t.expires(resp.Header),
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+	}
+	if t.now().Before(fetched.expires) || fetched.etag != "" || fetched.lastModified != "" {
+		t.store(key, fetched)
+	} else {
+		t.forget(key)
+	}
+	return resp, nil
+}
+
+// expires works out until when a response is fresh. max-age takes precedence over
+// Expires, which is taken relative to the server's Date to allow for clock skew.
+func (t *CachingTransport) expires(header http.Header) time.Time {
+	now := t.now()
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-cache":
+			return now
+		case "max-age":
+			seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+			if err != nil {
+				return now
+			}
+			age, _ := strconv.Atoi(header.Get("Age"))
+			return now.Add(time.Duration(seconds-age) * time.Second)
+		}
+	}
+
+	expires, err := http.ParseTime(header.Get("Expires"))
+	if err != nil {
+		return now
+	}
+	if date, err := http.ParseTime(header.Get("Date")); err == nil {
+		return now.Add(expires.Sub(date))
+	}
+	return expires
+}
+
+func (t *CachingTransport) store(key string, entry *cacheEntry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entries[key] = entry
+}
+
+func (t *CachingTransport) forget(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.entries, key)
+}
+
+func noStore(header http.Header) bool {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		if strings.EqualFold(strings.TrimSpace(directive), "no-store") {
+			return true
+		}
+	}
+	return false
+}
+
+// response rebuilds the stored response for req, each caller gets its own body
+func (e *cacheEntry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        strconv.Itoa(e.status) + " " + http.StatusText(e.status),
+		StatusCode:    e.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,223 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const conditionsJSON = `{"properties": {
+	"temperature": {"values": [{"value": 21.5}]},
+	"dewpoint": {"values": [{"value": 12.0}]}
+}}`
+
+// fakeClock lets the tests move past a response's expiry
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newTestTransport() (*CachingTransport, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 12, 1, 12, 0, 0, 0, time.UTC)}
+	transport := NewCachingTransport(http.DefaultTransport)
+	transport.now = clock.Now
+	return transport, clock
+}
+
+func get(t *testing.T, client *http.Client, url string) string {
+	t.Helper()
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read body: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	return string(body)
+}
+
+func TestCachingTransport_MaxAge(t *testing.T) {
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Cache-Control", "public, max-age=600")
+		w.Header().Set("Age", "100")
+		fmt.Fprintf(w, "response %d", requests.Load())
+	}))
+	defer ts.Close()
+
+	transport, clock := newTestTransport()
+	client := &http.Client{Transport: transport}
+
+	if body := get(t, client, ts.URL); body != "response 1" {
+		t.Errorf("Expected response 1, got %q", body)
+	}
+	clock.now = clock.now.Add(400 * time.Second)
+	if body := get(t, client, ts.URL); body != "response 1" || requests.Load() != 1 {
+		t.Errorf("Expected the cached response 1 after %d requests, got %q", requests.Load(), body)
+	}
+
+	// max-age counts from when the response was generated, 100s before it was received
+	clock.now = clock.now.Add(101 * time.Second)
+	if body := get(t, client, ts.URL); body != "response 2" {
+		t.Errorf("Expected response 2 once expired, got %q", body)
+	}
+}
+
+func TestCachingTransport_ETag(t *testing.T) {
+	var requests, notModified atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fmt.Fprint(w, conditionsJSON)
+	}))
+	defer ts.Close()
+
+	transport, clock := newTestTransport()
+	client := &http.Client{Transport: transport}
+
+	get(t, client, ts.URL)
+	clock.now = clock.now.Add(2 * time.Minute)
+	if body := get(t, client, ts.URL); body != conditionsJSON {
+		t.Errorf("Expected the stored body after revalidating, got %q", body)
+	}
+	if notModified.Load() != 1 {
+		t.Errorf("Expected 1 revalidation, got %d", notModified.Load())
+	}
+
+	// the 304 renewed the max-age
+	clock.now = clock.now.Add(30 * time.Second)
+	get(t, client, ts.URL)
+	if requests.Load() != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests.Load())
+	}
+}
+
+func TestCachingTransport_ExpiresAndLastModified(t *testing.T) {
+	var requests atomic.Int32
+	// the server's clock is an hour ahead, Expires counts from its Date
+	serverNow := time.Date(2024, 12, 1, 13, 0, 0, 0, time.UTC)
+	lastModified := serverNow.Add(-time.Hour).Format(http.TimeFormat)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Date", serverNow.Format(http.TimeFormat))
+		w.Header().Set("Expires", serverNow.Add(5*time.Minute).Format(http.TimeFormat))
+		w.Header().Set("Last-Modified", lastModified)
+		if r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fmt.Fprint(w, conditionsJSON)
+	}))
+	defer ts.Close()
+
+	transport, clock := newTestTransport()
+	client := &http.Client{Transport: transport}
+
+	get(t, client, ts.URL)
+	clock.now = clock.now.Add(4 * time.Minute)
+	get(t, client, ts.URL)
+	if requests.Load() != 1 {
+		t.Errorf("Expected 1 request within Expires, got %d", requests.Load())
+	}
+
+	clock.now = clock.now.Add(2 * time.Minute)
+	if body := get(t, client, ts.URL); body != conditionsJSON || requests.Load() != 2 {
+		t.Errorf("Expected a revalidated body after %d requests, got %q", requests.Load(), body)
+	}
+}
+
+func TestCachingTransport_NoStore(t *testing.T) {
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Cache-Control", "no-store, max-age=600")
+		fmt.Fprint(w, conditionsJSON)
+	}))
+	defer ts.Close()
+
+	transport, _ := newTestTransport()
+	client := &http.Client{Transport: transport}
+
+	get(t, client, ts.URL)
+	get(t, client, ts.URL)
+	if requests.Load() != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests.Load())
+	}
+}
+
+func TestCachingTransport_CancelledContext(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=600")
+		fmt.Fprint(w, conditionsJSON)
+	}))
+	defer ts.Close()
+
+	transport, _ := newTestTransport()
+	client := &http.Client{Transport: transport}
+	get(t, client, ts.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL, nil)
+	_, err := client.Do(req)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a cancelled request even when cached, got %v", err)
+	}
+}
+
+func TestGetOutdoorConditions_Cached(t *testing.T) {
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("User-Agent") != "test-agent" {
+			t.Errorf("Expected User-Agent test-agent, got %q", r.Header.Get("User-Agent"))
+		}
+		w.Header().Set("Cache-Control", "max-age=600")
+		fmt.Fprint(w, conditionsJSON)
+	}))
+	defer ts.Close()
+
+	transport, _ := newTestTransport()
+	c := &clientImpl{
+		httpClient: &http.Client{Transport: transport},
+		baseURL:    ts.URL,
+		userAgent:  "test-agent",
+	}
+
+	conditions, err := c.GetOutdoorConditions(context.Background())
+	if err != nil {
+		t.Fatalf("GetOutdoorConditions returned an error: %v", err)
+	}
+	dewpoint, err := c.GetOutdoorDewPoint(context.Background())
+	if err != nil {
+		t.Fatalf("GetOutdoorDewPoint returned an error: %v", err)
+	}
+
+	if conditions.Temperature != 21.5 || dewpoint != 12.0 {
+		t.Errorf("Expected 21.5 and 12.0, got %+v and %v", conditions, dewpoint)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("Expected both calls to share 1 request, got %d", requests.Load())
+	}
+}
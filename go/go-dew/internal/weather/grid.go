@@ -0,0 +1,237 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mugglemath/dewdrop-go/pkg/logging"
+)
+
+const defaultPointsURL = "https://api.weather.gov/points"
+
+// Grid is the NWS forecast office and grid square covering a location
+type Grid struct {
+	Office     string    `json:"office"`
+	X          int       `json:"grid_x"`
+	Y          int       `json:"grid_y"`
+	ResolvedAt time.Time `json:"resolved_at"`
+}
+
+// GridCache resolves coordinates to grids with the NWS points API and keeps them in a
+// JSON file, so go-dew can start while NWS is down. Grids rarely change, a cached one
+// is only looked up again once it is older than maxAge.
+type GridCache struct {
+	mu         sync.Mutex
+	path       string
+	maxAge     time.Duration
+	userAgent  string
+	pointsURL  string
+	httpClient *http.Client
+	grids      map[string]Grid
+}
+
+// NewGridCache keeps the grids in path, or only in memory if path is empty
+func NewGridCache(path string, maxAge time.Duration, userAgent string) *GridCache {
+	return &GridCache{
+		path:       path,
+		maxAge:     maxAge,
+		userAgent:  userAgent,
+		pointsURL:  defaultPointsURL,
+		httpClient: &http.Client{Timeout: 10 * time.Second, Transport: sharedTransport},
+	}
+}
+
+// Resolve returns the grid of the coordinates. If the lookup fails a stale cached grid
+// is still returned, there is only an error when neither is available.
+func (g *GridCache) Resolve(ctx context.Context, latitude, longitude string) (Grid, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	logger := logging.FromContext(ctx)
+	if g.grids == nil {
+		var err error
+		if g.grids, err = g.load(); err != nil {
+			logger.Warn("failed to read the NWS grid cache", "path", g.path, "error", err)
+			g.grids = map[string]Grid{}
+		}
+	}
+
+	key := latitude + "," + longitude
+	cached, ok := g.grids[key]
+	if ok && time.Since(cached.ResolvedAt) < g.maxAge {
+		return cached, nil
+	}
+
+	grid, err := g.lookup(ctx, latitude, longitude)
+	if err != nil {
+		if ok {
+			logger.Warn("failed to refresh the NWS grid, using the cached one", "office", cached.Office,
+				"resolved_at", cached.ResolvedAt, "error", err)
+			return cached, nil
+		}
+		return Grid{}, err
+	}
+	g.grids[key] = grid
+	if err := g.save(); err != nil {
+		logger.Warn("failed to write the NWS grid cache", "path", g.path, "error", err)
+	}
+	return grid, nil
+}
+
+// lookup parses the NWS points response of the coordinates
+func (g *GridCache) lookup(ctx context.Context, latitude, longitude string) (Grid, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf("%s/%s,%s", g.pointsURL, latitude, longitude), nil)
+	if err != nil {
+		return Grid{}, err
+	}
+	req.Header.Add("User-Agent", g.userAgent)
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return Grid{}, fmt.Errorf("error fetching grid data: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Grid{}, fmt.Errorf("error fetching grid data: %d", resp.StatusCode)
+	}
+
+	var point PointResponse
+	if err := json.NewDecoder(resp.Body).Decode(&point); err != nil {
+		return Grid{}, fmt.Errorf("error decoding grid data: %w", err)
+	}
+	if point.Properties.Office == "" {
+		return Grid{}, errors.New("error fetching grid data: no forecast office")
+	}
+	return Grid{
+		Office:     point.Properties.Office,
+		X:          point.Properties.GridX,
+		Y:          point.Properties.GridY,
+		ResolvedAt: time.Now(),
+	}, nil
+}
+
+func (g *GridCache) load() (map[string]Grid, error) {
+	grids := map[string]Grid{}
+	if g.path == "" {
+		return grids, nil
+	}
+	content, err := os.ReadFile(g.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return grids, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, &grids); err != nil {
+		return nil, err
+	}
+	return grids, nil
+}
+
+// save replaces the file in one step, so a crash can't leave half of it behind
+func (g *GridCache) save() error {
+	if g.path == "" {
+		return nil
+	}
+	content, err := json.MarshalIndent(g.grids, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(g.path), filepath.Base(g.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), g.path)
+}
+
+// locatingClient looks up the grid of its coordinates on first use, and again on
+// every call until that succeeds. Once the grid is older than the cache's maxAge it is
+// looked up again, keeping the current client while NWS can't be reached.
+type locatingClient struct {
+	grids               *GridCache
+	latitude, longitude string
+	userAgent           string
+
+	mu     sync.Mutex
+	client *clientImpl
+	grid   Grid
+}
+
+// NewClientForCoordinates returns a client that doesn't need NWS to be reachable yet
+func NewClientForCoordinates(grids *GridCache, latitude, longitude, userAgent string) Client {
+	return &locatingClient{
+		grids:     grids,
+		latitude:  latitude,
+		longitude: longitude,
+		userAgent: userAgent,
+	}
+}
+
+func (c *locatingClient) GetOutdoorDewPoint(ctx context.Context) (float64, error) {
+	client, err := c.resolve(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return client.GetOutdoorDewPoint(ctx)
+}
+
+func (c *locatingClient) GetOutdoorConditions(ctx context.Context) (Conditions, error) {
+	client, err := c.resolve(ctx)
+	if err != nil {
+		return Conditions{}, err
+	}
+	return client.GetOutdoorConditions(ctx)
+}
+
+func (c *locatingClient) resolve(ctx context.Context) (*clientImpl, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client != nil && time.Since(c.grid.ResolvedAt) < c.grids.maxAge {
+		return c.client, nil
+	}
+
+	logger := logging.FromContext(ctx)
+	grid, err := c.grids.Resolve(ctx, c.latitude, c.longitude)
+	if err != nil {
+		if c.client != nil {
+			logger.Warn("failed to refresh the NWS grid, using the current one", "office", c.grid.Office, "error", err)
+			return c.client, nil
+		}
+		return nil, fmt.Errorf("failed to locate the NWS grid: %w", err)
+	}
+	// an unchanged grid keeps its client, and with it the cached forecast
+	if c.client != nil && grid.Office == c.grid.Office && grid.X == c.grid.X && grid.Y == c.grid.Y {
+		c.grid = grid
+		return c.client, nil
+	}
+	client, err := NewClient(grid.Office, strconv.Itoa(grid.X), strconv.Itoa(grid.Y), c.userAgent)
+	if err != nil {
+		if c.client != nil {
+			logger.Warn("failed to switch to the new NWS grid, using the current one", "office", c.grid.Office, "error", err)
+			return c.client, nil
+		}
+		return nil, err
+	}
+	logger.Info("located the NWS grid", "office", grid.Office, "grid_x", grid.X, "grid_y", grid.Y)
+	c.client, c.grid = client, grid
+	return client, nil
+}
@@ -0,0 +1,179 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// setupPointsServer answers like the NWS points API, or with status if it isn't 200
+func setupPointsServer(t *testing.T, status *atomic.Int32, requests *atomic.Int32) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if code := int(status.Load()); code != http.StatusOK {
+			w.WriteHeader(code)
+			return
+		}
+		if r.URL.Path != "/40.73,-73.95" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"properties": {"gridId": "OKX", "gridX": 33, "gridY": 35}}`)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func newTestGridCache(path, pointsURL string, maxAge time.Duration) *GridCache {
+	grids := NewGridCache(path, maxAge, "test-agent")
+	grids.pointsURL = pointsURL
+	return grids
+}
+
+func TestGridCache_Resolve(t *testing.T) {
+	var status, requests atomic.Int32
+	status.Store(http.StatusOK)
+	ts := setupPointsServer(t, &status, &requests)
+	path := filepath.Join(t.TempDir(), "nws-grid.json")
+
+	grid, err := newTestGridCache(path, ts.URL, time.Hour).Resolve(context.Background(), "40.73", "-73.95")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if grid.Office != "OKX" || grid.X != 33 || grid.Y != 35 {
+		t.Errorf("Expected grid OKX 33,35, got %+v", grid)
+	}
+
+	// a restart reads the file instead of asking NWS
+	status.Store(http.StatusInternalServerError)
+	grid, err = newTestGridCache(path, ts.URL, time.Hour).Resolve(context.Background(), "40.73", "-73.95")
+	if err != nil {
+		t.Fatalf("Expected the cached grid, got %v", err)
+	}
+	if grid.Office != "OKX" || requests.Load() != 1 {
+		t.Errorf("Expected the cached grid without a request, got %+v after %d requests", grid, requests.Load())
+	}
+}
+
+func TestGridCache_Refresh(t *testing.T) {
+	var status, requests atomic.Int32
+	status.Store(http.StatusOK)
+	ts := setupPointsServer(t, &status, &requests)
+	path := filepath.Join(t.TempDir(), "nws-grid.json")
+
+	stale := map[string]Grid{
+		"40.73,-73.95": {Office: "ABC", X: 1, Y: 2, ResolvedAt: time.Now().Add(-48 * time.Hour)},
+	}
+	content, _ := json.Marshal(stale)
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatalf("failed to write cache: %v", err)
+	}
+
+	// a stale grid is still used while NWS is down
+	status.Store(http.StatusServiceUnavailable)
+	grid, err := newTestGridCache(path, ts.URL, 24*time.Hour).Resolve(context.Background(), "40.73", "-73.95")
+	if err != nil || grid.Office != "ABC" {
+		t.Errorf("Expected the stale grid ABC, got %+v, %v", grid, err)
+	}
+
+	status.Store(http.StatusOK)
+	grid, err = newTestGridCache(path, ts.URL, 24*time.Hour).Resolve(context.Background(), "40.73", "-73.95")
+	if err != nil || grid.Office != "OKX" {
+		t.Errorf("Expected the refreshed grid OKX, got %+v, %v", grid, err)
+	}
+
+	var saved map[string]Grid
+	content, _ = os.ReadFile(path)
+	if err := json.Unmarshal(content, &saved); err != nil || saved["40.73,-73.95"].Office != "OKX" {
+		t.Errorf("Expected the refreshed grid in the file, got %s", content)
+	}
+}
+
+func TestGridCache_Unavailable(t *testing.T) {
+	var status, requests atomic.Int32
+	status.Store(http.StatusServiceUnavailable)
+	ts := setupPointsServer(t, &status, &requests)
+
+	_, err := newTestGridCache("", ts.URL, time.Hour).Resolve(context.Background(), "40.73", "-73.95")
+	expected := "error fetching grid data: 503"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error %q, got %v", expected, err)
+	}
+}
+
+func TestLocatingClient_RetriesUntilLocated(t *testing.T) {
+	var status, requests atomic.Int32
+	status.Store(http.StatusServiceUnavailable)
+	ts := setupPointsServer(t, &status, &requests)
+
+	c := NewClientForCoordinates(newTestGridCache("", ts.URL, time.Hour), "40.73", "-73.95", "test-agent").(*locatingClient)
+
+	_, err := c.GetOutdoorConditions(context.Background())
+	expected := "failed to locate the NWS grid: error fetching grid data: 503"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error %q, got %v", expected, err)
+	}
+
+	status.Store(http.StatusOK)
+	client, err := c.resolve(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expectedURL := "https://api.weather.gov/gridpoints/OKX/33,35"
+	if client.baseURL != expectedURL {
+		t.Errorf("Expected baseURL %s, got %s", expectedURL, client.baseURL)
+	}
+
+	if _, err := c.resolve(context.Background()); err != nil || requests.Load() != 2 {
+		t.Errorf("Expected the grid to be looked up once it succeeded, got %d requests", requests.Load())
+	}
+}
+
+func TestLocatingClient_RefreshesStaleGrid(t *testing.T) {
+	var status, requests atomic.Int32
+	status.Store(http.StatusServiceUnavailable)
+	ts := setupPointsServer(t, &status, &requests)
+	path := filepath.Join(t.TempDir(), "nws-grid.json")
+
+	stale := map[string]Grid{
+		"40.73,-73.95": {Office: "ABC", X: 1, Y: 2, ResolvedAt: time.Now().Add(-48 * time.Hour)},
+	}
+	content, _ := json.Marshal(stale)
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatalf("failed to write cache: %v", err)
+	}
+
+	c := NewClientForCoordinates(newTestGridCache(path, ts.URL, 24*time.Hour), "40.73", "-73.95", "test-agent").(*locatingClient)
+
+	// the stale grid is used while NWS is down
+	client, err := c.resolve(context.Background())
+	if err != nil || client.baseURL != "https://api.weather.gov/gridpoints/ABC/1,2" {
+		t.Fatalf("Expected the stale grid ABC, got %v", err)
+	}
+
+	// and looked up again once NWS is back
+	status.Store(http.StatusOK)
+	client, err = c.resolve(context.Background())
+	expectedURL := "https://api.weather.gov/gridpoints/OKX/33,35"
+	if err != nil || client.baseURL != expectedURL {
+		t.Fatalf("Expected baseURL %s, got %v", expectedURL, err)
+	}
+
+	// the fresh grid is kept until it is stale too
+	if _, err := c.resolve(context.Background()); err != nil || requests.Load() != 2 {
+		t.Errorf("Expected no lookup of a fresh grid, got %d requests", requests.Load())
+	}
+	c.grid.ResolvedAt = time.Now().Add(-48 * time.Hour)
+	c.grids.grids["40.73,-73.95"] = c.grid
+	status.Store(http.StatusServiceUnavailable)
+	if again, err := c.resolve(context.Background()); err != nil || again != client || requests.Load() != 3 {
+		t.Errorf("Expected the current client after a failed lookup, got %v after %d requests", err, requests.Load())
+	}
+}
@@ -7,10 +7,23 @@ import (
 	"fmt"
 	"net/http"
 	"time"
+
+	"github.com/mugglemath/dewdrop-go/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const tracerName = "github.com/mugglemath/go-dew/internal/weather"
+
 type Client interface {
 	GetOutdoorDewPoint(ctx context.Context) (float64, error)
+	GetOutdoorConditions(ctx context.Context) (Conditions, error)
+}
+
+// Conditions are the current values of the gridpoint forecast in C
+type Conditions struct {
+	Temperature float64
+	Dewpoint    float64
 }
 
 type clientImpl struct {
@@ -29,6 +42,19 @@ type GridResponse struct {
 	} `json:"properties"`
 }
 
+type gridValues struct {
+	Values []struct {
+		Value float64 `json:"value"`
+	} `json:"values"`
+}
+
+type ConditionsResponse struct {
+	Properties struct {
+		Temperature gridValues `json:"temperature"`
+		Dewpoint    gridValues `json:"dewpoint"`
+	} `json:"properties"`
+}
+
 type PointResponse struct {
 	Properties struct {
 		Office string `json:"gridId"`
@@ -49,72 +75,75 @@ func NewClient(office, gridX, gridY, userAgent string) (*clientImpl, error) {
 	}
 	baseURL := fmt.Sprintf("https://api.weather.gov/gridpoints/%s/%s,%s", office, gridX, gridY)
 	return &clientImpl{
-		httpClient: &http.Client{Timeout: 10 * time.Second},
+		httpClient: &http.Client{Timeout: 10 * time.Second, Transport: sharedTransport},
 		baseURL:    baseURL,
 		userAgent:  userAgent,
 	}, nil
 }
 
-// GetGridData parses the NWS points response and returns the gridpoints variables
-func GetGridData(latitude, longitude, userAgent string) (string, int, int, error) {
-	baseURL := fmt.Sprintf("https://api.weather.gov/points/%s,%s", latitude, longitude)
+// GetOutdoorDewPoint retrieves the outdoor dew point from NWS using the gridpoints variables
+func (c *clientImpl) GetOutdoorDewPoint(ctx context.Context) (dewpoint float64, err error) {
+	ctx, span := c.startSpan(ctx, "weather.dewpoint")
+	defer func() { tracing.End(span, err) }()
 
-	req, err := http.NewRequest("GET", baseURL, nil)
-	if err != nil {
-		return "", 0, 0, err
+	var response GridResponse
+	if err := c.get(ctx, &response); err != nil {
+		return 0, err
 	}
 
-	req.Header.Add("User-Agent", userAgent)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", 0, 0, err
+	if len(response.Properties.Dewpoint.Values) == 0 {
+		return 0, fmt.Errorf("no dewpoint values")
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", 0, 0, fmt.Errorf("error fetching grid data: %d", resp.StatusCode)
+	return response.Properties.Dewpoint.Values[0].Value, nil
+}
+
+// GetOutdoorConditions retrieves outdoor temperature and dew point from the same gridpoints response
+func (c *clientImpl) GetOutdoorConditions(ctx context.Context) (conditions Conditions, err error) {
+	ctx, span := c.startSpan(ctx, "weather.conditions")
+	defer func() { tracing.End(span, err) }()
+
+	var response ConditionsResponse
+	if err := c.get(ctx, &response); err != nil {
+		return Conditions{}, err
 	}
 
-	var pointResponse PointResponse
-	err = json.NewDecoder(resp.Body).Decode(&pointResponse)
-	if err != nil {
-		return "", 0, 0, err
+	if len(response.Properties.Dewpoint.Values) == 0 {
+		return Conditions{}, fmt.Errorf("no dewpoint values")
+	}
+	if len(response.Properties.Temperature.Values) == 0 {
+		return Conditions{}, fmt.Errorf("no temperature values")
 	}
 
-	return pointResponse.Properties.Office, pointResponse.Properties.GridX, pointResponse.Properties.GridY, nil
+	return Conditions{
+		Temperature: response.Properties.Temperature.Values[0].Value,
+		Dewpoint:    response.Properties.Dewpoint.Values[0].Value,
+	}, nil
 }
 
-// GetOutdoorDewPoint retrieves the outdoor dew point from NWS using the gridpoints variables
-func (c *clientImpl) GetOutdoorDewPoint(ctx context.Context) (float64, error) {
-	req, err := http.NewRequest("GET", c.baseURL, nil)
+// get decodes the gridpoints response, which the HTTP client may answer from its cache
+func (c *clientImpl) get(ctx context.Context, response any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL, nil)
 	if err != nil {
-		return 0, err
+		return err
 	}
 
 	req.Header.Add("User-Agent", c.userAgent)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return 0, err
+		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("error fetching weather data: %d", resp.StatusCode)
+		return fmt.Errorf("error fetching weather data: %d", resp.StatusCode)
 	}
 
-	var response GridResponse
-	err = json.NewDecoder(resp.Body).Decode(&response)
-	if err != nil {
-		return 0, err
-	}
-
-	if len(response.Properties.Dewpoint.Values) == 0 {
-		return 0, fmt.Errorf("no dewpoint values")
-	}
+	return json.NewDecoder(resp.Body).Decode(response)
+}
 
-	return response.Properties.Dewpoint.Values[0].Value, nil
+func (c *clientImpl) startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, tracerName, name, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("url.full", c.baseURL)))
 }
@@ -9,6 +9,11 @@ import (
 	"net/http/httptest"
 	"testing"
 	"time"
+
+	"github.com/mugglemath/dewdrop-go/pkg/tracing"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func TestNewClient_ValidInputs(t *testing.T) {
@@ -93,8 +98,9 @@ func TestGetOutdoorDewPoint(t *testing.T) {
 
 	// create a client with the test server's URL
 	c := &clientImpl{
-		baseURL:   ts.URL,
-		userAgent: "test-agent",
+		httpClient: http.DefaultClient,
+		baseURL:    ts.URL,
+		userAgent:  "test-agent",
 	}
 
 	// call the function being tested
@@ -116,8 +122,9 @@ func TestGetOutdoorDewPoint_InvalidRequest(t *testing.T) {
 	defer ts.Close()
 
 	c := &clientImpl{
-		baseURL:   ts.URL,
-		userAgent: "test-agent",
+		httpClient: http.DefaultClient,
+		baseURL:    ts.URL,
+		userAgent:  "test-agent",
 	}
 
 	_, err := c.GetOutdoorDewPoint(context.Background())
@@ -133,8 +140,9 @@ func TestGetOutdoorDewPoint_Non200Response(t *testing.T) {
 	defer ts.Close()
 
 	c := &clientImpl{
-		baseURL:   ts.URL,
-		userAgent: "test-agent",
+		httpClient: http.DefaultClient,
+		baseURL:    ts.URL,
+		userAgent:  "test-agent",
 	}
 
 	_, err := c.GetOutdoorDewPoint(context.Background())
@@ -150,8 +158,9 @@ func TestGetOutdoorDewPoint_JSONDecodeError(t *testing.T) {
 	defer ts.Close()
 
 	c := &clientImpl{
-		baseURL:   ts.URL,
-		userAgent: "test-agent",
+		httpClient: http.DefaultClient,
+		baseURL:    ts.URL,
+		userAgent:  "test-agent",
 	}
 
 	_, err := c.GetOutdoorDewPoint(context.Background())
@@ -190,8 +199,9 @@ func TestGetOutdoorDewPoint_EmptyDewPointValues(t *testing.T) {
 	defer ts.Close()
 
 	c := &clientImpl{
-		baseURL:   ts.URL,
-		userAgent: "test-agent",
+		httpClient: http.DefaultClient,
+		baseURL:    ts.URL,
+		userAgent:  "test-agent",
 	}
 
 	_, err := c.GetOutdoorDewPoint(context.Background())
@@ -202,8 +212,9 @@ func TestGetOutdoorDewPoint_EmptyDewPointValues(t *testing.T) {
 
 func TestGetOutdoorDewPoint_NilContext(t *testing.T) {
 	c := &clientImpl{
-		baseURL:   "https://example.com",
-		userAgent: "test-agent",
+		httpClient: http.DefaultClient,
+		baseURL:    "https://example.com",
+		userAgent:  "test-agent",
 	}
 
 	_, err := c.GetOutdoorDewPoint(context.TODO())
@@ -217,8 +228,9 @@ func TestGetOutdoorDewPoint_CancelledContext(t *testing.T) {
 	cancel()
 
 	c := &clientImpl{
-		baseURL:   "https://example.com",
-		userAgent: "test-agent",
+		httpClient: http.DefaultClient,
+		baseURL:    "https://example.com",
+		userAgent:  "test-agent",
 	}
 
 	_, err := c.GetOutdoorDewPoint(ctx)
@@ -226,3 +238,94 @@ func TestGetOutdoorDewPoint_CancelledContext(t *testing.T) {
 		t.Errorf("GetOutdoorDewPoint did not return an error for a cancelled context")
 	}
 }
+
+func TestGetOutdoorConditions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"properties": {
+			"temperature": {"uom": "wmoUnit:degC", "values": [{"validTime": "2024-12-01T00:00:00+00:00/PT1H", "value": -4.5}]},
+			"dewpoint": {"uom": "wmoUnit:degC", "values": [{"validTime": "2024-12-01T00:00:00+00:00/PT2H", "value": -9.0}]}
+		}}`)
+	}))
+	defer ts.Close()
+
+	c := &clientImpl{
+		httpClient: http.DefaultClient,
+		baseURL:    ts.URL,
+		userAgent:  "test-agent",
+	}
+
+	conditions, err := c.GetOutdoorConditions(context.Background())
+	if err != nil {
+		t.Errorf("GetOutdoorConditions returned an error: %v", err)
+	}
+
+	expected := Conditions{Temperature: -4.5, Dewpoint: -9.0}
+	if conditions != expected {
+		t.Errorf("GetOutdoorConditions returned %+v, expected %+v", conditions, expected)
+	}
+}
+
+func TestGetOutdoorConditions_Span(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	shutdown, err := tracing.Install(context.Background(), "go-dew", sdktrace.WithSyncer(exporter))
+	if err != nil {
+		t.Fatalf("failed to install tracing: %v", err)
+	}
+	defer shutdown(context.Background())
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	c := &clientImpl{
+		httpClient: http.DefaultClient,
+		baseURL:    ts.URL,
+		userAgent:  "test-agent",
+	}
+	_, _ = c.GetOutdoorConditions(context.Background())
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || spans[0].Name != "weather.conditions" {
+		t.Fatalf("expected a weather.conditions span, got %v", spans)
+	}
+	if spans[0].Status.Code != codes.Error || spans[0].Status.Description != "error fetching weather data: 503" {
+		t.Errorf("expected an error status, got %v", spans[0].Status)
+	}
+}
+
+func TestGetOutdoorConditions_MissingTemperature(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"properties": {"temperature": {"values": []}, "dewpoint": {"values": [{"value": 1.0}]}}}`)
+	}))
+	defer ts.Close()
+
+	c := &clientImpl{
+		httpClient: http.DefaultClient,
+		baseURL:    ts.URL,
+		userAgent:  "test-agent",
+	}
+
+	_, err := c.GetOutdoorConditions(context.Background())
+	if err == nil || err.Error() != "no temperature values" {
+		t.Errorf("expected no temperature values error, got %v", err)
+	}
+}
+
+func TestGetOutdoorConditions_Non200Response(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Not found", http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	c := &clientImpl{
+		httpClient: http.DefaultClient,
+		baseURL:    ts.URL,
+		userAgent:  "test-agent",
+	}
+
+	_, err := c.GetOutdoorConditions(context.Background())
+	if err == nil {
+		t.Errorf("GetOutdoorConditions did not return an error for a non-200 response")
+	}
+}